@@ -0,0 +1,469 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	_ "embed"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/jezzaho/goro-web/internal"
+)
+
+// OpenAPI 3 description of the JSON API, kept in sync with apiHandlers by the tests
+//
+//go:embed openapi.json
+var openAPIDocument []byte
+
+// apiHandlers returns the JSON API handlers by path.
+func (app *Application) apiHandlers() map[string]http.HandlerFunc {
+	return map[string]http.HandlerFunc{
+		"/api/v1/openapi.json": app.OpenAPIHandler,
+		"/api/v1/carriers":     app.APICarriersHandler,
+		"/api/v1/routes":       app.APIRoutesHandler,
+		"/api/v1/operators":    app.APIOperatorsHandler,
+		"/api/v1/aircraft":     app.APIAircraftHandler,
+		"/api/v1/schedules":    app.APISchedulesHandler,
+		"/api/v1/capacity":     app.APICapacityHandler,
+		"/api/v1/blocktimes":   app.APIBlockTimesHandler,
+		"/api/v1/timeline":     app.APITimelineHandler,
+		"/api/v1/connections":  app.APIConnectionsHandler,
+		"/api/v1/fetches":      app.APIFetchesHandler,
+		"/api/v1/jobs":         app.APIJobsHandler,
+	}
+}
+
+// apiRoutes registers the JSON API on the router, unknown API paths answer
+// with a JSON error instead of the index page.
+func (app *Application) apiRoutes(router *http.ServeMux) {
+	for path, handler := range app.apiHandlers() {
+		router.HandleFunc(path, handler)
+	}
+	router.HandleFunc("/api/", func(w http.ResponseWriter, r *http.Request) {
+		writeAPIError(w, http.StatusNotFound, internal.T(requestLocale(r), "error.not_found"))
+	})
+}
+
+// apiError is the body of every error response of the JSON API.
+type apiError struct {
+	Error apiErrorDetail `json:"error"`
+}
+
+type apiErrorDetail struct {
+	Status  int    `json:"status"`
+	Message string `json:"message"`
+}
+
+func writeAPIError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, apiError{apiErrorDetail{Status: status, Message: message}})
+}
+
+// apiGet answers requests other than GET with a JSON error and reports
+// whether the handler should go on.
+func apiGet(w http.ResponseWriter, r *http.Request) bool {
+	if r.Method == http.MethodGet {
+		return true
+	}
+	w.Header().Set("Allow", http.MethodGet)
+	writeAPIError(w, http.StatusMethodNotAllowed, internal.T(requestLocale(r), "error.method_not_allowed"))
+	return false
+}
+
+func (app *Application) OpenAPIHandler(w http.ResponseWriter, r *http.Request) {
+	if !apiGet(w, r) {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(openAPIDocument)
+}
+
+type apiCarrier struct {
+	Code string `json:"code"`
+}
+
+func (app *Application) APICarriersHandler(w http.ResponseWriter, r *http.Request) {
+	if !apiGet(w, r) {
+		return
+	}
+	carriers := make([]apiCarrier, 0, len(internal.Carriers))
+	for _, code := range internal.Carriers {
+		carriers = append(carriers, apiCarrier{Code: code})
+	}
+	writeJSON(w, http.StatusOK, carriers)
+}
+
+// Operators of the reference data, only the one with the IATA or ICAO code
+// of the code query value when given
+func (app *Application) APIOperatorsHandler(w http.ResponseWriter, r *http.Request) {
+	if !apiGet(w, r) {
+		return
+	}
+	references := internal.References()
+	operators := references.Operators()
+	if code := r.URL.Query().Get("code"); code != "" {
+		operator, ok := references.Operator(code)
+		if !ok {
+			writeAPIError(w, http.StatusNotFound, internal.T(requestLocale(r), "error.not_found"))
+			return
+		}
+		operators = []internal.Operator{operator}
+	}
+	writeJSON(w, http.StatusOK, operators)
+}
+
+// Aircraft types of the reference data, only the one with the IATA or ICAO
+// code of the code query value when given
+func (app *Application) APIAircraftHandler(w http.ResponseWriter, r *http.Request) {
+	if !apiGet(w, r) {
+		return
+	}
+	references := internal.References()
+	aircraft := references.AircraftTypes()
+	if code := r.URL.Query().Get("code"); code != "" {
+		aircraftType, ok := references.AircraftType(code)
+		if !ok {
+			writeAPIError(w, http.StatusNotFound, internal.T(requestLocale(r), "error.not_found"))
+			return
+		}
+		aircraft = []internal.AircraftType{aircraftType}
+	}
+	writeJSON(w, http.StatusOK, aircraft)
+}
+
+// apiRoute is a route fetched by default for a carrier, both directions are
+// fetched.
+type apiRoute struct {
+	Carrier     string `json:"carrier"`
+	Origin      string `json:"origin"`
+	Destination string `json:"destination"`
+}
+
+// Default routes of the carrier query value, of all carriers when missing
+func (app *Application) APIRoutesHandler(w http.ResponseWriter, r *http.Request) {
+	if !apiGet(w, r) {
+		return
+	}
+	carriers := internal.Carriers
+	if carrier := strings.ToUpper(r.URL.Query().Get("carrier")); carrier != "" {
+		if _, ok := internal.CarrierCode(carrier); !ok {
+			writeAPIError(w, http.StatusBadRequest, internal.T(requestLocale(r), "error.parameter", fmt.Errorf("unknown carrier %q", carrier)))
+			return
+		}
+		carriers = []string{carrier}
+	}
+
+	routes := []apiRoute{}
+	for _, carrier := range carriers {
+		code, _ := internal.CarrierCode(carrier)
+		for _, q := range internal.GetQueryListForAirline(code, "", "", internal.TimeModeLT) {
+			routes = append(routes, apiRoute{Carrier: q.Airline, Origin: q.Origin, Destination: q.Destination})
+		}
+	}
+	writeJSON(w, http.StatusOK, routes)
+}
+
+// fromQuery reads the parameters from the query values, unlike the flags
+// the carrier has no default.
+func (f *scheduleParams) fromQuery(q url.Values) error {
+	f.carrier = strings.ToUpper(q.Get("carrier"))
+	f.from = q.Get("from")
+	f.to = q.Get("to")
+	f.season = q.Get("season")
+	f.origin = q.Get("origin")
+	f.destination = q.Get("destination")
+	f.timeMode = q.Get("time-mode")
+	f.dayBasis = q.Get("day-basis")
+	for name, value := range map[string]*bool{"separate": &f.separate, "exceptions": &f.exceptions, "merge-dst": &f.mergeDST} {
+		v, err := queryBool(q.Get(name))
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		*value = v
+	}
+	return nil
+}
+
+// queryBool reads a boolean query value, accepting "on" as sent by checkboxes.
+func queryBool(s string) (bool, error) {
+	switch s {
+	case "":
+		return false, nil
+	case "on":
+		return true, nil
+	default:
+		return strconv.ParseBool(s)
+	}
+}
+
+type apiSchedule struct {
+	Carrier  string                    `json:"carrier"`
+	Period   internal.Season           `json:"period"`
+	Records  []internal.ScheduleRecord `json:"records"`
+	Warnings []internal.Warning        `json:"warnings,omitempty"`
+}
+
+// Normalized schedule records of the carrier, or of a route of it, for the
+// dates or season of the query
+func (app *Application) APISchedulesHandler(w http.ResponseWriter, r *http.Request) {
+	if !apiGet(w, r) {
+		return
+	}
+	if schedule, ok := app.apiSchedule(w, r); ok {
+		writeJSON(w, http.StatusOK, schedule)
+	}
+}
+
+type apiCapacity struct {
+	Carrier string                    `json:"carrier"`
+	Period  internal.Season           `json:"period"`
+	Weeks   []internal.WeeklyCapacity `json:"weeks"`
+}
+
+// Weekly frequencies and seats per route of the schedule the query selects
+// like for /api/v1/schedules
+func (app *Application) APICapacityHandler(w http.ResponseWriter, r *http.Request) {
+	if !apiGet(w, r) {
+		return
+	}
+	schedule, ok := app.apiSchedule(w, r)
+	if !ok {
+		return
+	}
+	weeks := internal.WeeklyCapacities(schedule.Records, app.seats, schedule.Period.Start, schedule.Period.End)
+	writeJSON(w, http.StatusOK, apiCapacity{Carrier: schedule.Carrier, Period: schedule.Period, Weeks: weeks})
+}
+
+type apiBlockTimes struct {
+	Carrier    string                    `json:"carrier"`
+	Period     internal.Season           `json:"period"`
+	BlockTimes []internal.BlockTimeStats `json:"block_times"`
+}
+
+// Block time distributions per route, aircraft type and season of the
+// schedule the query selects like for /api/v1/schedules
+func (app *Application) APIBlockTimesHandler(w http.ResponseWriter, r *http.Request) {
+	if !apiGet(w, r) {
+		return
+	}
+	schedule, ok := app.apiSchedule(w, r)
+	if !ok {
+		return
+	}
+	stats := internal.BlockTimeStatistics(schedule.Records, schedule.Period.Start, schedule.Period.End)
+	writeJSON(w, http.StatusOK, apiBlockTimes{Carrier: schedule.Carrier, Period: schedule.Period, BlockTimes: stats})
+}
+
+// Flights of the schedule the query selects like for /api/v1/schedules on a
+// grid of days and departure times, as JSON, SVG or PNG
+func (app *Application) APITimelineHandler(w http.ResponseWriter, r *http.Request) {
+	if !apiGet(w, r) {
+		return
+	}
+	locale := requestLocale(r)
+	q := r.URL.Query()
+	byWeekday, err := groupByWeekday(q)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, internal.T(locale, "error.parameter", err))
+		return
+	}
+	if format := q.Get("format"); format != "" && format != "json" && format != "svg" && format != "png" {
+		writeAPIError(w, http.StatusBadRequest, internal.T(locale, "error.parameter", fmt.Errorf("invalid format %q", format)))
+		return
+	}
+	schedule, ok := app.apiSchedule(w, r)
+	if !ok {
+		return
+	}
+	timeline, err := internal.BuildTimeline(schedule.Records, q.Get("color"), byWeekday, schedule.Period.Start, schedule.Period.End)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, internal.T(locale, "error.parameter", err))
+		return
+	}
+	opts := internal.ExportOptions{Locale: locale}
+	writeTimeline(w, timeline, cmp.Or(q.Get("format"), "json"), schedule.Carrier+"_timeline", opts)
+}
+
+type apiConnections struct {
+	Origin      string                `json:"origin"`
+	Destination string                `json:"destination"`
+	Period      internal.Season       `json:"period"`
+	Connections []internal.Connection `json:"connections"`
+}
+
+// Connections from the origin of the default routes, KRK unless given, to
+// the destination through the hubs, by the carriers of the comma separated
+// carrier list or by all of them, as JSON, CSV or XLSX
+func (app *Application) APIConnectionsHandler(w http.ResponseWriter, r *http.Request) {
+	if !apiGet(w, r) {
+		return
+	}
+	locale := requestLocale(r)
+	q := r.URL.Query()
+	format := cmp.Or(q.Get("format"), "json")
+	if format != "json" && format != "csv" && format != "xlsx" {
+		writeAPIError(w, http.StatusBadRequest, internal.T(locale, "error.parameter", fmt.Errorf("invalid format %q", format)))
+		return
+	}
+	var params scheduleParams
+	if err := params.fromQuery(q); err != nil {
+		writeAPIError(w, http.StatusBadRequest, internal.T(locale, "error.parameter", err))
+		return
+	}
+	opts, err := params.exportOptions()
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, internal.T(locale, "error.parameter", err))
+		return
+	}
+	opts.Locale = locale
+	queries, period, err := params.connectionQueries(app.hubs)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, internal.T(locale, "error.route", err))
+		return
+	}
+
+	// Several carriers in one fetch are no snapshot of a carrier, the result
+	// is only kept with the job
+	result, ok := app.apiFetch(w, r, fmt.Sprintf("connections %v %+v", queries, params), func(ctx context.Context) (internal.FetchResult, error) {
+		data, err := app.fetch(ctx, queries)
+		if err != nil {
+			return internal.FetchResult{}, err
+		}
+		records, err := internal.PrepareRecords(data, opts)
+		return internal.FetchResult{Records: records}, err
+	})
+	if !ok {
+		return
+	}
+	origin := params.connectionOrigin()
+	connections, err := internal.BuildConnections(result.Records, origin, params.destination, app.hubs, period.Start, period.End)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, internal.T(locale, "error.parameter", err))
+		return
+	}
+
+	filename := fmt.Sprintf("connections_%s_%s", origin, strings.ToUpper(params.destination))
+	switch format {
+	case "xlsx":
+		w.Header().Set("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+		w.Header().Set("Content-Disposition", "attachment; filename=\""+filename+".xlsx\"")
+		if err := internal.WriteConnectionsXLSX(w, connections, opts); err != nil {
+			log.Printf("Error writing connection report: %v", err)
+		}
+	case "csv":
+		w.Header().Set("Content-Type", "text/csv")
+		w.Header().Set("Content-Disposition", "attachment; filename=\""+filename+".csv\"")
+		if err := internal.WriteConnectionsCSV(w, connections, opts); err != nil {
+			log.Printf("Error writing connection report: %v", err)
+		}
+	default:
+		if connections == nil {
+			connections = []internal.Connection{}
+		}
+		writeJSON(w, http.StatusOK, apiConnections{Origin: origin, Destination: strings.ToUpper(params.destination), Period: period, Connections: connections})
+	}
+}
+
+// apiSchedule fetches the schedule selected by the query. Errors are written
+// to w and reported by a false ok.
+func (app *Application) apiSchedule(w http.ResponseWriter, r *http.Request) (apiSchedule, bool) {
+	locale := requestLocale(r)
+
+	var params scheduleParams
+	if err := params.fromQuery(r.URL.Query()); err != nil {
+		writeAPIError(w, http.StatusBadRequest, internal.T(locale, "error.parameter", err))
+		return apiSchedule{}, false
+	}
+	opts, err := params.exportOptions()
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, internal.T(locale, "error.parameter", err))
+		return apiSchedule{}, false
+	}
+	period, err := params.period()
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, internal.T(locale, "error.parameter", err))
+		return apiSchedule{}, false
+	}
+	queries, err := params.job().Queries(period)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, internal.T(locale, "error.route", err))
+		return apiSchedule{}, false
+	}
+
+	// Stored like the exports of the page so the snapshot views and the
+	// diff work on it
+	snapshotParams := requestParams(r)
+	result, ok := app.apiFetch(w, r, fmt.Sprintf("schedule %v %+v", queries, params), func(ctx context.Context) (internal.FetchResult, error) {
+		data, err := app.fetch(ctx, queries)
+		if err != nil {
+			return internal.FetchResult{}, err
+		}
+		records, warnings, err := internal.PrepareValidatedRecords(data, opts, internal.DefaultRules(queries))
+		if err != nil {
+			return internal.FetchResult{}, err
+		}
+		if records == nil {
+			records = []internal.ScheduleRecord{}
+		}
+		snap := app.saveSnapshot(params.carrier, period.Start, period.End, snapshotParams, records, warnings)
+		return internal.FetchResult{Records: records, Warnings: warnings, Snapshot: snap.ID}, nil
+	})
+	if !ok {
+		return apiSchedule{}, false
+	}
+	return apiSchedule{Carrier: params.carrier, Period: period, Records: result.Records, Warnings: result.Warnings}, true
+}
+
+// apiFetch runs the fetch as a background job shared by the requests of the
+// key and waits for it up to fetchWait, the upstream API takes seconds per
+// query. A fetch still running is answered with 202 and the job for the
+// client to poll, by /api/v1/fetches or by repeating the request, a failed
+// one with the error. ok reports whether the result is there.
+func (app *Application) apiFetch(w http.ResponseWriter, r *http.Request, key string, run func(ctx context.Context) (internal.FetchResult, error)) (internal.FetchResult, bool) {
+	job := app.fetches.Wait(r.Context(), app.fetches.Start(key, run), app.fetchWait)
+	switch job.Status {
+	case internal.FetchDone:
+		return job.Result, true
+	case internal.FetchFailed:
+		writeAPIError(w, http.StatusBadGateway, internal.T(requestLocale(r), "error.fetch", job.Error))
+	default:
+		w.Header().Set("Location", "/api/v1/fetches?id="+strconv.FormatUint(job.ID, 10))
+		w.Header().Set("Retry-After", "10")
+		writeJSON(w, http.StatusAccepted, job)
+	}
+	return internal.FetchResult{}, false
+}
+
+// Background fetch of the id query value with its status
+func (app *Application) APIFetchesHandler(w http.ResponseWriter, r *http.Request) {
+	if !apiGet(w, r) {
+		return
+	}
+	locale := requestLocale(r)
+	id, err := strconv.ParseUint(r.URL.Query().Get("id"), 10, 64)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, internal.T(locale, "error.parameter", fmt.Errorf("invalid id %q", r.URL.Query().Get("id"))))
+		return
+	}
+	job, ok := app.fetches.Get(id)
+	if !ok {
+		writeAPIError(w, http.StatusNotFound, internal.T(locale, "error.not_found"))
+		return
+	}
+	writeJSON(w, http.StatusOK, job)
+}
+
+// Scheduled fetches configured by WATCH_FILE with their next run
+func (app *Application) APIJobsHandler(w http.ResponseWriter, r *http.Request) {
+	if !apiGet(w, r) {
+		return
+	}
+	jobs := []internal.JobStatus{}
+	if app.scheduler != nil {
+		jobs = app.scheduler.Jobs()
+	}
+	writeJSON(w, http.StatusOK, jobs)
+}
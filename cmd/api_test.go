@@ -0,0 +1,503 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jezzaho/goro-web/internal"
+)
+
+const testResponse = `[{"airline":"LH","flightNumber":1365,"periodOfOperationLT":{"startDate":"30MAR25","endDate":"26APR25","daysOfOperation":"1234567"},"legs":[{"origin":"KRK","destination":"FRA","aircraftOwner":"LH","aircraftType":"32N","aircraftDepartureTimeLT":620,"aircraftArrivalTimeLT":725,"aircraftDepartureTimeUTC":500,"aircraftArrivalTimeUTC":605}]}]`
+
+func testAPI(t *testing.T) *http.ServeMux {
+	t.Helper()
+	scheduler, err := internal.NewScheduler(internal.WatchConfig{
+		Jobs: []internal.WatchJob{{Name: "lh", Schedule: "0 6 * * *", Carrier: "LH", Season: "next"}},
+	}, nil, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	app := &Application{
+		fetch: func(_ context.Context, queries []internal.ApiQuery) ([]byte, error) {
+			if queries[0].Destination == "MUC" {
+				return nil, errors.New("connection refused")
+			}
+			return []byte(testResponse), nil
+		},
+		scheduler: scheduler,
+		seats:     internal.DefaultSeatTable(),
+		hubs:      internal.DefaultHubTable(),
+		fetches:   internal.NewFetchJobs(context.Background(), time.Minute),
+		fetchWait: 5 * time.Second,
+	}
+	router := http.NewServeMux()
+	app.apiRoutes(router)
+	return router
+}
+
+type openAPI map[string]any
+
+func loadOpenAPI(t *testing.T) openAPI {
+	t.Helper()
+	var doc openAPI
+	if err := json.Unmarshal(openAPIDocument, &doc); err != nil {
+		t.Fatalf("invalid OpenAPI document: %v", err)
+	}
+	return doc
+}
+
+// resolve follows a local $ref such as #/components/schemas/Route.
+func (doc openAPI) resolve(node map[string]any) map[string]any {
+	ref, ok := node["$ref"].(string)
+	if !ok {
+		return node
+	}
+	var current any = map[string]any(doc)
+	for _, part := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+		current = current.(map[string]any)[part]
+	}
+	return doc.resolve(current.(map[string]any))
+}
+
+// validate checks the decoded JSON value against the schema, returning the
+// violations found.
+func (doc openAPI) validate(schema map[string]any, value any, at string) []string {
+	schema = doc.resolve(schema)
+	var problems []string
+	if enum, ok := schema["enum"].([]any); ok && !slices.Contains(enum, value) {
+		problems = append(problems, fmt.Sprintf("%s: %v not in %v", at, value, enum))
+	}
+	switch schema["type"] {
+	case "object":
+		object, ok := value.(map[string]any)
+		if !ok {
+			return append(problems, fmt.Sprintf("%s: expected object, got %T", at, value))
+		}
+		for _, name := range asSlice(schema["required"]) {
+			if _, ok := object[name.(string)]; !ok {
+				problems = append(problems, fmt.Sprintf("%s: missing %s", at, name))
+			}
+		}
+		properties, _ := schema["properties"].(map[string]any)
+		for name, v := range object {
+			property, ok := properties[name].(map[string]any)
+			if !ok {
+				if properties != nil {
+					problems = append(problems, fmt.Sprintf("%s: undocumented property %s", at, name))
+				}
+				continue
+			}
+			problems = append(problems, doc.validate(property, v, at+"."+name)...)
+		}
+	case "array":
+		items, ok := value.([]any)
+		if !ok {
+			return append(problems, fmt.Sprintf("%s: expected array, got %T", at, value))
+		}
+		for i, item := range items {
+			problems = append(problems, doc.validate(schema["items"].(map[string]any), item, fmt.Sprintf("%s[%d]", at, i))...)
+		}
+	case "string":
+		s, ok := value.(string)
+		if !ok {
+			return append(problems, fmt.Sprintf("%s: expected string, got %T", at, value))
+		}
+		layout := map[any]string{"date": "2006-01-02", "date-time": time.RFC3339}[schema["format"]]
+		if _, err := time.Parse(layout, s); layout != "" && err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", at, err))
+		}
+	case "integer":
+		if n, ok := value.(float64); !ok || n != math.Trunc(n) {
+			problems = append(problems, fmt.Sprintf("%s: expected integer, got %v", at, value))
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			problems = append(problems, fmt.Sprintf("%s: expected boolean, got %T", at, value))
+		}
+	}
+	return problems
+}
+
+func asSlice(v any) []any {
+	s, _ := v.([]any)
+	return s
+}
+
+func TestOpenAPIDocumentPaths(t *testing.T) {
+	doc := loadOpenAPI(t)
+	if !strings.HasPrefix(doc["openapi"].(string), "3.") {
+		t.Errorf("expected an OpenAPI 3 document, got %v", doc["openapi"])
+	}
+	documented := slices.Sorted(maps.Keys(doc["paths"].(map[string]any)))
+	served := slices.Sorted(maps.Keys((&Application{}).apiHandlers()))
+	if !slices.Equal(documented, served) {
+		t.Errorf("expected documented paths %v to match the handlers %v", documented, served)
+	}
+}
+
+func TestAPIMatchesOpenAPI(t *testing.T) {
+	doc := loadOpenAPI(t)
+	router := testAPI(t)
+
+	tests := []struct {
+		name     string
+		method   string
+		target   string
+		expected int
+	}{
+		{name: "OpenAPI document", method: http.MethodGet, target: "/api/v1/openapi.json", expected: http.StatusOK},
+		{name: "Carriers", method: http.MethodGet, target: "/api/v1/carriers", expected: http.StatusOK},
+		{name: "Carriers wrong method", method: http.MethodPost, target: "/api/v1/carriers?lang=en", expected: http.StatusMethodNotAllowed},
+		{name: "All routes", method: http.MethodGet, target: "/api/v1/routes", expected: http.StatusOK},
+		{name: "Carrier routes", method: http.MethodGet, target: "/api/v1/routes?carrier=os", expected: http.StatusOK},
+		{name: "Unknown carrier routes", method: http.MethodGet, target: "/api/v1/routes?carrier=XX", expected: http.StatusBadRequest},
+		{name: "Operators", method: http.MethodGet, target: "/api/v1/operators", expected: http.StatusOK},
+		{name: "Operator by ICAO code", method: http.MethodGet, target: "/api/v1/operators?code=DLH", expected: http.StatusOK},
+		{name: "Unknown operator", method: http.MethodGet, target: "/api/v1/operators?code=XX", expected: http.StatusNotFound},
+		{name: "Aircraft types", method: http.MethodGet, target: "/api/v1/aircraft", expected: http.StatusOK},
+		{name: "Aircraft type by IATA code", method: http.MethodGet, target: "/api/v1/aircraft?code=32n", expected: http.StatusOK},
+		{name: "Unknown aircraft type", method: http.MethodGet, target: "/api/v1/aircraft?code=XYZ", expected: http.StatusNotFound},
+		{name: "Schedule", method: http.MethodGet, target: "/api/v1/schedules?carrier=LH&origin=KRK&destination=FRA&from=2025-03-30&to=2025-04-26&separate=true&time-mode=both", expected: http.StatusOK},
+		{name: "Schedule of season", method: http.MethodGet, target: "/api/v1/schedules?carrier=LH&season=S25&exceptions=on&merge-dst=1&day-basis=arrival", expected: http.StatusOK},
+		{name: "Schedule without carrier", method: http.MethodGet, target: "/api/v1/schedules?from=2025-03-30&to=2025-04-26", expected: http.StatusBadRequest},
+		{name: "Schedule with invalid date", method: http.MethodGet, target: "/api/v1/schedules?carrier=LH&from=30.03.2025&to=2025-04-26", expected: http.StatusBadRequest},
+		{name: "Schedule with invalid flag", method: http.MethodGet, target: "/api/v1/schedules?carrier=LH&separate=maybe", expected: http.StatusBadRequest},
+		{name: "Schedule with invalid time mode", method: http.MethodGet, target: "/api/v1/schedules?carrier=LH&time-mode=gmt", expected: http.StatusBadRequest},
+		{name: "Schedule of invalid airport", method: http.MethodGet, target: "/api/v1/schedules?carrier=LH&origin=KRK&destination=X1X", expected: http.StatusBadRequest},
+		{name: "Schedule of airport missing from the reference data", method: http.MethodGet, target: "/api/v1/schedules?carrier=LH&origin=KRK&destination=BOS", expected: http.StatusOK},
+		{name: "Schedule fetch failure", method: http.MethodGet, target: "/api/v1/schedules?carrier=LH&origin=KRK&destination=MUC", expected: http.StatusBadGateway},
+		{name: "Capacity", method: http.MethodGet, target: "/api/v1/capacity?carrier=LH&origin=KRK&destination=FRA&from=2025-03-30&to=2025-04-26", expected: http.StatusOK},
+		{name: "Capacity without carrier", method: http.MethodGet, target: "/api/v1/capacity?season=S25", expected: http.StatusBadRequest},
+		{name: "Capacity fetch failure", method: http.MethodGet, target: "/api/v1/capacity?carrier=LH&origin=KRK&destination=MUC", expected: http.StatusBadGateway},
+		{name: "Block times", method: http.MethodGet, target: "/api/v1/blocktimes?carrier=LH&origin=KRK&destination=FRA&season=S25", expected: http.StatusOK},
+		{name: "Block times with invalid season", method: http.MethodGet, target: "/api/v1/blocktimes?carrier=LH&season=summer", expected: http.StatusBadRequest},
+		{name: "Timeline", method: http.MethodGet, target: "/api/v1/timeline?carrier=LH&origin=KRK&destination=FRA&from=2025-03-30&to=2025-04-26&by=day&color=aircraft", expected: http.StatusOK},
+		{name: "Timeline with invalid grouping", method: http.MethodGet, target: "/api/v1/timeline?carrier=LH&by=month", expected: http.StatusBadRequest},
+		{name: "Timeline with invalid format", method: http.MethodGet, target: "/api/v1/timeline?carrier=LH&format=gif", expected: http.StatusBadRequest},
+		{name: "Timeline with invalid colouring", method: http.MethodGet, target: "/api/v1/timeline?carrier=LH&origin=KRK&destination=FRA&from=2025-03-30&to=2025-04-26&color=owner", expected: http.StatusBadRequest},
+		{name: "Connections", method: http.MethodGet, target: "/api/v1/connections?carrier=LH&destination=JFK&from=2025-03-30&to=2025-04-26", expected: http.StatusOK},
+		{name: "Connections without destination", method: http.MethodGet, target: "/api/v1/connections?carrier=LH&from=2025-03-30&to=2025-04-26", expected: http.StatusBadRequest},
+		{name: "Connections of unknown carrier", method: http.MethodGet, target: "/api/v1/connections?carrier=LH,XX&destination=JFK", expected: http.StatusBadRequest},
+		{name: "Connections with invalid format", method: http.MethodGet, target: "/api/v1/connections?destination=JFK&format=pdf", expected: http.StatusBadRequest},
+		{name: "Jobs", method: http.MethodGet, target: "/api/v1/jobs", expected: http.StatusOK},
+		{name: "Unknown path", method: http.MethodGet, target: "/api/v1/flights", expected: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, tt.target, nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, r)
+
+			if w.Code != tt.expected {
+				t.Fatalf("Test %s failed: expected status %d, got %d: %s", tt.name, tt.expected, w.Code, w.Body)
+			}
+			if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+				t.Errorf("Test %s failed: expected JSON, got %q", tt.name, ct)
+			}
+			var body any
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatalf("Test %s failed: invalid JSON body: %v", tt.name, err)
+			}
+
+			item, ok := doc["paths"].(map[string]any)[r.URL.Path].(map[string]any)
+			if !ok {
+				// Undocumented paths only have to answer with the error body
+				errorSchema := map[string]any{"$ref": "#/components/schemas/Error"}
+				for _, problem := range doc.validate(errorSchema, body, "body") {
+					t.Error(problem)
+				}
+				return
+			}
+			operation := item["get"].(map[string]any)
+
+			declared := make(map[string]bool)
+			for _, p := range asSlice(operation["parameters"]) {
+				declared[doc.resolve(p.(map[string]any))["name"].(string)] = true
+			}
+			for name := range r.URL.Query() {
+				if !declared[name] {
+					t.Errorf("Test %s failed: query parameter %s is not documented", tt.name, name)
+				}
+			}
+
+			response, ok := operation["responses"].(map[string]any)[fmt.Sprint(w.Code)].(map[string]any)
+			if !ok {
+				t.Fatalf("Test %s failed: status %d is not documented", tt.name, w.Code)
+			}
+			schema := doc.resolve(response)["content"].(map[string]any)["application/json"].(map[string]any)["schema"].(map[string]any)
+			for _, problem := range doc.validate(schema, body, "body") {
+				t.Error(problem)
+			}
+		})
+	}
+}
+
+func TestAPISchedule(t *testing.T) {
+	router := testAPI(t)
+
+	tests := []struct {
+		name     string
+		target   string
+		expected string
+	}{
+		{
+			name:     "Separated days with UTC times",
+			target:   "/api/v1/schedules?carrier=lh&origin=krk&destination=fra&from=2025-03-30&to=2025-04-26&separate=true&time-mode=both",
+			expected: `{"carrier":"LH","period":{"code":"","from":"2025-03-30","to":"2025-04-26"},"days":["......7","1......",".2.....","..3....","...4...","....5..",".....6."],"warnings":["empty_route LH FRA-KRK"]}`,
+		},
+		{
+			name:     "Season",
+			target:   "/api/v1/schedules?carrier=LH&origin=KRK&destination=FRA&season=S25",
+			expected: `{"carrier":"LH","period":{"code":"S25","from":"2025-03-30","to":"2025-10-25"},"days":["1234567"],"warnings":["empty_route LH FRA-KRK"]}`,
+		},
+		{
+			name:     "Default routes without flights",
+			target:   "/api/v1/schedules?carrier=LH&season=S25",
+			expected: `{"carrier":"LH","period":{"code":"S25","from":"2025-03-30","to":"2025-10-25"},"days":["1234567"],"warnings":["empty_route LH FRA-KRK","empty_route LH KRK-MUC","empty_route LH MUC-KRK"]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.target, nil))
+
+			var schedule struct {
+				Carrier  string                    `json:"carrier"`
+				Period   json.RawMessage           `json:"period"`
+				Records  []internal.ScheduleRecord `json:"records"`
+				Warnings []internal.Warning        `json:"warnings"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &schedule); err != nil {
+				t.Fatalf("Test %s failed: %v: %s", tt.name, err, w.Body)
+			}
+			days := []string{}
+			for _, r := range schedule.Records {
+				days = append(days, r.Days.String())
+			}
+			var warnings []string
+			for _, w := range schedule.Warnings {
+				warnings = append(warnings, fmt.Sprintf("%s %s %s-%s", w.Rule, w.Airline, w.Origin, w.Destination))
+			}
+			got, _ := json.Marshal(struct {
+				Carrier  string          `json:"carrier"`
+				Period   json.RawMessage `json:"period"`
+				Days     []string        `json:"days"`
+				Warnings []string        `json:"warnings,omitempty"`
+			}{schedule.Carrier, schedule.Period, days, warnings})
+			if string(got) != tt.expected {
+				t.Errorf("Test %s failed: expected %s, got %s", tt.name, tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestAPIBlockTimes(t *testing.T) {
+	router := testAPI(t)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/blocktimes?carrier=LH&origin=KRK&destination=FRA&from=2025-03-20&to=2025-04-26", nil))
+
+	var blockTimes struct {
+		BlockTimes []internal.BlockTimeStats `json:"block_times"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &blockTimes); err != nil {
+		t.Fatalf("invalid JSON body: %v: %s", err, w.Body)
+	}
+	// Daily A320neo of 1:45 from Sunday 30 March, the first day of S25
+	if stats := blockTimes.BlockTimes; len(stats) != 1 || stats[0].Season != "S25" || stats[0].Operations != 28 || stats[0].Median != 105 || stats[0].MedianChange != nil {
+		t.Errorf("unexpected block times %+v", stats)
+	}
+}
+
+func TestAPIFetchInBackground(t *testing.T) {
+	doc := loadOpenAPI(t)
+	release := make(chan struct{})
+	var fetches atomic.Int32
+	app := &Application{
+		fetch: func(ctx context.Context, _ []internal.ApiQuery) ([]byte, error) {
+			fetches.Add(1)
+			select {
+			case <-release:
+				return []byte(testResponse), nil
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		},
+		seats:     internal.DefaultSeatTable(),
+		fetches:   internal.NewFetchJobs(context.Background(), time.Minute),
+		fetchWait: 10 * time.Millisecond,
+	}
+	router := http.NewServeMux()
+	app.apiRoutes(router)
+	get := func(target string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+		return w
+	}
+	target := "/api/v1/schedules?carrier=LH&origin=KRK&destination=FRA&from=2025-03-30&to=2025-04-26"
+
+	w := get(target)
+	if w.Code != http.StatusAccepted || w.Header().Get("Location") != "/api/v1/fetches?id=1" {
+		t.Fatalf("expected the running fetch, got %d %q: %s", w.Code, w.Header().Get("Location"), w.Body)
+	}
+	schema := map[string]any{"$ref": "#/components/schemas/Fetch"}
+	var body any
+	json.Unmarshal(w.Body.Bytes(), &body)
+	for _, problem := range doc.validate(schema, body, "body") {
+		t.Error(problem)
+	}
+	// Polling the request again waits for the same fetch
+	if w := get(target); w.Code != http.StatusAccepted || fetches.Load() > 1 {
+		t.Errorf("expected the same running fetch, got %d after %d fetches", w.Code, fetches.Load())
+	}
+
+	close(release)
+	var job internal.FetchJob
+	for range 100 {
+		w = get("/api/v1/fetches?id=1")
+		json.Unmarshal(w.Body.Bytes(), &job)
+		if job.Status != internal.FetchRunning {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if job.Status != internal.FetchDone {
+		t.Fatalf("expected the fetch to be done, got %+v", job)
+	}
+	if w := get(target); w.Code != http.StatusOK || fetches.Load() != 1 {
+		t.Errorf("expected the schedule of the finished fetch, got %d after %d fetches: %s", w.Code, fetches.Load(), w.Body)
+	}
+	if w := get("/api/v1/fetches?id=2"); w.Code != http.StatusNotFound {
+		t.Errorf("expected unknown fetch, got %d", w.Code)
+	}
+}
+
+func TestAPIAircraft(t *testing.T) {
+	router := testAPI(t)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/aircraft?code=A20N", nil))
+
+	var aircraft []internal.AircraftType
+	if err := json.Unmarshal(w.Body.Bytes(), &aircraft); err != nil {
+		t.Fatalf("invalid JSON body: %v: %s", err, w.Body)
+	}
+	if len(aircraft) != 1 || aircraft[0].IATA != "32N" || aircraft[0].WakeCategory != "M" {
+		t.Errorf("unexpected aircraft types %+v", aircraft)
+	}
+}
+
+func TestAPICapacity(t *testing.T) {
+	router := testAPI(t)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/capacity?carrier=LH&origin=KRK&destination=FRA&from=2025-03-30&to=2025-04-26", nil))
+
+	var capacity struct {
+		Weeks []internal.WeeklyCapacity `json:"weeks"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &capacity); err != nil {
+		t.Fatalf("invalid JSON body: %v: %s", err, w.Body)
+	}
+	var got []string
+	for _, week := range capacity.Weeks {
+		got = append(got, fmt.Sprintf("%s %s-%s %s %d %d", week.Carrier, week.Origin, week.Destination, week.Week, week.Frequencies, week.Seats))
+	}
+	// Daily A320neo from Sunday 30 March up to Saturday 26 April
+	expected := []string{
+		"LH KRK-FRA 2025-W13 1 180",
+		"LH KRK-FRA 2025-W14 7 1260",
+		"LH KRK-FRA 2025-W15 7 1260",
+		"LH KRK-FRA 2025-W16 7 1260",
+		"LH KRK-FRA 2025-W17 6 1080",
+	}
+	if !slices.Equal(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+}
+
+func TestAPITimeline(t *testing.T) {
+	router := testAPI(t)
+	tests := []struct {
+		name        string
+		format      string
+		contentType string
+		prefix      string
+	}{
+		{name: "SVG", format: "svg", contentType: "image/svg+xml", prefix: "<svg"},
+		{name: "PNG", format: "png", contentType: "image/png", prefix: "\x89PNG"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/timeline?carrier=LH&origin=KRK&destination=FRA&from=2025-03-30&to=2025-04-26&format="+tt.format, nil))
+			if w.Code != http.StatusOK {
+				t.Fatalf("Test %s failed: expected status 200, got %d: %s", tt.name, w.Code, w.Body)
+			}
+			if ct := w.Header().Get("Content-Type"); ct != tt.contentType {
+				t.Errorf("Test %s failed: expected %q, got %q", tt.name, tt.contentType, ct)
+			}
+			if !strings.HasPrefix(w.Body.String(), tt.prefix) {
+				t.Errorf("Test %s failed: expected body to start with %q", tt.name, tt.prefix)
+			}
+		})
+	}
+}
+
+func TestAPIConnections(t *testing.T) {
+	var fetched []string
+	app := &Application{
+		fetch: func(_ context.Context, queries []internal.ApiQuery) ([]byte, error) {
+			for _, q := range queries {
+				fetched = append(fetched, q.Airline+" "+q.Origin+"-"+q.Destination)
+			}
+			return []byte(`[` +
+				`{"airline":"LH","flightNumber":1365,"periodOfOperationLT":{"startDate":"31MAR25","endDate":"06APR25","daysOfOperation":"1234567"},"legs":[{"origin":"KRK","destination":"FRA","aircraftOwner":"LH","aircraftType":"32N","aircraftDepartureTimeLT":620,"aircraftArrivalTimeLT":725}]},` +
+				`{"airline":"LH","flightNumber":400,"periodOfOperationLT":{"startDate":"31MAR25","endDate":"06APR25","daysOfOperation":"1.3.5.."},"legs":[{"origin":"FRA","destination":"JFK","aircraftOwner":"LH","aircraftType":"388","aircraftDepartureTimeLT":790,"aircraftArrivalTimeLT":940}]}` +
+				`]`), nil
+		},
+		hubs:      internal.DefaultHubTable(),
+		fetches:   internal.NewFetchJobs(context.Background(), time.Minute),
+		fetchWait: 5 * time.Second,
+	}
+	router := http.NewServeMux()
+	app.apiRoutes(router)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/connections?carrier=LH&destination=jfk&from=2025-03-31&to=2025-04-06&format=csv&lang=en", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body)
+	}
+	expected := []string{"LH KRK-FRA", "LH KRK-MUC", "LH FRA-JFK", "LH MUC-JFK"}
+	if !slices.Equal(fetched, expected) {
+		t.Errorf("expected queries %v, got %v", expected, fetched)
+	}
+	lines := strings.Split(strings.TrimSpace(w.Body.String()), "\n")
+	if len(lines) != 2 || !strings.HasPrefix(lines[1], "KRK,FRA,JFK,LH1365,10:20,12:05,LH400,13:10,15:40,65,") || !strings.HasSuffix(lines[1], ",1.3.5..") {
+		t.Errorf("unexpected report %q", lines)
+	}
+}
+
+func TestAPIErrorLocale(t *testing.T) {
+	router := testAPI(t)
+	r := httptest.NewRequest(http.MethodGet, "/api/v1/routes?carrier=XX", nil)
+	r.Header.Set("Accept-Language", "en-GB,en;q=0.9")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+
+	expected := `{"error":{"status":400,"message":"Invalid parameter: unknown carrier \"XX\""}}` + "\n"
+	if w.Body.String() != expected {
+		t.Errorf("expected %s, got %s", expected, w.Body)
+	}
+}
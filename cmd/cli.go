@@ -0,0 +1,590 @@
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jezzaho/goro-web/internal"
+)
+
+const usage = `Usage: goro-web [command] [flags]
+
+Commands:
+  serve        run the web application (default)
+  export       fetch a schedule and write it as CSV, XLSX or JSON
+  diff         compare two stored snapshots
+  import       store saved API responses or exported snapshots
+  connections  find connections to a destination through the hubs
+
+Run goro-web <command> -h for the flags of a command.
+`
+
+var commands = map[string]func(args []string) error{
+	"serve":       serveCommand,
+	"export":      exportCommand,
+	"diff":        diffCommand,
+	"import":      importCommand,
+	"connections": connectionsCommand,
+}
+
+// run dispatches to the subcommand, serving when none is given.
+func run(args []string) error {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		if err := loadReferences(); err != nil {
+			return err
+		}
+		return serveCommand(args)
+	}
+	if args[0] == "help" {
+		fmt.Print(usage)
+		return nil
+	}
+	command, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprint(os.Stderr, usage)
+		return fmt.Errorf("unknown command %q", args[0])
+	}
+	if err := loadReferences(); err != nil {
+		return err
+	}
+	return command(args[1:])
+}
+
+func templatesFile() string {
+	return cmp.Or(os.Getenv("TEMPLATES_FILE"), "templates.json")
+}
+
+func snapshotsFile() string {
+	return cmp.Or(os.Getenv("SNAPSHOTS_FILE"), "snapshots.db")
+}
+
+func seatsFile() string {
+	return cmp.Or(os.Getenv("SEATS_FILE"), "seats.csv")
+}
+
+func hubsFile() string {
+	return cmp.Or(os.Getenv("HUBS_FILE"), "hubs.csv")
+}
+
+func operatorsFile() string {
+	return cmp.Or(os.Getenv("OPERATORS_FILE"), "operators.csv")
+}
+
+func aircraftFile() string {
+	return cmp.Or(os.Getenv("AIRCRAFT_FILE"), "aircraft.csv")
+}
+
+// loadReferences replaces the embedded operators and aircraft types with
+// those of the reference files for every command.
+func loadReferences() error {
+	references, err := internal.LoadReferenceData(operatorsFile(), aircraftFile())
+	if err != nil {
+		return fmt.Errorf("loading reference data: %w", err)
+	}
+	internal.SetReferences(references)
+	return nil
+}
+
+// scheduleParams select what is fetched and how the records are normalized,
+// given as flags or API query parameters named like the form fields of the
+// web page.
+type scheduleParams struct {
+	carrier     string
+	from        string
+	to          string
+	season      string
+	origin      string
+	destination string
+	timeMode    string
+	dayBasis    string
+	separate    bool
+	exceptions  bool
+	mergeDST    bool
+}
+
+func (f *scheduleParams) register(flags *flag.FlagSet) {
+	flags.StringVar(&f.carrier, "carrier", "LH", "carrier code: "+strings.Join(internal.Carriers, ", "))
+	flags.StringVar(&f.from, "from", "", "first day, YYYY-MM-DD")
+	flags.StringVar(&f.to, "to", "", "last day, YYYY-MM-DD")
+	flags.StringVar(&f.season, "season", "", "season instead of dates: current, next or a code such as S25")
+	flags.StringVar(&f.origin, "origin", "", "origin airport, replaces the default routes of the carrier")
+	flags.StringVar(&f.destination, "destination", "", "destination airport")
+	flags.StringVar(&f.timeMode, "time-mode", "lt", "times: lt, utc or both")
+	flags.StringVar(&f.dayBasis, "day-basis", "departure", "days follow the departure or arrival")
+	flags.BoolVar(&f.separate, "separate", false, "one record per weekday")
+	flags.BoolVar(&f.exceptions, "exceptions", false, "report cancelled days as exceptions")
+	flags.BoolVar(&f.mergeDST, "merge-dst", false, "merge periods split by a daylight saving time switch")
+}
+
+// period returns the season or the dates given, the current season when neither is.
+func (f *scheduleParams) period() (internal.Season, error) {
+	if f.from == "" && f.to == "" {
+		return f.job().Period(time.Now())
+	}
+	if f.season != "" {
+		return internal.Season{}, errors.New("season cannot be combined with from and to")
+	}
+	return parsePeriod(f.from, f.to)
+}
+
+// periodGiven reports whether dates or a season were given.
+func (f *scheduleParams) periodGiven() bool {
+	return f.from != "" || f.to != "" || f.season != ""
+}
+
+// parsePeriod reads the first and last day given as YYYY-MM-DD.
+func parsePeriod(fromDate, toDate string) (internal.Season, error) {
+	from, err := time.Parse("2006-01-02", fromDate)
+	if err != nil {
+		return internal.Season{}, fmt.Errorf("invalid from date: %w", err)
+	}
+	to, err := time.Parse("2006-01-02", toDate)
+	if err != nil {
+		return internal.Season{}, fmt.Errorf("invalid to date: %w", err)
+	}
+	if to.Before(from) {
+		return internal.Season{}, errors.New("to date is before from date")
+	}
+	return internal.Season{Start: from, End: to}, nil
+}
+
+func (f *scheduleParams) job() internal.WatchJob {
+	mode, _ := internal.ParseTimeMode(f.timeMode)
+	return internal.WatchJob{
+		Carrier:     strings.ToUpper(f.carrier),
+		Origin:      f.origin,
+		Destination: f.destination,
+		Season:      f.season,
+		TimeMode:    mode,
+		Separate:    f.separate,
+	}
+}
+
+// connectionOrigin returns the origin of the connections, the airport the
+// default routes start at unless another one is given.
+func (f *scheduleParams) connectionOrigin() string {
+	return cmp.Or(strings.ToUpper(strings.TrimSpace(f.origin)), "KRK")
+}
+
+// connectionQueries returns the queries of the connections from the origin
+// to the destination through the hubs of the table, by the carriers given
+// as a comma separated list or by all carriers, with the period they cover.
+func (f *scheduleParams) connectionQueries(hubs internal.HubTable) ([]internal.ApiQuery, internal.Season, error) {
+	period, err := f.period()
+	if err != nil {
+		return nil, internal.Season{}, err
+	}
+	mode, err := internal.ParseTimeMode(f.timeMode)
+	if err != nil {
+		return nil, internal.Season{}, err
+	}
+	carriers := internal.Carriers
+	if f.carrier != "" {
+		carriers = strings.Split(f.carrier, ",")
+	}
+	queries, err := internal.ConnectionQueries(carriers, hubs, f.connectionOrigin(), f.destination, period, mode)
+	return queries, period, err
+}
+
+// checkCarrier rejects a carrier without default routes, which would
+// otherwise fetch nothing.
+func (f *scheduleParams) checkCarrier() error {
+	if _, ok := internal.CarrierCode(strings.ToUpper(f.carrier)); !ok {
+		return fmt.Errorf("unknown carrier %q, expected one of %s", f.carrier, strings.Join(internal.Carriers, ", "))
+	}
+	return nil
+}
+
+func (f *scheduleParams) exportOptions() (internal.ExportOptions, error) {
+	mode, err := internal.ParseTimeMode(f.timeMode)
+	if err != nil {
+		return internal.ExportOptions{}, err
+	}
+	basis, err := internal.ParseDayBasis(f.dayBasis)
+	if err != nil {
+		return internal.ExportOptions{}, err
+	}
+	return internal.ExportOptions{
+		Separate:   f.separate,
+		Exceptions: f.exceptions,
+		TimeMode:   mode,
+		DayBasis:   basis,
+		MergeDST:   f.mergeDST,
+	}, nil
+}
+
+// commandParams returns the season and the flags given on the command line
+// that the web page keeps with its snapshots.
+func commandParams(flags *flag.FlagSet, period internal.Season) map[string]string {
+	params := make(map[string]string)
+	if period.Code != "" {
+		params["season"] = period.Code
+	}
+	flags.Visit(func(f *flag.Flag) {
+		if slices.Contains(snapshotParams, f.Name) {
+			params[f.Name] = f.Value.String()
+		}
+	})
+	return params
+}
+
+// formatFlags select the language and format of the written reports.
+type formatFlags struct {
+	format     string
+	lang       string
+	delimiter  string
+	dateFormat string
+	output     string
+}
+
+func (f *formatFlags) register(flags *flag.FlagSet) {
+	flags.StringVar(&f.format, "format", "csv", "output format: csv, xlsx or json")
+	flags.StringVar(&f.lang, "lang", "", "language of the headers: "+fmt.Sprint(internal.Locales))
+	flags.StringVar(&f.delimiter, "delimiter", "", "CSV delimiter: semicolon, comma or tab, language default when empty")
+	flags.StringVar(&f.dateFormat, "date-format", "", "date format: iso or pl, language default when empty")
+	flags.StringVar(&f.output, "o", "-", "output file, - for standard output")
+}
+
+func (f *formatFlags) apply(opts *internal.ExportOptions) error {
+	if !slices.Contains([]string{"csv", "xlsx", "json"}, f.format) {
+		return fmt.Errorf("unknown format %q", f.format)
+	}
+	delimiter, ok := delimiterOptions[f.delimiter]
+	if !ok && f.delimiter != "" {
+		return fmt.Errorf("unknown delimiter %q", f.delimiter)
+	}
+	dateLayout, ok := dateFormatOptions[f.dateFormat]
+	if !ok && f.dateFormat != "" {
+		return fmt.Errorf("unknown date format %q", f.dateFormat)
+	}
+	opts.Locale = internal.ParseLocale(f.lang, "")
+	opts.Delimiter = delimiter
+	opts.DateLayout = dateLayout
+	return nil
+}
+
+// write creates the output file, or uses standard output, and writes to it.
+func (f *formatFlags) write(write func(w io.Writer) error) error {
+	if f.output == "-" {
+		return write(os.Stdout)
+	}
+	file, err := os.Create(f.output)
+	if err != nil {
+		return err
+	}
+	if err := write(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+func readInput(name string) ([]byte, error) {
+	if name == "-" {
+		return io.ReadAll(os.Stdin)
+	}
+	return os.ReadFile(name)
+}
+
+func exportCommand(args []string) error {
+	flags := flag.NewFlagSet("export", flag.ContinueOnError)
+	var schedule scheduleParams
+	schedule.register(flags)
+	var format formatFlags
+	format.register(flags)
+	templateName := flags.String("template", "", "saved export template")
+	columns := flags.String("columns", "", "comma separated column keys, overrides -template")
+	input := flags.String("input", "", "saved API response to export instead of fetching, - for standard input")
+	save := flags.Bool("save", false, "store the export as a snapshot")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() > 0 {
+		return fmt.Errorf("unexpected arguments %v", flags.Args())
+	}
+	if err := schedule.checkCarrier(); err != nil {
+		return err
+	}
+
+	opts, err := schedule.exportOptions()
+	if err != nil {
+		return err
+	}
+	if err := format.apply(&opts); err != nil {
+		return err
+	}
+	opts.Template = internal.ParseColumnList(*columns)
+	if len(opts.Template.Columns) == 0 {
+		if opts.Template, err = internal.NewTemplateStore(templatesFile()).Get(*templateName); err != nil {
+			return err
+		}
+	}
+	if err := opts.Template.Validate(); err != nil {
+		return err
+	}
+	period, err := schedule.period()
+	if err != nil {
+		return err
+	}
+	// A saved response covers its own period unless one is given
+	periodOfRecords := *input != "" && !schedule.periodGiven()
+
+	// Saved responses have no queries, their routes are not expected
+	var data []byte
+	var queries []internal.ApiQuery
+	if *input != "" {
+		data, err = readInput(*input)
+	} else {
+		if queries, err = schedule.job().Queries(period); err != nil {
+			return err
+		}
+		data, err = fetchSchedule(context.Background(), queries)
+	}
+	if err != nil {
+		return err
+	}
+	rules := internal.DefaultRules(queries)
+	records, warnings, err := internal.PrepareValidatedRecords(data, opts, rules)
+	if err != nil {
+		return err
+	}
+	opts.Warnings = warnings
+	for _, warning := range opts.Warnings {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", warning.Message(opts.Locale))
+	}
+	if periodOfRecords {
+		var ok bool
+		if period, ok = recordsPeriod(records); !ok {
+			return errors.New("no records in the input, give its period with -from and -to or -season")
+		}
+	}
+
+	snap := newSnapshot(strings.ToUpper(schedule.carrier), period.Start, period.End, commandParams(flags, period), records)
+	snap.Warnings = opts.Warnings
+	if *save {
+		store, err := internal.OpenSnapshotStore(snapshotsFile())
+		if err != nil {
+			return err
+		}
+		defer store.Close()
+		if err := store.Save(&snap); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "Saved snapshot %d\n", snap.ID)
+	}
+
+	return format.write(func(w io.Writer) error {
+		switch format.format {
+		case "xlsx":
+			return internal.WriteRecordsXLSX(w, records, opts)
+		case "json":
+			return json.NewEncoder(w).Encode(snap)
+		default:
+			_, err := internal.CreateCSVFromResponse(w, data, opts, rules)
+			return err
+		}
+	})
+}
+
+// recordsPeriod returns the period from the first to the last day of the
+// records, ok is false without records.
+func recordsPeriod(records []internal.ScheduleRecord) (period internal.Season, ok bool) {
+	for i, r := range records {
+		if i == 0 || r.StartDate.Before(period.Start) {
+			period.Start = r.StartDate
+		}
+		if i == 0 || r.EndDate.After(period.End) {
+			period.End = r.EndDate
+		}
+	}
+	return period, len(records) > 0
+}
+
+func diffCommand(args []string) error {
+	flags := flag.NewFlagSet("diff", flag.ContinueOnError)
+	var format formatFlags
+	format.register(flags)
+	carrier := flags.String("carrier", "", "compare the latest snapshot of the carrier, of any carrier when empty, with the latest earlier one of the same query")
+	fromID := flags.Uint64("from", 0, "ID of the older snapshot")
+	toID := flags.Uint64("to", 0, "ID of the newer snapshot")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	var opts internal.ExportOptions
+	if err := format.apply(&opts); err != nil {
+		return err
+	}
+	if (*fromID == 0) != (*toID == 0) {
+		return errors.New("-from and -to must be given together")
+	}
+
+	store, err := internal.OpenSnapshotStore(snapshotsFile())
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	var from, to internal.Snapshot
+	if *fromID == 0 {
+		if from, to, err = store.LatestPair(strings.ToUpper(*carrier)); err != nil {
+			return err
+		}
+	} else {
+		if from, err = store.Get(*fromID); err != nil {
+			return fmt.Errorf("snapshot %d: %w", *fromID, err)
+		}
+		if to, err = store.Get(*toID); err != nil {
+			return fmt.Errorf("snapshot %d: %w", *toID, err)
+		}
+	}
+
+	changes := internal.DiffRecords(from.Records, to.Records)
+	return format.write(func(w io.Writer) error {
+		switch format.format {
+		case "xlsx":
+			return internal.WriteChangesXLSX(w, changes, opts)
+		case "json":
+			return json.NewEncoder(w).Encode(changes)
+		default:
+			return internal.WriteChangesCSV(w, changes, opts)
+		}
+	})
+}
+
+// importCommand stores files as snapshots. A file holds either a saved API
+// response, normalized with the schedule flags, or a snapshot written by
+// export -format json or GET /snapshots/{id}.
+func importCommand(args []string) error {
+	flags := flag.NewFlagSet("import", flag.ContinueOnError)
+	var schedule scheduleParams
+	schedule.register(flags)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() == 0 {
+		return errors.New("no files to import")
+	}
+	if err := schedule.checkCarrier(); err != nil {
+		return err
+	}
+	opts, err := schedule.exportOptions()
+	if err != nil {
+		return err
+	}
+
+	store, err := internal.OpenSnapshotStore(snapshotsFile())
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	for _, name := range flags.Args() {
+		data, err := readInput(name)
+		if err != nil {
+			return err
+		}
+		var snap internal.Snapshot
+		if bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+			if err := json.Unmarshal(data, &snap); err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+		} else {
+			period, err := schedule.period()
+			if err != nil {
+				return err
+			}
+			records, err := internal.PrepareRecords(data, opts)
+			if err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+			// The response covers its own period unless one is given
+			if !schedule.periodGiven() {
+				var ok bool
+				if period, ok = recordsPeriod(records); !ok {
+					return fmt.Errorf("%s: no records, give its period with -from and -to or -season", name)
+				}
+			}
+			snap = newSnapshot(strings.ToUpper(schedule.carrier), period.Start, period.End, commandParams(flags, period), records)
+		}
+		if err := store.Save(&snap); err != nil {
+			return err
+		}
+		fmt.Printf("Imported %s as snapshot %d\n", name, snap.ID)
+	}
+	return nil
+}
+
+// connectionsCommand fetches the default routes of the carriers into the
+// hubs and the onward flights to the destination and writes the connections
+// between them.
+func connectionsCommand(args []string) error {
+	flags := flag.NewFlagSet("connections", flag.ContinueOnError)
+	var schedule scheduleParams
+	flags.StringVar(&schedule.carrier, "carriers", "", "comma separated carriers of the flights, all when empty")
+	flags.StringVar(&schedule.from, "from", "", "first day, YYYY-MM-DD")
+	flags.StringVar(&schedule.to, "to", "", "last day, YYYY-MM-DD")
+	flags.StringVar(&schedule.season, "season", "", "season instead of dates: current, next or a code such as S25")
+	flags.StringVar(&schedule.origin, "origin", "KRK", "origin airport of the default routes")
+	flags.StringVar(&schedule.destination, "destination", "", "final destination airport")
+	flags.StringVar(&schedule.timeMode, "time-mode", "lt", "times: lt, utc or both")
+	var format formatFlags
+	format.register(flags)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() > 0 {
+		return fmt.Errorf("unexpected arguments %v", flags.Args())
+	}
+
+	opts, err := schedule.exportOptions()
+	if err != nil {
+		return err
+	}
+	if err := format.apply(&opts); err != nil {
+		return err
+	}
+	hubs, err := internal.LoadHubTable(hubsFile())
+	if err != nil {
+		return fmt.Errorf("loading hub table: %w", err)
+	}
+	queries, period, err := schedule.connectionQueries(hubs)
+	if err != nil {
+		return err
+	}
+	data, err := fetchSchedule(context.Background(), queries)
+	if err != nil {
+		return err
+	}
+	records, err := internal.PrepareRecords(data, opts)
+	if err != nil {
+		return err
+	}
+	connections, err := internal.BuildConnections(records, schedule.connectionOrigin(), schedule.destination, hubs, period.Start, period.End)
+	if err != nil {
+		return err
+	}
+
+	return format.write(func(w io.Writer) error {
+		switch format.format {
+		case "xlsx":
+			return internal.WriteConnectionsXLSX(w, connections, opts)
+		case "json":
+			if connections == nil {
+				connections = []internal.Connection{}
+			}
+			return json.NewEncoder(w).Encode(connections)
+		default:
+			return internal.WriteConnectionsCSV(w, connections, opts)
+		}
+	})
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jezzaho/goro-web/internal"
+)
+
+func TestExportCommandInput(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("TEMPLATES_FILE", filepath.Join(dir, "templates.json"))
+	input := filepath.Join(dir, "response.json")
+	if err := os.WriteFile(input, []byte(testResponse), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Without dates the snapshot covers the period of the saved response
+	output := filepath.Join(dir, "export.json")
+	if err := exportCommand([]string{"-input", input, "-format", "json", "-o", output}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	data, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var snap internal.Snapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		t.Fatalf("invalid snapshot: %v", err)
+	}
+	if from, to := snap.From.Format("2006-01-02"), snap.To.Format("2006-01-02"); from != "2025-03-30" || to != "2025-04-26" {
+		t.Errorf("expected the period of the records, got %s to %s", from, to)
+	}
+
+	output = filepath.Join(dir, "export.csv")
+	if err := exportCommand([]string{"-input", input, "-lang", "en", "-o", output}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if data, _ := os.ReadFile(output); !strings.Contains(string(data), "KRK") {
+		t.Errorf("expected the records in the CSV, got %q", data)
+	}
+}
@@ -1,9 +1,13 @@
 package main
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/jezzaho/goro-web/internal"
@@ -13,17 +17,66 @@ var progressChan = make(chan int)
 
 const postURL = "https://api.lufthansa.com/v1/oauth/token"
 
+// Form values for the delimiter and date format selects, empty means locale default
+var delimiterOptions = map[string]rune{
+	"semicolon": ';',
+	"comma":     ',',
+	"tab":       '\t',
+}
+
+var dateFormatOptions = map[string]string{
+	"iso": "2006-01-02",
+	"pl":  "02.01.2006",
+}
+
+// Locale from the lang form/query value, falling back to Accept-Language
+func requestLocale(r *http.Request) internal.Locale {
+	return internal.ParseLocale(r.FormValue("lang"), r.Header.Get("Accept-Language"))
+}
+
 func (app *Application) MockHandler(w http.ResponseWriter, r *http.Request) {
+	locale := requestLocale(r)
 	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		http.Error(w, internal.T(locale, "error.method_not_allowed"), http.StatusMethodNotAllowed)
+		return
+	}
+	snap, opts, ok := app.exportFromForm(w, r)
+	if !ok {
+		return
+	}
+	w.Header().Set("Content-Type", "text/csv")
+
+	// Headerss
+
+	currentDate := time.Now().Format("20060102")
+	filename := fmt.Sprintf("%s_%s.csv", currentDate, snap.Carrier)
+	w.Header().Set("Content-Disposition", "attachment; filename=\""+filename+"\"")
+	w.Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
+	w.Header().Set("Pragma", "no-cache")
+	w.Header().Set("Expires", "0")
+	// CSV has no room for the warnings, the preview and XLSX export list them
+	w.Header().Set("X-Validation-Warnings", strconv.Itoa(len(opts.Warnings)))
+
+	if err := internal.WriteCSV(w, snap.Records, opts); err != nil {
+		log.Printf("Error creating CSV: %v", err)
+		http.Error(w, internal.T(locale, "error.csv", err), http.StatusInternalServerError)
 		return
 	}
+
+}
+
+// exportFromForm fetches and normalizes the schedule requested by the page
+// form and stores it as a snapshot. Errors are written to w and reported by
+// a false ok.
+func (app *Application) exportFromForm(w http.ResponseWriter, r *http.Request) (snap internal.Snapshot, opts internal.ExportOptions, ok bool) {
+	locale := requestLocale(r)
 	// From parsing
 	err := r.ParseForm()
 	if err != nil {
 		log.Println("Error during Form Parsing: ", err)
+		http.Error(w, internal.T(locale, "error.form"), http.StatusBadRequest)
+		return
 	}
-	log.Println(r.Form)
 	// Access the query parameters
 	// Carrier Format in two letters
 	carrier := r.FormValue("carrier")
@@ -31,75 +84,380 @@ func (app *Application) MockHandler(w http.ResponseWriter, r *http.Request) {
 	dateTo := r.FormValue("date-to")
 	separate := r.FormValue("separate")
 
+	// Explicit column list from the form editor wins over a saved template
+	template := internal.ParseColumnList(r.FormValue("columns"))
+	if len(template.Columns) == 0 {
+		template, err = app.templates.Get(r.FormValue("template"))
+		if err != nil {
+			http.Error(w, internal.T(locale, "error.template", err), http.StatusBadRequest)
+			return
+		}
+	}
+	if err := template.Validate(); err != nil {
+		http.Error(w, internal.T(locale, "error.template", err), http.StatusBadRequest)
+		return
+	}
+	timeMode, err := internal.ParseTimeMode(r.FormValue("time-mode"))
+	if err != nil {
+		http.Error(w, internal.T(locale, "error.form"), http.StatusBadRequest)
+		return
+	}
+	dayBasis, err := internal.ParseDayBasis(r.FormValue("day-basis"))
+	if err != nil {
+		http.Error(w, internal.T(locale, "error.form"), http.StatusBadRequest)
+		return
+	}
+
 	// Carrier check for Query
-	var carrierNumber int
-
-	switch carrier {
-	case "LH":
-		carrierNumber = 0
-	case "OS":
-		carrierNumber = 1
-	case "LX":
-		carrierNumber = 2
-	case "SN":
-		carrierNumber = 3
-	case "EN":
-		carrierNumber = 4
-	default:
-		carrierNumber = 0
+	carrierNumber, known := internal.CarrierCode(carrier)
+	if !known {
+		http.Error(w, internal.T(locale, "error.carrier", carrier), http.StatusBadRequest)
+		return
 	}
 
-	log.Println(carrierNumber)
+	period, err := parsePeriod(dateFrom, dateTo)
+	if err != nil {
+		http.Error(w, internal.T(locale, "error.dates", err), http.StatusBadRequest)
+		return
+	}
 	dateFromSSIM := internal.DateToSSIM(dateFrom)
 	dateToSSIM := internal.DateToSSIM(dateTo)
-	log.Println(dateFromSSIM)
-	log.Println(dateToSSIM)
 	separateBool := false
 	if separate == "on" {
 		separateBool = true
 	}
-	log.Println(separateBool)
 
-	auth, err := internal.PostForAuth(http.DefaultClient, postURL)
+	query := internal.GetQueryListForAirline(carrierNumber, dateFromSSIM, dateToSSIM, timeMode)
+	// A route given on the form replaces the default routes of the carrier
+	if origin, destination := r.FormValue("origin"), r.FormValue("destination"); origin != "" || destination != "" {
+		query = []internal.ApiQuery{{
+			Airline:         carrier,
+			StartDate:       dateFromSSIM,
+			EndDate:         dateToSSIM,
+			DaysOfOperation: "1234567",
+			TimeMode:        timeMode.Query(),
+			Origin:          strings.ToUpper(strings.TrimSpace(origin)),
+			Destination:     strings.ToUpper(strings.TrimSpace(destination)),
+		}}
+	}
+	for _, q := range query {
+		if err := q.Validate(); err != nil {
+			http.Error(w, internal.T(locale, "error.route", err), http.StatusBadRequest)
+			return
+		}
+	}
+
+	progressChan <- 33
+	data, err := app.fetch(r.Context(), query)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		log.Printf("Fetch failed: %v", err)
+		progressChan <- 100
+		http.Error(w, internal.T(locale, "error.fetch", err), http.StatusServiceUnavailable)
 		return
 	}
-	query := internal.GetQueryListForAirline(carrierNumber, dateFromSSIM, dateToSSIM)
-	progressChan <- 33
-	data := internal.GetApiData(query, auth)
 	progressChan <- 66
-	data = internal.FlattenJSON(data)
 	progressChan <- 100
-	w.Header().Set("Content-Type", "text/csv")
 
-	// Headerss
+	opts = internal.ExportOptions{
+		Separate:   separateBool,
+		Exceptions: r.FormValue("exceptions") == "on",
+		TimeMode:   timeMode,
+		DayBasis:   dayBasis,
+		MergeDST:   r.FormValue("merge-dst") == "on",
+		Template:   template,
+		Locale:     locale,
+		Delimiter:  delimiterOptions[r.FormValue("delimiter")],
+		DateLayout: dateFormatOptions[r.FormValue("date-format")],
+	}
+	records, warnings, err := internal.PrepareValidatedRecords(data, opts, internal.DefaultRules(query))
+	if err != nil {
+		log.Printf("Error creating CSV: %v", err)
+		http.Error(w, internal.T(locale, "error.csv", err), http.StatusInternalServerError)
+		return
+	}
+	opts.Warnings = warnings
+	if len(opts.Warnings) > 0 {
+		log.Printf("Export of %s raised %d validation warnings", carrier, len(opts.Warnings))
+	}
+	return app.saveSnapshot(carrier, period.Start, period.End, requestParams(r), records, opts.Warnings), opts, true
+}
 
-	currentDate := time.Now().Format("20060102")
-	filename := fmt.Sprintf("%s_%s.csv", currentDate, carrier)
-	w.Header().Set("Content-Disposition", "attachment; filename=\""+filename+"\"")
-	w.Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
-	w.Header().Set("Pragma", "no-cache")
-	w.Header().Set("Expires", "0")
+// Options of an export kept with its snapshot
+var snapshotParams = []string{"time-mode", "day-basis", "separate", "exceptions", "merge-dst", "origin", "destination", "template", "columns", "delimiter", "date-format"}
 
-	fmt.Printf("DATA: \n%v+", string(data))
-	// Use the modified CreateCSVFromResponse function
-	if err := internal.CreateCSVFromResponse(w, data, separateBool); err != nil {
-		log.Printf("Error creating CSV: %v", err)
-		http.Error(w, "Error creating CSV: "+err.Error(), http.StatusInternalServerError)
+// requestParams returns the options of the request kept with its snapshot.
+func requestParams(r *http.Request) map[string]string {
+	params := make(map[string]string)
+	for _, p := range snapshotParams {
+		if v := r.FormValue(p); v != "" {
+			params[p] = v
+		}
+	}
+	return params
+}
+
+// saveSnapshot stores the exported records, failures are only logged so the
+// download still succeeds and the returned snapshot has no ID.
+func (app *Application) saveSnapshot(carrier string, from, to time.Time, params map[string]string, records []internal.ScheduleRecord, warnings []internal.Warning) internal.Snapshot {
+	snap := newSnapshot(carrier, from, to, params, records)
+	snap.Warnings = warnings
+	if app.snapshots == nil {
+		return snap
+	}
+	if err := app.snapshots.Save(&snap); err != nil {
+		log.Printf("Error saving snapshot: %v", err)
+		snap.ID = 0
+	}
+	return snap
+}
+
+func newSnapshot(carrier string, from, to time.Time, params map[string]string, records []internal.ScheduleRecord) internal.Snapshot {
+	return internal.Snapshot{
+		SnapshotMeta: internal.SnapshotMeta{Carrier: carrier, From: from, To: to, Params: params},
+		Records:      records,
+	}
+}
+
+// Stored snapshots of the carrier query value, all carriers when missing
+func (app *Application) SnapshotsHandler(w http.ResponseWriter, r *http.Request) {
+	locale := requestLocale(r)
+	if r.Method != http.MethodGet {
+		http.Error(w, internal.T(locale, "error.method_not_allowed"), http.StatusMethodNotAllowed)
+		return
+	}
+	snapshots, err := app.snapshots.List(r.URL.Query().Get("carrier"))
+	if err != nil {
+		http.Error(w, internal.T(locale, "error.internal", err), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, snapshots)
+}
+
+// Single snapshot with its records, or its removal
+func (app *Application) SnapshotHandler(w http.ResponseWriter, r *http.Request) {
+	locale := requestLocale(r)
+	id, err := strconv.ParseUint(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, internal.T(locale, "error.snapshot_not_found"), http.StatusNotFound)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		snap, err := app.snapshots.Get(id)
+		if err != nil {
+			writeSnapshotError(w, locale, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, snap)
+	case http.MethodDelete:
+		if err := app.snapshots.Delete(id); err != nil {
+			writeSnapshotError(w, locale, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, internal.T(locale, "error.method_not_allowed"), http.StatusMethodNotAllowed)
+	}
+}
+
+// Changes between two snapshots given by the from and to IDs, or between the
+// latest snapshot of the carrier and the latest earlier one with the same
+// query, as CSV, XLSX or JSON
+func (app *Application) DiffHandler(w http.ResponseWriter, r *http.Request) {
+	locale := requestLocale(r)
+	if r.Method != http.MethodGet {
+		http.Error(w, internal.T(locale, "error.method_not_allowed"), http.StatusMethodNotAllowed)
 		return
 	}
+	query := r.URL.Query()
 
+	var from, to internal.Snapshot
+	if query.Get("from") == "" && query.Get("to") == "" {
+		var err error
+		from, to, err = app.snapshots.LatestPair(strings.ToUpper(query.Get("carrier")))
+		if errors.Is(err, internal.ErrNoEarlierSnapshot) {
+			http.Error(w, internal.T(locale, "error.diff", err), http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			writeSnapshotError(w, locale, err)
+			return
+		}
+	} else {
+		fromID, err := strconv.ParseUint(query.Get("from"), 10, 64)
+		if err != nil {
+			http.Error(w, internal.T(locale, "error.diff", err), http.StatusBadRequest)
+			return
+		}
+		toID, err := strconv.ParseUint(query.Get("to"), 10, 64)
+		if err != nil {
+			http.Error(w, internal.T(locale, "error.diff", err), http.StatusBadRequest)
+			return
+		}
+		if from, err = app.snapshots.Get(fromID); err != nil {
+			writeSnapshotError(w, locale, err)
+			return
+		}
+		if to, err = app.snapshots.Get(toID); err != nil {
+			writeSnapshotError(w, locale, err)
+			return
+		}
+	}
+
+	changes := internal.DiffRecords(from.Records, to.Records)
+	opts := internal.ExportOptions{
+		Locale:     locale,
+		Delimiter:  delimiterOptions[query.Get("delimiter")],
+		DateLayout: dateFormatOptions[query.Get("date-format")],
+	}
+	filename := fmt.Sprintf("changes_%d_%d", from.ID, to.ID)
+
+	switch query.Get("format") {
+	case "json":
+		writeJSON(w, http.StatusOK, changes)
+	case "xlsx":
+		w.Header().Set("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+		w.Header().Set("Content-Disposition", "attachment; filename=\""+filename+".xlsx\"")
+		if err := internal.WriteChangesXLSX(w, changes, opts); err != nil {
+			log.Printf("Error writing changes report: %v", err)
+		}
+	case "", "csv":
+		w.Header().Set("Content-Type", "text/csv")
+		w.Header().Set("Content-Disposition", "attachment; filename=\""+filename+".csv\"")
+		if err := internal.WriteChangesCSV(w, changes, opts); err != nil {
+			log.Printf("Error writing changes report: %v", err)
+		}
+	default:
+		http.Error(w, internal.T(locale, "error.diff", "unknown format "+query.Get("format")), http.StatusBadRequest)
+	}
+}
+
+func writeSnapshotError(w http.ResponseWriter, locale internal.Locale, err error) {
+	if errors.Is(err, internal.ErrSnapshotNotFound) {
+		http.Error(w, internal.T(locale, "error.snapshot_not_found"), http.StatusNotFound)
+		return
+	}
+	http.Error(w, internal.T(locale, "error.internal", err), http.StatusInternalServerError)
+}
+
+// Data passed to the index page template
+type indexPage struct {
+	Locale  internal.Locale
+	Locales []internal.Locale
+	// Messages used by the page scripts
+	JSMessages map[string]string
+	Seasons    []internal.Season
+}
+
+func (p indexPage) T(key string) string {
+	return internal.T(p.Locale, key)
 }
 
 func (app *Application) IndexHandler(w http.ResponseWriter, r *http.Request) {
 	if r.URL.Path == "/" {
-		http.ServeFile(w, r, "static/index.html")
+		locale := requestLocale(r)
+		page := indexPage{
+			Locale:     locale,
+			Locales:    internal.Locales,
+			JSMessages: internal.Messages(locale, "js."),
+			Seasons:    internal.UpcomingSeasons(time.Now(), defaultSeasonCount),
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		if err := app.index.Execute(w, page); err != nil {
+			log.Printf("Error rendering index page: %v", err)
+		}
+		return
+	}
+	// The pages are templates rendered by their handlers, never served raw
+	if r.URL.Path == "/index.html" {
+		http.Redirect(w, r, "/", http.StatusMovedPermanently)
+		return
+	}
+	if strings.HasSuffix(r.URL.Path, ".html") {
+		http.NotFound(w, r)
 		return
 	}
 	app.fs.ServeHTTP(w, r)
 }
 
+// Seasons after the current one offered on the form and by /seasons
+const (
+	defaultSeasonCount = 2
+	maxSeasonCount     = 10
+)
+
+// Current and next IATA seasons, their number set by the count query value
+func (app *Application) SeasonsHandler(w http.ResponseWriter, r *http.Request) {
+	locale := requestLocale(r)
+	if r.Method != http.MethodGet {
+		http.Error(w, internal.T(locale, "error.method_not_allowed"), http.StatusMethodNotAllowed)
+		return
+	}
+	count := defaultSeasonCount
+	if v := r.URL.Query().Get("count"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 || n > maxSeasonCount {
+			http.Error(w, internal.T(locale, "error.form"), http.StatusBadRequest)
+			return
+		}
+		count = n
+	}
+	writeJSON(w, http.StatusOK, internal.UpcomingSeasons(time.Now(), count))
+}
+
+type columnInfo struct {
+	Key    string `json:"key"`
+	Header string `json:"header"`
+}
+
+// Columns available for export templates with headers in the request locale
+func (app *Application) ColumnsHandler(w http.ResponseWriter, r *http.Request) {
+	locale := requestLocale(r)
+	if r.Method != http.MethodGet {
+		http.Error(w, internal.T(locale, "error.method_not_allowed"), http.StatusMethodNotAllowed)
+		return
+	}
+	columns := make([]columnInfo, 0, len(internal.Columns))
+	for _, c := range internal.Columns {
+		columns = append(columns, columnInfo{Key: c.Key, Header: c.Header(locale)})
+	}
+	writeJSON(w, http.StatusOK, columns)
+}
+
+// Saved export templates - GET lists them, POST saves one
+func (app *Application) TemplatesHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		templates, err := app.templates.List()
+		if err != nil {
+			http.Error(w, internal.T(requestLocale(r), "error.internal", err), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, http.StatusOK, templates)
+	case http.MethodPost:
+		var t internal.ExportTemplate
+		if err := json.NewDecoder(r.Body).Decode(&t); err != nil {
+			http.Error(w, internal.T(requestLocale(r), "error.template", err), http.StatusBadRequest)
+			return
+		}
+		if err := app.templates.Save(t); err != nil {
+			http.Error(w, internal.T(requestLocale(r), "error.template", err), http.StatusBadRequest)
+			return
+		}
+		writeJSON(w, http.StatusCreated, t)
+	default:
+		http.Error(w, internal.T(requestLocale(r), "error.method_not_allowed"), http.StatusMethodNotAllowed)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("Error encoding JSON response: %v", err)
+	}
+}
+
 // Live progress handler
 
 func (app *Application) ProgressStreamHandler(w http.ResponseWriter, r *http.Request) {
@@ -3,6 +3,9 @@ package main
 import (
 	"context"
 	"errors"
+	"flag"
+	"fmt"
+	"html/template"
 	"log"
 	"net/http"
 	"os"
@@ -11,11 +14,27 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/jezzaho/goro-web/internal"
 	"github.com/joho/godotenv"
 )
 
 type Application struct {
-	fs http.Handler
+	fs        http.Handler
+	index     *template.Template
+	preview   *template.Template
+	templates *internal.TemplateStore
+	snapshots *internal.SnapshotStore
+	// fetch downloads schedules for the page and the JSON API
+	fetch     internal.Fetcher
+	scheduler *internal.Scheduler
+	// seats gives the capacity of aircraft types for the capacity reports
+	seats internal.SeatTable
+	// hubs gives the connection times of the hubs for the connection finder
+	hubs internal.HubTable
+	// fetches runs the fetches of the JSON API in the background, a request
+	// waits up to fetchWait for its fetch before answering with its status
+	fetches   *internal.FetchJobs
+	fetchWait time.Duration
 }
 
 type AppLogger struct{}
@@ -101,40 +120,137 @@ func WithPort(port string) Option {
 	}
 }
 
+// fetchRetention is how long the result of a fetch of the JSON API is kept
+// for the client polling for it
+const fetchRetention = 15 * time.Minute
+
+// fetchSchedule downloads the schedules of the queries from the Lufthansa
+// API, stopping once ctx is done
+func fetchSchedule(ctx context.Context, queries []internal.ApiQuery) ([]byte, error) {
+	auth, err := internal.PostForAuth(http.DefaultClient, postURL)
+	if err != nil {
+		return nil, err
+	}
+	data, err := internal.GetApiData(ctx, queries, auth)
+	if err != nil {
+		return nil, err
+	}
+	return internal.FlattenJSON(data), nil
+}
+
 func main() {
 	godotenv.Load()
 
-	srv := NewServer(WithPort(":3333"))
+	if err := run(os.Args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return
+		}
+		log.Printf("ERROR: %v", err)
+		os.Exit(1)
+	}
+}
+
+// serveCommand runs the web application until SIGINT or SIGTERM.
+func serveCommand(args []string) error {
+	flags := flag.NewFlagSet("serve", flag.ContinueOnError)
+	port := flags.String("port", ":3333", "address the server listens on")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
 
-	app := Application{}
+	srv := NewServer(WithPort(*port))
+
+	snapshots, err := internal.OpenSnapshotStore(snapshotsFile())
+	if err != nil {
+		return err
+	}
+	defer snapshots.Close()
+
+	seats, err := internal.LoadSeatTable(seatsFile())
+	if err != nil {
+		return fmt.Errorf("loading seat table: %w", err)
+	}
+	hubs, err := internal.LoadHubTable(hubsFile())
+	if err != nil {
+		return fmt.Errorf("loading hub table: %w", err)
+	}
+
+	// Stops the scheduler and the fetches still running on shutdown
+	ctx, stopBackground := context.WithCancel(context.Background())
+	defer stopBackground()
+
+	app := Application{
+		templates: internal.NewTemplateStore(templatesFile()),
+		snapshots: snapshots,
+		fetch:     fetchSchedule,
+		seats:     seats,
+		hubs:      hubs,
+		fetches:   internal.NewFetchJobs(ctx, fetchRetention),
+		// Well within the write timeout of the server
+		fetchWait: 20 * time.Second,
+	}
 
 	fs := http.FileServer(http.Dir("static"))
 	app.fs = fs
+	app.index = template.Must(template.ParseFiles("static/index.html"))
+	app.preview = template.Must(template.ParseFiles("static/preview.html"))
 
 	srv.router.HandleFunc("/", app.IndexHandler)
 	srv.router.HandleFunc("/csv", app.MockHandler)
 	srv.router.HandleFunc("/progress", app.ProgressStreamHandler)
+	srv.router.HandleFunc("/columns", app.ColumnsHandler)
+	srv.router.HandleFunc("/templates", app.TemplatesHandler)
+	srv.router.HandleFunc("/seasons", app.SeasonsHandler)
+	srv.router.HandleFunc("/snapshots", app.SnapshotsHandler)
+	srv.router.HandleFunc("/snapshots/{id}", app.SnapshotHandler)
+	srv.router.HandleFunc("/snapshots/{id}/export", app.SnapshotExportHandler)
+	srv.router.HandleFunc("/snapshots/{id}/capacity", app.SnapshotCapacityHandler)
+	srv.router.HandleFunc("/snapshots/{id}/blocktimes", app.SnapshotBlockTimesHandler)
+	srv.router.HandleFunc("/snapshots/{id}/movements", app.SnapshotMovementsHandler)
+	srv.router.HandleFunc("/snapshots/{id}/rotations", app.SnapshotRotationsHandler)
+	srv.router.HandleFunc("/snapshots/{id}/timeline", app.SnapshotTimelineHandler)
+	srv.router.HandleFunc("/preview", app.PreviewHandler)
+	srv.router.HandleFunc("/preview/{id}", app.SnapshotPreviewHandler)
+	srv.router.HandleFunc("/diff", app.DiffHandler)
+	app.apiRoutes(srv.router)
+
+	if watchFile := os.Getenv("WATCH_FILE"); watchFile != "" {
+		cfg, err := internal.LoadWatchConfig(watchFile)
+		if err != nil {
+			return fmt.Errorf("loading watch config: %w", err)
+		}
+		scheduler, err := internal.NewScheduler(cfg, fetchSchedule, snapshots)
+		if err != nil {
+			return fmt.Errorf("creating scheduler: %w", err)
+		}
+		app.scheduler = scheduler
+		go scheduler.Run(ctx)
+		srv.logger.Info("Scheduler started with %d jobs", len(cfg.Jobs))
+	}
 
 	signalChan := make(chan os.Signal, 1)
 	signal.Notify(signalChan, syscall.SIGINT, syscall.SIGTERM)
 
 	if err := srv.Start(); err != nil {
-		srv.logger.Error("Error during server startup: ", err)
-		os.Exit(1)
+		return fmt.Errorf("server startup: %w", err)
 	}
 	srv.logger.Info("Server started on port ", srv.config.port)
 
-	sig := <-signalChan
-	srv.logger.Info("Received shutdown signal: ", sig)
+	select {
+	case sig := <-signalChan:
+		srv.logger.Info("Received shutdown signal: ", sig)
+	case err := <-srv.errors:
+		return err
+	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	stopBackground()
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
-	if err := srv.Shutdown(ctx); err != nil {
-		srv.logger.Error("Graceful Server shutdown failed", err)
-		os.Exit(1)
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("graceful server shutdown failed: %w", err)
 	}
 
 	srv.logger.Info("Server shutdown completed")
-
+	return nil
 }
@@ -0,0 +1,539 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jezzaho/goro-web/internal"
+)
+
+// previewQuery is the filter and order of a preview, its downloads apply them too.
+type previewQuery struct {
+	Filter     internal.RecordFilter
+	Sort       string
+	Descending bool
+}
+
+func parsePreviewQuery(q url.Values) (previewQuery, error) {
+	p := previewQuery{
+		Filter: internal.RecordFilter{
+			FlightNumber: strings.TrimSpace(q.Get("flight")),
+			AircraftType: strings.TrimSpace(q.Get("aircraft")),
+		},
+		Sort:       q.Get("sort"),
+		Descending: q.Get("order") == "desc",
+	}
+	if v := q.Get("weekday"); v != "" {
+		day, err := strconv.Atoi(v)
+		if err != nil || day < 1 || day > 7 {
+			return p, fmt.Errorf("invalid weekday %q", v)
+		}
+		p.Filter.Weekday = day
+	}
+	if v := q.Get("date"); v != "" {
+		date, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			return p, fmt.Errorf("invalid date %q", v)
+		}
+		p.Filter.Date = date
+	}
+	return p, nil
+}
+
+func (p previewQuery) apply(records []internal.ScheduleRecord) ([]internal.ScheduleRecord, error) {
+	records = internal.FilterRecords(records, p.Filter)
+	if p.Sort == "" {
+		return records, nil
+	}
+	return records, internal.SortRecordsBy(records, p.Sort, p.Descending)
+}
+
+// snapshotExportOptions rebuilds the export options from the params stored
+// with the snapshot. The template, delimiter and date format can be
+// overridden by the query, the language comes from the request. The
+// warnings are those of all records of the snapshot.
+func (app *Application) snapshotExportOptions(snap internal.Snapshot, r *http.Request) (internal.ExportOptions, error) {
+	params, q := snap.Params, r.URL.Query()
+	opts := internal.ExportOptions{
+		Locale:     requestLocale(r),
+		Delimiter:  delimiterOptions[cmp.Or(q.Get("delimiter"), params["delimiter"])],
+		DateLayout: dateFormatOptions[cmp.Or(q.Get("date-format"), params["date-format"])],
+		Warnings:   snap.Warnings,
+	}
+
+	var err error
+	if opts.TimeMode, err = internal.ParseTimeMode(params["time-mode"]); err != nil {
+		return opts, err
+	}
+	if opts.DayBasis, err = internal.ParseDayBasis(params["day-basis"]); err != nil {
+		return opts, err
+	}
+	// Records are stored normalized, the flags only select the columns
+	opts.Separate, _ = queryBool(params["separate"])
+	opts.Exceptions, _ = queryBool(params["exceptions"])
+	opts.MergeDST, _ = queryBool(params["merge-dst"])
+
+	columns, template := params["columns"], params["template"]
+	if q.Has("columns") || q.Has("template") {
+		columns, template = q.Get("columns"), q.Get("template")
+	}
+	opts.Template = internal.ParseColumnList(columns)
+	if len(opts.Template.Columns) == 0 {
+		if opts.Template, err = app.templates.Get(template); err != nil {
+			return opts, err
+		}
+	}
+	return opts, opts.Template.Validate()
+}
+
+// Creates a snapshot from the page form and points to its preview
+func (app *Application) PreviewHandler(w http.ResponseWriter, r *http.Request) {
+	locale := requestLocale(r)
+	if r.Method != http.MethodPost {
+		http.Error(w, internal.T(locale, "error.method_not_allowed"), http.StatusMethodNotAllowed)
+		return
+	}
+	snap, _, ok := app.exportFromForm(w, r)
+	if !ok {
+		return
+	}
+	if snap.ID == 0 {
+		http.Error(w, internal.T(locale, "error.internal", "snapshot not stored"), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Location", fmt.Sprintf("/preview/%d?lang=%s", snap.ID, locale))
+	w.WriteHeader(http.StatusCreated)
+}
+
+// Data passed to the preview page template
+type previewPage struct {
+	Locale   internal.Locale
+	Snapshot internal.SnapshotMeta
+	Query    url.Values
+	Columns  []previewColumn
+	Rows     [][]string
+	Shown    int
+	Weekdays []previewWeekday
+	// Downloads of the shown records by format
+	Downloads map[string]string
+	// Movements, Rotations and Timeline are the paths of the reports of the snapshot
+	Movements     string
+	Buckets       []int
+	Rotations     string
+	MinTurnaround int
+	Timeline      string
+	// Warnings are the rows of the validation warnings of the snapshot, with a header row
+	Warnings [][]string
+}
+
+type previewColumn struct {
+	Label string
+	// SortURL orders by the column, descending when already sorted ascending
+	SortURL string
+	// Order is "asc" or "desc" for the sorted column
+	Order string
+}
+
+type previewWeekday struct {
+	Day       int
+	Label     string
+	Count     int
+	FilterURL string
+}
+
+func (p previewPage) T(key string, args ...any) string {
+	return internal.T(p.Locale, key, args...)
+}
+
+// withQuery returns path with the query values replaced by the pairs.
+func withQuery(path string, q url.Values, pairs ...string) string {
+	values := url.Values{}
+	for key, v := range q {
+		values[key] = v
+	}
+	for i := 0; i+1 < len(pairs); i += 2 {
+		values.Set(pairs[i], pairs[i+1])
+	}
+	return path + "?" + values.Encode()
+}
+
+// Sortable, filterable table of a snapshot with operation counts per weekday
+func (app *Application) SnapshotPreviewHandler(w http.ResponseWriter, r *http.Request) {
+	locale := requestLocale(r)
+	if r.Method != http.MethodGet {
+		http.Error(w, internal.T(locale, "error.method_not_allowed"), http.StatusMethodNotAllowed)
+		return
+	}
+	snap, query, opts, ok := app.snapshotRecords(w, r)
+	if !ok {
+		return
+	}
+	rows, err := internal.RecordRows(snap.Records, opts)
+	if err != nil {
+		http.Error(w, internal.T(locale, "error.template", err), http.StatusBadRequest)
+		return
+	}
+
+	path := fmt.Sprintf("/preview/%d", snap.ID)
+	q := r.URL.Query()
+	page := previewPage{
+		Locale:        locale,
+		Snapshot:      snap.SnapshotMeta,
+		Query:         q,
+		Rows:          rows[1:],
+		Shown:         len(snap.Records),
+		Downloads:     make(map[string]string),
+		Movements:     fmt.Sprintf("/snapshots/%d/movements", snap.ID),
+		Buckets:       internal.MovementBuckets,
+		Rotations:     fmt.Sprintf("/snapshots/%d/rotations", snap.ID),
+		MinTurnaround: internal.DefaultMinTurnaround,
+		Timeline:      fmt.Sprintf("/snapshots/%d/timeline", snap.ID),
+	}
+	if len(snap.Warnings) > 0 {
+		page.Warnings = internal.WarningRows(snap.Warnings, locale, cmp.Or(opts.DateLayout, locale.Format().DateLayout))
+	}
+	for i, key := range opts.ExportTemplate().Columns {
+		column := previewColumn{Label: rows[0][i], SortURL: withQuery(path, q, "sort", key, "order", "asc")}
+		if query.Sort == key {
+			column.Order = "asc"
+			if query.Descending {
+				column.Order = "desc"
+			} else {
+				column.SortURL = withQuery(path, q, "sort", key, "order", "desc")
+			}
+		}
+		page.Columns = append(page.Columns, column)
+	}
+	for i, count := range internal.WeekdayCounts(snap.Records) {
+		day := strconv.Itoa(i + 1)
+		page.Weekdays = append(page.Weekdays, previewWeekday{
+			Day:       i + 1,
+			Label:     internal.T(locale, "weekday."+day),
+			Count:     count,
+			FilterURL: withQuery(path, q, "weekday", day),
+		})
+	}
+	for _, format := range []string{"csv", "xlsx", "json"} {
+		page.Downloads[format] = withQuery(fmt.Sprintf("/snapshots/%d/export", snap.ID), q, "format", format, "lang", string(locale))
+	}
+	page.Downloads["capacity"] = withQuery(fmt.Sprintf("/snapshots/%d/capacity", snap.ID), q, "format", "xlsx", "lang", string(locale))
+	page.Downloads["blocktimes"] = withQuery(fmt.Sprintf("/snapshots/%d/blocktimes", snap.ID), q, "format", "xlsx", "lang", string(locale))
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := app.preview.Execute(w, page); err != nil {
+		log.Printf("Error rendering preview page: %v", err)
+	}
+}
+
+// Records of a snapshot filtered and sorted like its preview, as CSV, XLSX or JSON
+func (app *Application) SnapshotExportHandler(w http.ResponseWriter, r *http.Request) {
+	locale := requestLocale(r)
+	if r.Method != http.MethodGet {
+		http.Error(w, internal.T(locale, "error.method_not_allowed"), http.StatusMethodNotAllowed)
+		return
+	}
+	snap, _, opts, ok := app.snapshotRecords(w, r)
+	if !ok {
+		return
+	}
+
+	filename := fmt.Sprintf("%s_%s_%d", snap.FetchedAt.Format("20060102"), snap.Carrier, snap.ID)
+	switch r.URL.Query().Get("format") {
+	case "json":
+		if snap.Records == nil {
+			snap.Records = []internal.ScheduleRecord{}
+		}
+		writeJSON(w, http.StatusOK, snap.Records)
+	case "xlsx":
+		w.Header().Set("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+		w.Header().Set("Content-Disposition", "attachment; filename=\""+filename+".xlsx\"")
+		if err := internal.WriteRecordsXLSX(w, snap.Records, opts); err != nil {
+			log.Printf("Error writing export: %v", err)
+		}
+	case "", "csv":
+		w.Header().Set("Content-Type", "text/csv")
+		w.Header().Set("Content-Disposition", "attachment; filename=\""+filename+".csv\"")
+		if err := internal.WriteCSV(w, snap.Records, opts); err != nil {
+			log.Printf("Error writing export: %v", err)
+		}
+	default:
+		http.Error(w, internal.T(locale, "error.form"), http.StatusBadRequest)
+	}
+}
+
+// Block time distributions per route, aircraft type and season of the
+// snapshot records shown by the preview, as CSV, XLSX or JSON
+func (app *Application) SnapshotBlockTimesHandler(w http.ResponseWriter, r *http.Request) {
+	locale := requestLocale(r)
+	if r.Method != http.MethodGet {
+		http.Error(w, internal.T(locale, "error.method_not_allowed"), http.StatusMethodNotAllowed)
+		return
+	}
+	snap, _, opts, ok := app.snapshotRecords(w, r)
+	if !ok {
+		return
+	}
+	stats := internal.BlockTimeStatistics(snap.Records, snap.From, snap.To)
+
+	filename := fmt.Sprintf("%s_%s_%d_blocktimes", snap.FetchedAt.Format("20060102"), snap.Carrier, snap.ID)
+	switch r.URL.Query().Get("format") {
+	case "json":
+		writeJSON(w, http.StatusOK, stats)
+	case "xlsx":
+		w.Header().Set("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+		w.Header().Set("Content-Disposition", "attachment; filename=\""+filename+".xlsx\"")
+		if err := internal.WriteBlockTimesXLSX(w, stats, opts); err != nil {
+			log.Printf("Error writing block time report: %v", err)
+		}
+	case "", "csv":
+		w.Header().Set("Content-Type", "text/csv")
+		w.Header().Set("Content-Disposition", "attachment; filename=\""+filename+".csv\"")
+		if err := internal.WriteBlockTimesCSV(w, stats, opts); err != nil {
+			log.Printf("Error writing block time report: %v", err)
+		}
+	default:
+		http.Error(w, internal.T(locale, "error.form"), http.StatusBadRequest)
+	}
+}
+
+// Weekly frequencies and seats per route of the snapshot records shown by
+// the preview, as CSV, XLSX or JSON
+func (app *Application) SnapshotCapacityHandler(w http.ResponseWriter, r *http.Request) {
+	locale := requestLocale(r)
+	if r.Method != http.MethodGet {
+		http.Error(w, internal.T(locale, "error.method_not_allowed"), http.StatusMethodNotAllowed)
+		return
+	}
+	snap, _, opts, ok := app.snapshotRecords(w, r)
+	if !ok {
+		return
+	}
+	weeks := internal.WeeklyCapacities(snap.Records, app.seats, snap.From, snap.To)
+
+	filename := fmt.Sprintf("%s_%s_%d_capacity", snap.FetchedAt.Format("20060102"), snap.Carrier, snap.ID)
+	switch r.URL.Query().Get("format") {
+	case "json":
+		writeJSON(w, http.StatusOK, weeks)
+	case "xlsx":
+		w.Header().Set("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+		w.Header().Set("Content-Disposition", "attachment; filename=\""+filename+".xlsx\"")
+		if err := internal.WriteCapacityXLSX(w, weeks, opts); err != nil {
+			log.Printf("Error writing capacity report: %v", err)
+		}
+	case "", "csv":
+		w.Header().Set("Content-Type", "text/csv")
+		w.Header().Set("Content-Disposition", "attachment; filename=\""+filename+".csv\"")
+		if err := internal.WriteCapacityCSV(w, weeks, opts); err != nil {
+			log.Printf("Error writing capacity report: %v", err)
+		}
+	default:
+		http.Error(w, internal.T(locale, "error.form"), http.StatusBadRequest)
+	}
+}
+
+// Departures and arrivals at the airport of the query per time bucket of
+// each day, or each weekday, of the snapshot as CSV, XLSX, JSON or an SVG heatmap
+func (app *Application) SnapshotMovementsHandler(w http.ResponseWriter, r *http.Request) {
+	locale := requestLocale(r)
+	if r.Method != http.MethodGet {
+		http.Error(w, internal.T(locale, "error.method_not_allowed"), http.StatusMethodNotAllowed)
+		return
+	}
+	snap, _, opts, ok := app.snapshotRecords(w, r)
+	if !ok {
+		return
+	}
+	q := r.URL.Query()
+	bucket, err := strconv.Atoi(cmp.Or(q.Get("bucket"), "60"))
+	if err != nil {
+		http.Error(w, internal.T(locale, "error.parameter", fmt.Errorf("invalid bucket %q", q.Get("bucket"))), http.StatusBadRequest)
+		return
+	}
+	byWeekday, err := groupByWeekday(q)
+	if err != nil {
+		http.Error(w, internal.T(locale, "error.parameter", err), http.StatusBadRequest)
+		return
+	}
+	profile, err := internal.BuildMovementProfile(snap.Records, q.Get("airport"), bucket, snap.From, snap.To)
+	if err != nil {
+		http.Error(w, internal.T(locale, "error.parameter", err), http.StatusBadRequest)
+		return
+	}
+
+	filename := fmt.Sprintf("%s_%s_%d_movements_%s", snap.FetchedAt.Format("20060102"), snap.Carrier, snap.ID, profile.Airport)
+	switch q.Get("format") {
+	case "json":
+		writeJSON(w, http.StatusOK, profile)
+	case "svg":
+		w.Header().Set("Content-Type", "image/svg+xml")
+		if err := internal.WriteMovementsSVG(w, profile, byWeekday, opts); err != nil {
+			log.Printf("Error writing movements chart: %v", err)
+		}
+	case "xlsx":
+		w.Header().Set("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+		w.Header().Set("Content-Disposition", "attachment; filename=\""+filename+".xlsx\"")
+		if err := internal.WriteMovementsXLSX(w, profile, byWeekday, opts); err != nil {
+			log.Printf("Error writing movements report: %v", err)
+		}
+	case "", "csv":
+		w.Header().Set("Content-Type", "text/csv")
+		w.Header().Set("Content-Disposition", "attachment; filename=\""+filename+".csv\"")
+		if err := internal.WriteMovementsCSV(w, profile, byWeekday, opts); err != nil {
+			log.Printf("Error writing movements report: %v", err)
+		}
+	default:
+		http.Error(w, internal.T(locale, "error.form"), http.StatusBadRequest)
+	}
+}
+
+// groupByWeekday reads whether a report sums the weekdays, the default, or
+// has a row per date.
+func groupByWeekday(q url.Values) (bool, error) {
+	switch q.Get("by") {
+	case "", "weekday":
+		return true, nil
+	case "day":
+		return false, nil
+	default:
+		return false, fmt.Errorf("invalid grouping %q", q.Get("by"))
+	}
+}
+
+// Flights of the snapshot drawn on a grid of days and departure times, as
+// SVG, PNG or JSON
+func (app *Application) SnapshotTimelineHandler(w http.ResponseWriter, r *http.Request) {
+	locale := requestLocale(r)
+	if r.Method != http.MethodGet {
+		http.Error(w, internal.T(locale, "error.method_not_allowed"), http.StatusMethodNotAllowed)
+		return
+	}
+	snap, _, opts, ok := app.snapshotRecords(w, r)
+	if !ok {
+		return
+	}
+	q := r.URL.Query()
+	byWeekday, err := groupByWeekday(q)
+	if err != nil {
+		http.Error(w, internal.T(locale, "error.parameter", err), http.StatusBadRequest)
+		return
+	}
+	timeline, err := internal.BuildTimeline(snap.Records, q.Get("color"), byWeekday, snap.From, snap.To)
+	if err != nil {
+		http.Error(w, internal.T(locale, "error.parameter", err), http.StatusBadRequest)
+		return
+	}
+
+	filename := fmt.Sprintf("%s_%s_%d_timeline", snap.FetchedAt.Format("20060102"), snap.Carrier, snap.ID)
+	if !writeTimeline(w, timeline, cmp.Or(q.Get("format"), "svg"), filename, opts) {
+		http.Error(w, internal.T(locale, "error.form"), http.StatusBadRequest)
+	}
+}
+
+// writeTimeline writes the timeline as SVG, PNG or JSON and reports false,
+// without writing anything, for another format.
+func writeTimeline(w http.ResponseWriter, timeline internal.Timeline, format, filename string, opts internal.ExportOptions) bool {
+	switch format {
+	case "json":
+		writeJSON(w, http.StatusOK, timeline)
+	case "svg":
+		w.Header().Set("Content-Type", "image/svg+xml")
+		w.Header().Set("Content-Disposition", "inline; filename=\""+filename+".svg\"")
+		if err := internal.WriteTimelineSVG(w, timeline, opts); err != nil {
+			log.Printf("Error writing timeline: %v", err)
+		}
+	case "png":
+		w.Header().Set("Content-Type", "image/png")
+		w.Header().Set("Content-Disposition", "inline; filename=\""+filename+".png\"")
+		if err := internal.WriteTimelinePNG(w, timeline); err != nil {
+			log.Printf("Error writing timeline: %v", err)
+		}
+	default:
+		return false
+	}
+	return true
+}
+
+// Arrivals at the station of the query paired with the departures of the
+// same aircraft, as CSV, XLSX or JSON
+func (app *Application) SnapshotRotationsHandler(w http.ResponseWriter, r *http.Request) {
+	locale := requestLocale(r)
+	if r.Method != http.MethodGet {
+		http.Error(w, internal.T(locale, "error.method_not_allowed"), http.StatusMethodNotAllowed)
+		return
+	}
+	snap, _, opts, ok := app.snapshotRecords(w, r)
+	if !ok {
+		return
+	}
+	q := r.URL.Query()
+	minTurnaround := internal.DefaultMinTurnaround
+	if v := q.Get("min-turnaround"); v != "" {
+		var err error
+		if minTurnaround, err = strconv.Atoi(v); err != nil {
+			http.Error(w, internal.T(locale, "error.parameter", fmt.Errorf("invalid minimum turnaround %q", v)), http.StatusBadRequest)
+			return
+		}
+	}
+	rotations, err := internal.BuildRotations(snap.Records, q.Get("station"), minTurnaround)
+	if err != nil {
+		http.Error(w, internal.T(locale, "error.parameter", err), http.StatusBadRequest)
+		return
+	}
+
+	filename := fmt.Sprintf("%s_%s_%d_rotations_%s", snap.FetchedAt.Format("20060102"), snap.Carrier, snap.ID, strings.ToUpper(q.Get("station")))
+	switch q.Get("format") {
+	case "json":
+		if rotations == nil {
+			rotations = []internal.Rotation{}
+		}
+		writeJSON(w, http.StatusOK, rotations)
+	case "xlsx":
+		w.Header().Set("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+		w.Header().Set("Content-Disposition", "attachment; filename=\""+filename+".xlsx\"")
+		if err := internal.WriteRotationsXLSX(w, rotations, opts); err != nil {
+			log.Printf("Error writing rotation report: %v", err)
+		}
+	case "", "csv":
+		w.Header().Set("Content-Type", "text/csv")
+		w.Header().Set("Content-Disposition", "attachment; filename=\""+filename+".csv\"")
+		if err := internal.WriteRotationsCSV(w, rotations, opts); err != nil {
+			log.Printf("Error writing rotation report: %v", err)
+		}
+	default:
+		http.Error(w, internal.T(locale, "error.form"), http.StatusBadRequest)
+	}
+}
+
+// snapshotRecords loads the snapshot of the path with its records filtered
+// and sorted by the query. Errors are written to w and reported by a false ok.
+func (app *Application) snapshotRecords(w http.ResponseWriter, r *http.Request) (snap internal.Snapshot, query previewQuery, opts internal.ExportOptions, ok bool) {
+	locale := requestLocale(r)
+	id, err := strconv.ParseUint(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, internal.T(locale, "error.snapshot_not_found"), http.StatusNotFound)
+		return
+	}
+	if snap, err = app.snapshots.Get(id); err != nil {
+		writeSnapshotError(w, locale, err)
+		return
+	}
+	if opts, err = app.snapshotExportOptions(snap, r); err != nil {
+		http.Error(w, internal.T(locale, "error.template", err), http.StatusBadRequest)
+		return
+	}
+	if query, err = parsePreviewQuery(r.URL.Query()); err != nil {
+		http.Error(w, internal.T(locale, "error.parameter", err), http.StatusBadRequest)
+		return
+	}
+	if snap.Records, err = query.apply(snap.Records); err != nil {
+		http.Error(w, internal.T(locale, "error.parameter", err), http.StatusBadRequest)
+		return
+	}
+	return snap, query, opts, true
+}
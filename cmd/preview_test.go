@@ -0,0 +1,439 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jezzaho/goro-web/internal"
+)
+
+func testPreview(t *testing.T) (*http.ServeMux, uint64) {
+	t.Helper()
+	store, err := internal.OpenSnapshotStore(filepath.Join(t.TempDir(), "snapshots.db"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	start, _ := time.Parse("2006-01-02", "2025-03-30")
+	end, _ := time.Parse("2006-01-02", "2025-04-26")
+	record := func(flight int, departure string, days string, aircraft string) internal.ScheduleRecord {
+		var r internal.ScheduleRecord
+		r.Origin, r.Destination, r.Airline, r.FlightNumber, r.AircraftType = "KRK", "FRA", "LH", flight, aircraft
+		r.StartDate, r.EndDate = start, end
+		r.Departure, _ = internal.ParseTimeOfDay(departure)
+		// Summer time in Kraków, 1:45 to Frankfurt
+		r.DepartureUTC = r.Departure - 120
+		r.ArrivalUTC = r.DepartureUTC + 105
+		r.Days, _ = internal.ParseWeekdays(days)
+		return r
+	}
+	snap := newSnapshot("LH", start, end, map[string]string{"date-format": "iso"}, []internal.ScheduleRecord{
+		record(1365, "10:20", "1.3.5..", "32N"),
+		record(1623, "06:00", "1234567", "E95"),
+		record(999, "22:50", ".2.4.6.", "320"),
+	})
+	snap.Warnings = []internal.Warning{{
+		Rule:      "duplicate_flight",
+		Route:     internal.Route{Airline: "LH", FlightNumber: 1365, Origin: "KRK", Destination: "FRA"},
+		StartDate: start,
+		EndDate:   start.AddDate(0, 0, 2),
+		Values:    []string{"2"},
+	}}
+	if err := store.Save(&snap); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	app := &Application{
+		preview:   template.Must(template.ParseFiles("../static/preview.html")),
+		templates: internal.NewTemplateStore(filepath.Join(t.TempDir(), "templates.json")),
+		snapshots: store,
+		seats:     internal.DefaultSeatTable(),
+	}
+	router := http.NewServeMux()
+	router.HandleFunc("/preview/{id}", app.SnapshotPreviewHandler)
+	router.HandleFunc("/snapshots/{id}/export", app.SnapshotExportHandler)
+	router.HandleFunc("/snapshots/{id}/capacity", app.SnapshotCapacityHandler)
+	router.HandleFunc("/snapshots/{id}/blocktimes", app.SnapshotBlockTimesHandler)
+	router.HandleFunc("/snapshots/{id}/movements", app.SnapshotMovementsHandler)
+	router.HandleFunc("/snapshots/{id}/rotations", app.SnapshotRotationsHandler)
+	router.HandleFunc("/snapshots/{id}/timeline", app.SnapshotTimelineHandler)
+	return router, snap.ID
+}
+
+func TestSnapshotPreview(t *testing.T) {
+	router, id := testPreview(t)
+
+	tests := []struct {
+		name     string
+		query    string
+		status   int
+		contains []string
+		excludes []string
+	}{
+		{
+			name:     "All records",
+			query:    "lang=en",
+			status:   http.StatusOK,
+			contains: []string{"Showing 3 of 3 records", ">1365<", ">1623<", ">999<", "sort=flight_number", `action="/snapshots/1/movements"`, "Validation warnings", ">LH1365<", ">2025-04-01<", "Flight number departs more than once a day on 2 days"},
+		},
+		{
+			name:     "Filtered by weekday and aircraft",
+			query:    "lang=en&weekday=2&aircraft=320",
+			status:   http.StatusOK,
+			contains: []string{"Showing 1 of 3 records", ">999<", `<option value="2" selected>`},
+			excludes: []string{">1365<", ">1623<"},
+		},
+		{
+			name:     "Sorted descending",
+			query:    "lang=en&sort=departure&order=desc",
+			status:   http.StatusOK,
+			contains: []string{"&#9660;"},
+		},
+		{
+			name:     "Nothing matches",
+			query:    "lang=pl&flight=1",
+			status:   http.StatusOK,
+			contains: []string{"Brak rekordów", "Pokazano 0 z 3 rekordów"},
+		},
+		{name: "Invalid weekday", query: "weekday=8", status: http.StatusBadRequest},
+		{name: "Unknown sort column", query: "sort=gate", status: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/preview/"+strconv.FormatUint(id, 10)+"?"+tt.query, nil))
+			if w.Code != tt.status {
+				t.Fatalf("Test %s failed: expected status %d, got %d: %s", tt.name, tt.status, w.Code, w.Body)
+			}
+			body := w.Body.String()
+			for _, s := range tt.contains {
+				if !strings.Contains(body, s) {
+					t.Errorf("Test %s failed: expected page to contain %q", tt.name, s)
+				}
+			}
+			for _, s := range tt.excludes {
+				if strings.Contains(body, s) {
+					t.Errorf("Test %s failed: expected page not to contain %q", tt.name, s)
+				}
+			}
+		})
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/preview/42", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected status %d for a missing snapshot, got %d", http.StatusNotFound, w.Code)
+	}
+}
+
+func TestSnapshotExport(t *testing.T) {
+	router, id := testPreview(t)
+	path := "/snapshots/" + strconv.FormatUint(id, 10) + "/export"
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path+"?format=json&sort=flight_number&order=desc&weekday=1", nil))
+	var records []internal.ScheduleRecord
+	if err := json.Unmarshal(w.Body.Bytes(), &records); err != nil {
+		t.Fatalf("invalid JSON body: %v: %s", err, w.Body)
+	}
+	var flights []int
+	for _, r := range records {
+		flights = append(flights, r.FlightNumber)
+	}
+	if len(flights) != 2 || flights[0] != 1623 || flights[1] != 1365 {
+		t.Errorf("expected flights [1623 1365], got %v", flights)
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path+"?lang=en&columns=flight_number,departure&sort=departure", nil))
+	expected := "Flight,Departure\n1623,06:00\n1365,10:20\n999,22:50\n"
+	if got := strings.ReplaceAll(w.Body.String(), "\r\n", "\n"); got != expected {
+		t.Errorf("expected %q, got %q", expected, got)
+	}
+	if cd := w.Header().Get("Content-Disposition"); !strings.HasSuffix(cd, "_LH_"+strconv.FormatUint(id, 10)+".csv\"") {
+		t.Errorf("unexpected Content-Disposition %q", cd)
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path+"?format=pdf", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status %d for an unknown format, got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+func TestSnapshotCapacity(t *testing.T) {
+	router, id := testPreview(t)
+	path := "/snapshots/" + strconv.FormatUint(id, 10) + "/capacity"
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path+"?lang=en&aircraft=32N", nil))
+	lines := strings.Split(strings.TrimSpace(strings.ReplaceAll(w.Body.String(), "\r\n", "\n")), "\n")
+	// LH1365 on Mon, Wed, Fri with 180 seats
+	expected := []string{
+		"Carrier,From,To,Week,Week start,Frequencies,Seats,Operations without seats",
+		"LH,KRK,FRA,2025-W14,2025-03-31,3,540,0",
+		"LH,KRK,FRA,2025-W15,2025-04-07,3,540,0",
+		"LH,KRK,FRA,2025-W16,2025-04-14,3,540,0",
+		"LH,KRK,FRA,2025-W17,2025-04-21,3,540,0",
+	}
+	if !slices.Equal(lines, expected) {
+		t.Errorf("expected %q, got %q", expected, lines)
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path+"?format=xlsx", nil))
+	if ct := w.Header().Get("Content-Type"); w.Code != http.StatusOK || !strings.Contains(ct, "spreadsheetml") {
+		t.Errorf("expected a spreadsheet, got status %d with %q", w.Code, ct)
+	}
+}
+
+func TestSnapshotBlockTimes(t *testing.T) {
+	router, id := testPreview(t)
+	path := "/snapshots/" + strconv.FormatUint(id, 10) + "/blocktimes"
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path+"?lang=en", nil))
+	lines := strings.Split(strings.TrimSpace(strings.ReplaceAll(w.Body.String(), "\r\n", "\n")), "\n")
+	expected := []string{
+		"Carrier,From,To,Aircraft type,Season,Operations,Min block time,Median block time,Max block time,Median change (min)",
+		"LH,KRK,FRA,320,S25,12,01:45,01:45,01:45,",
+		"LH,KRK,FRA,32N,S25,12,01:45,01:45,01:45,",
+		"LH,KRK,FRA,E95,S25,28,01:45,01:45,01:45,",
+	}
+	if !slices.Equal(lines, expected) {
+		t.Errorf("expected %q, got %q", expected, lines)
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path+"?format=pdf", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status %d for an unknown format, got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+func TestSnapshotMovements(t *testing.T) {
+	router, id := testPreview(t)
+	path := "/snapshots/" + strconv.FormatUint(id, 10) + "/movements"
+
+	tests := []struct {
+		name        string
+		query       string
+		status      int
+		contentType string
+		contains    string
+	}{
+		{name: "Weekday table", query: "airport=KRK&lang=en", status: http.StatusOK, contentType: "text/csv", contains: "Mon,Departures,0,0,0,0,0,0,4,0,0,0,4,"},
+		{name: "Daily table", query: "airport=KRK&by=day&bucket=30&lang=en", status: http.StatusOK, contentType: "text/csv", contains: "2025-03-31,Total,"},
+		{name: "Chart", query: "airport=krk&format=svg&lang=en", status: http.StatusOK, contentType: "image/svg+xml", contains: "Movements at KRK"},
+		{name: "JSON", query: "airport=KRK&format=json", status: http.StatusOK, contentType: "application/json", contains: `"bucket_minutes":60`},
+		{name: "Spreadsheet", query: "airport=KRK&format=xlsx", status: http.StatusOK, contentType: "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"},
+		{name: "Missing airport", query: "", status: http.StatusBadRequest},
+		{name: "Invalid bucket", query: "airport=KRK&bucket=20", status: http.StatusBadRequest},
+		{name: "Invalid grouping", query: "airport=KRK&by=month", status: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path+"?"+tt.query, nil))
+			if w.Code != tt.status {
+				t.Fatalf("Test %s failed: expected status %d, got %d: %s", tt.name, tt.status, w.Code, w.Body)
+			}
+			if tt.status != http.StatusOK {
+				return
+			}
+			if ct := w.Header().Get("Content-Type"); ct != tt.contentType {
+				t.Errorf("Test %s failed: expected %q, got %q", tt.name, tt.contentType, ct)
+			}
+			if !strings.Contains(w.Body.String(), tt.contains) {
+				t.Errorf("Test %s failed: expected body to contain %q", tt.name, tt.contains)
+			}
+		})
+	}
+}
+
+func TestSnapshotRotations(t *testing.T) {
+	router, id := testPreview(t)
+	path := "/snapshots/" + strconv.FormatUint(id, 10) + "/rotations"
+
+	tests := []struct {
+		name        string
+		query       string
+		status      int
+		contentType string
+		contains    string
+	}{
+		{name: "Report", query: "station=KRK&lang=en", status: http.StatusOK, contentType: "text/csv", contains: "Station,Inbound,"},
+		{name: "JSON", query: "station=krk&format=json", status: http.StatusOK, contentType: "application/json", contains: `"station":"KRK"`},
+		{name: "Spreadsheet", query: "station=KRK&format=xlsx", status: http.StatusOK, contentType: "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"},
+		{name: "Missing station", query: "", status: http.StatusBadRequest},
+		{name: "Invalid minimum turnaround", query: "station=KRK&min-turnaround=half", status: http.StatusBadRequest},
+		{name: "Negative minimum turnaround", query: "station=KRK&min-turnaround=-5", status: http.StatusBadRequest},
+		{name: "Invalid format", query: "station=KRK&format=pdf", status: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path+"?"+tt.query, nil))
+			if w.Code != tt.status {
+				t.Fatalf("Test %s failed: expected status %d, got %d: %s", tt.name, tt.status, w.Code, w.Body)
+			}
+			if tt.status != http.StatusOK {
+				return
+			}
+			if ct := w.Header().Get("Content-Type"); ct != tt.contentType {
+				t.Errorf("Test %s failed: expected %q, got %q", tt.name, tt.contentType, ct)
+			}
+			if !strings.Contains(w.Body.String(), tt.contains) {
+				t.Errorf("Test %s failed: expected body to contain %q", tt.name, tt.contains)
+			}
+		})
+	}
+}
+
+func TestSnapshotTimeline(t *testing.T) {
+	router, id := testPreview(t)
+	path := "/snapshots/" + strconv.FormatUint(id, 10) + "/timeline"
+
+	tests := []struct {
+		name        string
+		query       string
+		status      int
+		contentType string
+		contains    string
+	}{
+		{name: "Weekly chart", query: "lang=en", status: http.StatusOK, contentType: "image/svg+xml", contains: "<title>LH1365 KRK-FRA 10:20-"},
+		{name: "Daily chart by aircraft", query: "by=day&color=aircraft&lang=en", status: http.StatusOK, contentType: "image/svg+xml", contains: "2025-03-31"},
+		{name: "Picture", query: "format=png", status: http.StatusOK, contentType: "image/png", contains: "PNG"},
+		{name: "JSON", query: "format=json&color=aircraft", status: http.StatusOK, contentType: "application/json", contains: `"keys":["320","32N","E95"]`},
+		{name: "Invalid colouring", query: "color=owner", status: http.StatusBadRequest},
+		{name: "Invalid grouping", query: "by=month", status: http.StatusBadRequest},
+		{name: "Invalid format", query: "format=csv", status: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path+"?"+tt.query, nil))
+			if w.Code != tt.status {
+				t.Fatalf("Test %s failed: expected status %d, got %d: %s", tt.name, tt.status, w.Code, w.Body)
+			}
+			if tt.status != http.StatusOK {
+				return
+			}
+			if ct := w.Header().Get("Content-Type"); ct != tt.contentType {
+				t.Errorf("Test %s failed: expected %q, got %q", tt.name, tt.contentType, ct)
+			}
+			if !strings.Contains(w.Body.String(), tt.contains) {
+				t.Errorf("Test %s failed: expected body to contain %q", tt.name, tt.contains)
+			}
+		})
+	}
+}
+
+func TestIndexHandlerTemplates(t *testing.T) {
+	app := &Application{
+		index: template.Must(template.ParseFiles("../static/index.html")),
+		fs:    http.FileServer(http.Dir("../static")),
+	}
+	tests := []struct {
+		name     string
+		target   string
+		expected int
+	}{
+		{name: "Rendered page", target: "/", expected: http.StatusOK},
+		{name: "Raw page redirected", target: "/index.html", expected: http.StatusMovedPermanently},
+		{name: "Raw preview template", target: "/preview.html", expected: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			app.IndexHandler(w, httptest.NewRequest(http.MethodGet, tt.target, nil))
+			if w.Code != tt.expected || strings.Contains(w.Body.String(), "{{") {
+				t.Errorf("Test %s failed: expected status %d without template markup, got %d", tt.name, tt.expected, w.Code)
+			}
+		})
+	}
+	w := httptest.NewRecorder()
+	app.IndexHandler(w, httptest.NewRequest(http.MethodGet, "/index.html", nil))
+	if location := w.Header().Get("Location"); location != "/" {
+		t.Errorf("expected redirect to /, got %q", location)
+	}
+}
+
+func TestPreviewHandlerDates(t *testing.T) {
+	app := &Application{templates: internal.NewTemplateStore(filepath.Join(t.TempDir(), "templates.json"))}
+	tests := []struct {
+		name string
+		form string
+	}{
+		{name: "Missing dates", form: "carrier=LH"},
+		{name: "Short date", form: "carrier=LH&date-from=2025&date-to=2025-04-26"},
+		{name: "To before from", form: "carrier=LH&date-from=2025-04-26&date-to=2025-03-30"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/preview?lang=en", strings.NewReader(tt.form))
+			r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			w := httptest.NewRecorder()
+			app.PreviewHandler(w, r)
+			if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "Invalid dates") {
+				t.Errorf("Test %s failed: expected 400 for invalid dates, got %d %q", tt.name, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestPreviewHandlerFetch(t *testing.T) {
+	store, err := internal.OpenSnapshotStore(filepath.Join(t.TempDir(), "snapshots.db"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer store.Close()
+	var fetched []internal.ApiQuery
+	app := &Application{
+		fetch: func(_ context.Context, queries []internal.ApiQuery) ([]byte, error) {
+			fetched = queries
+			return []byte(testResponse), nil
+		},
+		templates: internal.NewTemplateStore(filepath.Join(t.TempDir(), "templates.json")),
+		snapshots: store,
+	}
+	// Nobody follows the progress of the export
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for {
+			select {
+			case <-progressChan:
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	form := "carrier=LH&date-from=2025-03-30&date-to=2025-04-26&origin=krk&destination=fra"
+	r := httptest.NewRequest(http.MethodPost, "/preview", strings.NewReader(form))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	app.PreviewHandler(w, r)
+	if w.Code != http.StatusCreated || len(fetched) != 1 || fetched[0].StartDate != "30MAR25" || fetched[0].Origin != "KRK" {
+		t.Fatalf("expected the route to be fetched and stored, got %d %q for %+v", w.Code, w.Body.String(), fetched)
+	}
+	snap, err := store.Latest("LH")
+	if err != nil || len(snap.Records) == 0 || snap.From.Format("2006-01-02") != "2025-03-30" {
+		t.Errorf("expected the snapshot of the period, got %+v (%v)", snap.SnapshotMeta, err)
+	}
+}
@@ -0,0 +1,160 @@
+package internal
+
+import (
+	_ "embed"
+	"encoding/csv"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	// Airport time zones have to resolve on hosts without a zoneinfo database
+	_ "time/tzdata"
+)
+
+// Airport is an entry of the embedded airport reference data.
+type Airport struct {
+	IATA     string `json:"iata"`
+	ICAO     string `json:"icao"`
+	Name     string `json:"name"`
+	City     string `json:"city"`
+	Country  string `json:"country"`
+	TimeZone string `json:"tz"`
+}
+
+//go:embed data/airports.csv
+var airportsCSV string
+
+var (
+	airportsOnce sync.Once
+	airportList  []Airport
+	airportIndex map[string]Airport
+)
+
+func loadAirports() {
+	rows, err := csv.NewReader(strings.NewReader(airportsCSV)).ReadAll()
+	if err != nil {
+		panic(fmt.Sprintf("invalid embedded airport data: %v", err))
+	}
+	airportIndex = make(map[string]Airport)
+	// First row is the header
+	for _, row := range rows[1:] {
+		a := Airport{IATA: row[0], ICAO: row[1], Name: row[2], City: row[3], Country: row[4], TimeZone: row[5]}
+		airportList = append(airportList, a)
+		airportIndex[a.IATA] = a
+		airportIndex[a.ICAO] = a
+	}
+}
+
+// Airports lists every airport of the reference data.
+func Airports() []Airport {
+	airportsOnce.Do(loadAirports)
+	return airportList
+}
+
+// LookupAirport finds an airport by its IATA or ICAO code.
+func LookupAirport(code string) (Airport, bool) {
+	airportsOnce.Do(loadAirports)
+	a, ok := airportIndex[strings.ToUpper(strings.TrimSpace(code))]
+	return a, ok
+}
+
+// ValidateAirport checks that code is a well-formed IATA airport code. Codes
+// missing from the reference data are accepted, KnownAirport tells them.
+func ValidateAirport(code string) error {
+	code = strings.TrimSpace(code)
+	if len(code) != 3 || strings.IndexFunc(code, func(c rune) bool { return !unicode.IsLetter(c) || c > unicode.MaxASCII }) >= 0 {
+		return fmt.Errorf("invalid airport code %q", code)
+	}
+	return nil
+}
+
+// KnownAirport reports whether the reference data has the airport with the
+// IATA code, only those get their local times checked against a time zone.
+func KnownAirport(code string) bool {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	a, ok := LookupAirport(code)
+	return ok && a.IATA == code
+}
+
+// Location returns the time zone of the airport.
+func (a Airport) Location() (*time.Location, error) {
+	return time.LoadLocation(a.TimeZone)
+}
+
+// airportLocation looks up the time zone of the airport with the given code.
+func airportLocation(code string) (*time.Location, error) {
+	a, ok := LookupAirport(code)
+	if !ok {
+		return nil, fmt.Errorf("unknown airport %q", code)
+	}
+	return a.Location()
+}
+
+// LocalToUTC converts a local time at the airport on date to UTC, taking the
+// daylight saving time in force on that date into account.
+func LocalToUTC(code string, date time.Time, t TimeOfDay) (time.Time, error) {
+	loc, err := airportLocation(code)
+	if err != nil {
+		return time.Time{}, err
+	}
+	local := time.Date(date.Year(), date.Month(), date.Day(), 0, int(t), 0, 0, loc)
+	return local.UTC(), nil
+}
+
+// AtAirport returns the instant as local time at the airport.
+func AtAirport(code string, instant time.Time) (time.Time, error) {
+	loc, err := airportLocation(code)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return instant.In(loc), nil
+}
+
+// UTCOffset returns the UTC offset in minutes of the airport at the instant.
+func UTCOffset(code string, instant time.Time) (int, error) {
+	local, err := AtAirport(code, instant)
+	if err != nil {
+		return 0, err
+	}
+	_, offset := local.Zone()
+	return offset / 60, nil
+}
+
+// CheckVariation compares a UTC offset reported for a local time at the
+// airport on date with the one of the time zone database.
+func CheckVariation(code string, date time.Time, t TimeOfDay, variation int) error {
+	utc, err := LocalToUTC(code, date, t)
+	if err != nil {
+		return err
+	}
+	expected, err := UTCOffset(code, utc)
+	if err != nil {
+		return err
+	}
+	if expected != variation {
+		return fmt.Errorf("%s on %s: UTC offset %+d min, expected %+d min", code, date.Format(dateLayout), variation, expected)
+	}
+	return nil
+}
+
+// DSTTransitions lists the days between from and to, both inclusive, on which
+// the UTC offset of the airport differs from the day before.
+func DSTTransitions(code string, from, to time.Time) ([]time.Time, error) {
+	loc, err := airportLocation(code)
+	if err != nil {
+		return nil, err
+	}
+	var transitions []time.Time
+	// Offsets are compared at noon, clear of the switch in the early morning
+	_, previous := from.AddDate(0, 0, -1).Add(12 * time.Hour).In(loc).Zone()
+	for date := from; !date.After(to); date = date.AddDate(0, 0, 1) {
+		_, offset := date.Add(12 * time.Hour).In(loc).Zone()
+		if offset != previous {
+			transitions = append(transitions, date)
+		}
+		previous = offset
+	}
+	return transitions, nil
+}
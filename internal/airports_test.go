@@ -0,0 +1,135 @@
+package internal
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestAirportData(t *testing.T) {
+	seen := make(map[string]bool)
+	for _, a := range Airports() {
+		if len(a.IATA) != 3 || len(a.ICAO) != 4 {
+			t.Errorf("invalid codes of %v", a)
+		}
+		if seen[a.IATA] || seen[a.ICAO] {
+			t.Errorf("duplicated airport %v", a)
+		}
+		seen[a.IATA], seen[a.ICAO] = true, true
+		if _, err := a.Location(); err != nil {
+			t.Errorf("invalid time zone of %s: %v", a.IATA, err)
+		}
+	}
+}
+
+func TestLookupAirport(t *testing.T) {
+	tests := []struct {
+		code     string
+		expected string
+		found    bool
+	}{
+		{code: "KRK", expected: "KRK", found: true},
+		{code: "epkk", expected: "KRK", found: true},
+		{code: " fra ", expected: "FRA", found: true},
+		{code: "XXX", found: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.code, func(t *testing.T) {
+			a, ok := LookupAirport(tt.code)
+			if ok != tt.found || a.IATA != tt.expected {
+				t.Errorf("expected %q (%v), got %q (%v)", tt.expected, tt.found, a.IATA, ok)
+			}
+		})
+	}
+
+	if err := ValidateAirport("EPKK"); err == nil {
+		t.Errorf("expected ICAO code to be rejected as IATA code")
+	}
+	for _, code := range []string{"krk", "BOS"} {
+		if err := ValidateAirport(code); err != nil {
+			t.Errorf("unexpected error for %s: %v", code, err)
+		}
+	}
+	if !KnownAirport("krk") || KnownAirport("BOS") || KnownAirport("EPKK") {
+		t.Errorf("expected only KRK in the reference data")
+	}
+}
+
+func TestLocalToUTC(t *testing.T) {
+	tests := []struct {
+		name     string
+		code     string
+		date     string
+		time     TimeOfDay
+		expected string
+	}{
+		{name: "Winter time", code: "KRK", date: "2025-03-29", time: 600, expected: "2025-03-29T09:00:00Z"},
+		{name: "Summer time", code: "KRK", date: "2025-03-30", time: 600, expected: "2025-03-30T08:00:00Z"},
+		{name: "Before midnight UTC", code: "KRK", date: "2025-07-01", time: 30, expected: "2025-06-30T22:30:00Z"},
+		{name: "Western hemisphere", code: "JFK", date: "2025-07-01", time: 1320, expected: "2025-07-02T02:00:00Z"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			date, _ := time.Parse(dateLayout, tt.date)
+			result, err := LocalToUTC(tt.code, date, tt.time)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := result.Format(time.RFC3339); got != tt.expected {
+				t.Errorf("expected %s, got %s", tt.expected, got)
+			}
+		})
+	}
+
+	if _, err := LocalToUTC("XXX", time.Now(), 0); err == nil {
+		t.Errorf("expected error for unknown airport")
+	}
+}
+
+func TestAtAirport(t *testing.T) {
+	instant := time.Date(2025, 10, 26, 12, 0, 0, 0, time.UTC)
+	local, err := AtAirport("LHR", instant)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if local.Hour() != 12 {
+		t.Errorf("expected 12:00 in London after the switch, got %v", local)
+	}
+	if offset, _ := UTCOffset("KRK", instant.AddDate(0, 0, -1)); offset != 120 {
+		t.Errorf("expected +120 in Kraków before the switch, got %d", offset)
+	}
+}
+
+func TestCheckVariation(t *testing.T) {
+	date := time.Date(2025, 3, 30, 0, 0, 0, 0, time.UTC)
+	if err := CheckVariation("FRA", date, 600, 120); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := CheckVariation("FRA", date, 600, 60); err == nil {
+		t.Errorf("expected winter offset to be reported after the switch")
+	}
+}
+
+func TestDSTTransitions(t *testing.T) {
+	from := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2025, 12, 31, 0, 0, 0, 0, time.UTC)
+
+	transitions, err := DSTTransitions("WAW", from, to)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var got []string
+	for _, d := range transitions {
+		got = append(got, d.Format(dateLayout))
+	}
+	expected := []string{"2025-03-30", "2025-10-26"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+
+	if transitions, _ := DSTTransitions("SIN", from, to); len(transitions) != 0 {
+		t.Errorf("expected no transitions in Singapore, got %v", transitions)
+	}
+}
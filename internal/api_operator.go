@@ -2,6 +2,7 @@ package internal
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -30,36 +31,76 @@ type ApiQuery struct {
 	Destination     string
 }
 
+// Validate checks the airline, that both airports are well-formed codes and
+// that they differ.
+func (a ApiQuery) Validate() error {
+	if len(a.Airline) != 2 {
+		return fmt.Errorf("invalid airline %q", a.Airline)
+	}
+	if err := ValidateAirport(a.Origin); err != nil {
+		return err
+	}
+	if err := ValidateAirport(a.Destination); err != nil {
+		return err
+	}
+	if a.Origin == a.Destination {
+		return fmt.Errorf("origin and destination are both %s", a.Origin)
+	}
+	return nil
+}
+
 // Swaping ApiQuery Fields for faster search in-out flight A to B  - swap - B to A.
 func (a *ApiQuery) Swap() {
 	a.Origin, a.Destination = a.Destination, a.Origin
 }
 
-func GetApiData(queryList []ApiQuery, apiAuth Auth) []byte {
+// GetApiData fetches both directions of every query, pausing between the
+// calls to stay within the QPS limit of the API. It stops with the error of
+// ctx once ctx is done.
+func GetApiData(ctx context.Context, queryList []ApiQuery, apiAuth Auth) ([]byte, error) {
 	queryResult := ""
 
-	time.Sleep(6000 * time.Millisecond)
+	if err := sleepContext(ctx, 6000*time.Millisecond); err != nil {
+		return nil, err
+	}
 	for _, query := range queryList {
-		time.Sleep(6000 * time.Millisecond)
-		queryResult += getApiResponse(apiAuth, query)
+		if err := sleepContext(ctx, 6000*time.Millisecond); err != nil {
+			return nil, err
+		}
+		queryResult += getApiResponse(ctx, apiAuth, query)
 		if queryResult == "" {
 			log.Println("Empty query response before  query reverse")
 		}
 		// Swap query fields Origin and Destination for full result
 		query.Swap()
 		// Has to sleep - otherwise QPS is exceeded for Api Call
-		time.Sleep(6000 * time.Millisecond)
-		queryResultP2 := getApiResponse(apiAuth, query)
+		if err := sleepContext(ctx, 6000*time.Millisecond); err != nil {
+			return nil, err
+		}
+		queryResultP2 := getApiResponse(ctx, apiAuth, query)
 		if queryResultP2 == "" {
 			log.Println("Empty query response after query reverse")
 		}
 		queryResult += queryResultP2
 	}
 
-	return []byte(queryResult)
+	return []byte(queryResult), nil
+
+}
 
+// sleepContext waits for d or until ctx is done, returning the error of ctx.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
 }
-func getApiResponse(auth Auth, query ApiQuery) string {
+
+func getApiResponse(ctx context.Context, auth Auth, query ApiQuery) string {
 
 	client := http.Client{}
 	getUrl := "https://api.lufthansa.com/v1/flight-schedules/flightschedules/passenger"
@@ -76,7 +117,7 @@ func getApiResponse(auth Auth, query ApiQuery) string {
 	fullURL := fmt.Sprintf("%s?%s", getUrl, queryParams.Encode())
 
 	// Perform the GET request
-	request, err := http.NewRequest("GET", fullURL, nil)
+	request, err := http.NewRequestWithContext(ctx, "GET", fullURL, nil)
 	if err != nil {
 		log.Println("Error during construction of GET request: ", err.Error())
 	}
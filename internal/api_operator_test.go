@@ -98,3 +98,34 @@ func TestPostForAuth(t *testing.T) {
 		})
 	}
 }
+
+func TestApiQueryValidate(t *testing.T) {
+	tests := []struct {
+		name      string
+		query     ApiQuery
+		expectErr bool
+	}{
+		{name: "Known route", query: ApiQuery{Airline: "LH", Origin: "KRK", Destination: "FRA"}},
+		{name: "Airport missing from the reference data", query: ApiQuery{Airline: "LH", Origin: "BOS", Destination: "SFO"}},
+		{name: "Invalid origin", query: ApiQuery{Airline: "LH", Origin: "K1K", Destination: "FRA"}, expectErr: true},
+		{name: "Missing destination", query: ApiQuery{Airline: "LH", Origin: "KRK"}, expectErr: true},
+		{name: "Same airports", query: ApiQuery{Airline: "LH", Origin: "KRK", Destination: "KRK"}, expectErr: true},
+		{name: "Invalid airline", query: ApiQuery{Airline: "DLH", Origin: "KRK", Destination: "FRA"}, expectErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.query.Validate(); (err != nil) != tt.expectErr {
+				t.Errorf("expected error: %v, got: %v", tt.expectErr, err)
+			}
+		})
+	}
+
+	for code := 0; code <= 4; code++ {
+		for _, q := range GetQueryListForAirline(code, "1JAN25", "31JAN25", TimeModeLT) {
+			if err := q.Validate(); err != nil {
+				t.Errorf("default route %s-%s of %s: %v", q.Origin, q.Destination, q.Airline, err)
+			}
+		}
+	}
+}
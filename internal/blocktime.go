@@ -0,0 +1,142 @@
+package internal
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// BlockTimeStats is the distribution of the scheduled block times of a
+// carrier on a route in one direction, with an aircraft type, in a season.
+type BlockTimeStats struct {
+	Carrier      string `json:"carrier"`
+	Origin       string `json:"origin"`
+	Destination  string `json:"destination"`
+	AircraftType string `json:"aircraft_type"`
+	Season       string `json:"season"`
+	Operations   int    `json:"operations"`
+	// Block times in minutes over the operations
+	Min    int `json:"min"`
+	Median int `json:"median"`
+	Max    int `json:"max"`
+	// MedianChange is the change of the median against the previous season
+	// of the route and aircraft type, nil when it has no statistics
+	MedianChange *int `json:"median_change,omitempty"`
+}
+
+type blockTimeKey struct {
+	carrier, origin, destination, aircraftType string
+	season                                     Season
+}
+
+// utcBlockMinutes returns the scheduled block time of the flight from its
+// UTC departure and arrival.
+func utcBlockMinutes(f Flight) int {
+	return int(f.ArrivalUTC) + minutesPerDay*f.ArrivalUTCDateDiff - int(f.DepartureUTC) - minutesPerDay*f.DepartureUTCDateDiff
+}
+
+// median returns the middle value, the upper one of an even count.
+func median(values []int) int {
+	sorted := slices.Sorted(slices.Values(values))
+	return sorted[len(sorted)/2]
+}
+
+// BlockTimeStatistics collects the block times of the operations of the
+// records from one date to another per carrier, route, direction, aircraft
+// type and season, each season compared with the one before.
+func BlockTimeStatistics(records []ScheduleRecord, from, to time.Time) []BlockTimeStats {
+	blocks := make(map[blockTimeKey][]int)
+	for _, op := range ExpandRecords(records) {
+		if op.Date.Before(from) || op.Date.After(to) {
+			continue
+		}
+		key := blockTimeKey{op.Flight.Airline, op.Flight.Origin, op.Flight.Destination, op.Flight.AircraftType, SeasonOf(op.Date)}
+		blocks[key] = append(blocks[key], utcBlockMinutes(op.Flight))
+	}
+
+	keys := slices.Collect(maps.Keys(blocks))
+	slices.SortFunc(keys, func(a, b blockTimeKey) int {
+		return cmp.Or(
+			cmp.Compare(a.carrier, b.carrier),
+			cmp.Compare(a.origin, b.origin),
+			cmp.Compare(a.destination, b.destination),
+			cmp.Compare(a.aircraftType, b.aircraftType),
+			a.season.Start.Compare(b.season.Start),
+		)
+	})
+
+	stats := make([]BlockTimeStats, 0, len(keys))
+	for i, key := range keys {
+		b := blocks[key]
+		s := BlockTimeStats{
+			Carrier:      key.carrier,
+			Origin:       key.origin,
+			Destination:  key.destination,
+			AircraftType: key.aircraftType,
+			Season:       key.season.Code,
+			Operations:   len(b),
+			Min:          slices.Min(b),
+			Median:       median(b),
+			Max:          slices.Max(b),
+		}
+		// The key before is the previous season of the same carrier, route
+		// and aircraft type when only the season differs
+		if i > 0 {
+			previous := keys[i-1]
+			previous.season = key.season
+			if previous == key && keys[i-1].season.Next().Code == key.season.Code {
+				change := s.Median - stats[i-1].Median
+				s.MedianChange = &change
+			}
+		}
+		stats = append(stats, s)
+	}
+	return stats
+}
+
+// blockTimeColumns are the columns of the block time report.
+var blockTimeColumns = []string{"carrier", "origin", "destination", "aircraft_type", "season", "operations", "min", "median", "max", "median_change"}
+
+// BlockTimeRows renders the block time report with a header row in the
+// locale, the block times as hours and minutes and the change of the median
+// in minutes.
+func BlockTimeRows(stats []BlockTimeStats, l Locale) [][]string {
+	header := make([]string, 0, len(blockTimeColumns))
+	for _, c := range blockTimeColumns {
+		header = append(header, T(l, "blocktime."+c))
+	}
+	rows := [][]string{header}
+	for _, s := range stats {
+		var change string
+		if s.MedianChange != nil {
+			change = fmt.Sprintf("%+d", *s.MedianChange)
+		}
+		rows = append(rows, []string{
+			s.Carrier,
+			s.Origin,
+			s.Destination,
+			s.AircraftType,
+			s.Season,
+			strconv.Itoa(s.Operations),
+			NumberToTime(int64(s.Min)),
+			NumberToTime(int64(s.Median)),
+			NumberToTime(int64(s.Max)),
+			change,
+		})
+	}
+	return rows
+}
+
+// WriteBlockTimesCSV writes the block time report using the locale and format of opts.
+func WriteBlockTimesCSV(writer io.Writer, stats []BlockTimeStats, opts ExportOptions) error {
+	return writeCSVRows(writer, BlockTimeRows(stats, opts.Locale), opts.format().Delimiter)
+}
+
+// WriteBlockTimesXLSX writes the block time report as a spreadsheet.
+func WriteBlockTimesXLSX(writer io.Writer, stats []BlockTimeStats, opts ExportOptions) error {
+	return WriteXLSX(writer, T(opts.Locale, "blocktime.sheet"), BlockTimeRows(stats, opts.Locale))
+}
@@ -0,0 +1,48 @@
+package internal
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBlockTimeStatistics(t *testing.T) {
+	record := func(origin, destination, number, start, end, days, aircraft string, departureUTC, arrivalUTC TimeOfDay) ScheduleRecord {
+		r := testRecord(origin, destination, "LH", number, "00:00", "00:00", start, end, days, aircraft, "LH", "J")
+		r.DepartureUTC, r.ArrivalUTC = departureUTC, arrivalUTC
+		return r
+	}
+	records := []ScheduleRecord{
+		// Winter 1:45, summer 1:40 on Mondays and 1:50 on the other days
+		record("KRK", "FRA", "1365", "2025-03-17", "2025-03-29", "1234567", "32N", 9*60+20, 11*60+5),
+		record("KRK", "FRA", "1365", "2025-03-30", "2025-04-13", "1......", "32N", 8*60+20, 10*60),
+		record("KRK", "FRA", "1365", "2025-03-30", "2025-04-13", ".234567", "32N", 8*60+20, 10*60+10),
+		// The way back arrives after midnight UTC
+		func() ScheduleRecord {
+			r := record("FRA", "KRK", "1364", "2025-03-30", "2025-04-13", "1234567", "32N", 23*60, 30)
+			r.ArrivalUTCDateDiff = 1
+			return r
+		}(),
+		// Not flown in the summer, no change to compare
+		record("KRK", "FRA", "1367", "2025-03-24", "2025-03-29", "1234567", "E95", 12*60, 13*60+50),
+		record("KRK", "FRA", "1369", "2025-10-26", "2025-11-02", "1234567", "E95", 12*60, 13*60+55),
+	}
+
+	from, _ := time.Parse(dateLayout, "2025-03-01")
+	to, _ := time.Parse(dateLayout, "2025-10-31")
+	var got []string
+	for _, row := range BlockTimeRows(BlockTimeStatistics(records, from, to), LocaleEN)[1:] {
+		got = append(got, strings.Join(row, " "))
+	}
+	expected := []string{
+		"LH FRA KRK 32N S25 15 01:30 01:30 01:30 ",
+		"LH KRK FRA 32N W24 13 01:45 01:45 01:45 ",
+		"LH KRK FRA 32N S25 15 01:40 01:50 01:50 +5",
+		"LH KRK FRA E95 W24 6 01:50 01:50 01:50 ",
+		"LH KRK FRA E95 W25 6 01:55 01:55 01:55 ",
+	}
+	if !slices.Equal(got, expected) {
+		t.Errorf("expected\n%q\ngot\n%q", expected, got)
+	}
+}
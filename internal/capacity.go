@@ -0,0 +1,198 @@
+package internal
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// seatKey is an aircraft type in a cabin configuration, an empty
+// configuration stands for any configuration of the type.
+type seatKey struct {
+	aircraftType  string
+	configuration string
+}
+
+// SeatTable maps aircraft types, optionally in a cabin configuration, to
+// their seat capacity.
+type SeatTable struct {
+	seats map[seatKey]int
+}
+
+// ReadSeatTable reads a CSV with aircraft_type, configuration and seats
+// columns and a header row.
+func ReadSeatTable(r io.Reader) (SeatTable, error) {
+	rows, err := readReferenceCSV(r, []string{"aircraft_type", "configuration", "seats"}, "seat table")
+	if err != nil {
+		return SeatTable{}, err
+	}
+	table := SeatTable{seats: make(map[seatKey]int)}
+	for i, row := range rows {
+		seats, err := strconv.Atoi(row[2])
+		if err != nil || seats <= 0 {
+			return SeatTable{}, fmt.Errorf("invalid seats %q for aircraft %s on line %d", row[2], row[0], i+2)
+		}
+		table.seats[newSeatKey(row[0], row[1])] = seats
+	}
+	return table, nil
+}
+
+func newSeatKey(aircraftType, configuration string) seatKey {
+	return seatKey{strings.ToUpper(strings.TrimSpace(aircraftType)), strings.ToUpper(strings.TrimSpace(configuration))}
+}
+
+// DefaultSeatTable returns the typical seat capacity of the aircraft types
+// of the reference data.
+func DefaultSeatTable() SeatTable {
+	table := SeatTable{seats: make(map[seatKey]int)}
+	for _, a := range References().AircraftTypes() {
+		if a.Seats > 0 {
+			table.seats[seatKey{aircraftType: a.IATA}] = a.Seats
+		}
+	}
+	return table
+}
+
+// LoadSeatTable returns the default seat table with the entries of the CSV
+// file at path added or replacing the defaults. A missing file leaves the
+// defaults.
+func LoadSeatTable(path string) (SeatTable, error) {
+	table := DefaultSeatTable()
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return table, nil
+	}
+	if err != nil {
+		return table, err
+	}
+	defer f.Close()
+
+	custom, err := ReadSeatTable(f)
+	if err != nil {
+		return table, err
+	}
+	for key, seats := range custom.seats {
+		table.seats[key] = seats
+	}
+	return table, nil
+}
+
+// Seats returns the capacity of the aircraft type in the configuration,
+// falling back to the capacity of the type in any configuration.
+func (t SeatTable) Seats(aircraftType, configuration string) (int, bool) {
+	key := newSeatKey(aircraftType, configuration)
+	if seats, ok := t.seats[key]; ok {
+		return seats, true
+	}
+	seats, ok := t.seats[seatKey{aircraftType: key.aircraftType}]
+	return seats, ok
+}
+
+// WeeklyCapacity is the number of operations and seats of a carrier on a
+// route in a week.
+type WeeklyCapacity struct {
+	Carrier     string `json:"carrier"`
+	Origin      string `json:"origin"`
+	Destination string `json:"destination"`
+	// Week is the ISO week such as 2025-W14, starting on WeekStart, a Monday
+	Week        string    `json:"week"`
+	WeekStart   time.Time `json:"week_start"`
+	Frequencies int       `json:"frequencies"`
+	Seats       int       `json:"seats"`
+	// UnknownSeats counts the frequencies flown by aircraft missing from the
+	// seat table, they add no seats
+	UnknownSeats int `json:"unknown_seats"`
+}
+
+type capacityKey struct {
+	carrier, origin, destination string
+	week                         time.Time
+}
+
+// WeeklyCapacities counts the operations of the records from one date to
+// another per carrier, route and week, with their seats from the table.
+// Weeks cut by the period only count the days within it.
+func WeeklyCapacities(records []ScheduleRecord, seats SeatTable, from, to time.Time) []WeeklyCapacity {
+	weeks := make(map[capacityKey]*WeeklyCapacity)
+	for _, op := range ExpandRecords(records) {
+		if op.Date.Before(from) || op.Date.After(to) {
+			continue
+		}
+		start := op.Date.AddDate(0, 0, 1-WeekdayOf(op.Date))
+		key := capacityKey{op.Flight.Airline, op.Flight.Origin, op.Flight.Destination, start}
+		week, ok := weeks[key]
+		if !ok {
+			year, number := start.ISOWeek()
+			week = &WeeklyCapacity{
+				Carrier:     key.carrier,
+				Origin:      key.origin,
+				Destination: key.destination,
+				Week:        fmt.Sprintf("%d-W%02d", year, number),
+				WeekStart:   start,
+			}
+			weeks[key] = week
+		}
+		week.Frequencies++
+		if n, ok := seats.Seats(op.Flight.AircraftType, op.Flight.ConfigurationVersion); ok {
+			week.Seats += n
+		} else {
+			week.UnknownSeats++
+		}
+	}
+
+	out := make([]WeeklyCapacity, 0, len(weeks))
+	for _, week := range weeks {
+		out = append(out, *week)
+	}
+	slices.SortFunc(out, func(a, b WeeklyCapacity) int {
+		return cmp.Or(
+			cmp.Compare(a.Carrier, b.Carrier),
+			cmp.Compare(a.Origin, b.Origin),
+			cmp.Compare(a.Destination, b.Destination),
+			a.WeekStart.Compare(b.WeekStart),
+		)
+	})
+	return out
+}
+
+// capacityColumns are the columns of the capacity report.
+var capacityColumns = []string{"carrier", "origin", "destination", "week", "week_start", "frequencies", "seats", "unknown_seats"}
+
+// CapacityRows renders the capacity report with a header row in the locale.
+func CapacityRows(weeks []WeeklyCapacity, l Locale, dateLayout string) [][]string {
+	header := make([]string, 0, len(capacityColumns))
+	for _, c := range capacityColumns {
+		header = append(header, T(l, "capacity."+c))
+	}
+	rows := [][]string{header}
+	for _, w := range weeks {
+		rows = append(rows, []string{
+			w.Carrier,
+			w.Origin,
+			w.Destination,
+			w.Week,
+			w.WeekStart.Format(dateLayout),
+			strconv.Itoa(w.Frequencies),
+			strconv.Itoa(w.Seats),
+			strconv.Itoa(w.UnknownSeats),
+		})
+	}
+	return rows
+}
+
+// WriteCapacityCSV writes the capacity report using the locale and format of opts.
+func WriteCapacityCSV(writer io.Writer, weeks []WeeklyCapacity, opts ExportOptions) error {
+	format := opts.format()
+	return writeCSVRows(writer, CapacityRows(weeks, opts.Locale, format.DateLayout), format.Delimiter)
+}
+
+// WriteCapacityXLSX writes the capacity report as a spreadsheet.
+func WriteCapacityXLSX(writer io.Writer, weeks []WeeklyCapacity, opts ExportOptions) error {
+	return WriteXLSX(writer, T(opts.Locale, "capacity.sheet"), CapacityRows(weeks, opts.Locale, opts.format().DateLayout))
+}
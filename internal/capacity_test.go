@@ -0,0 +1,104 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSeatTable(t *testing.T) {
+	table, err := ReadSeatTable(strings.NewReader("aircraft_type,configuration,seats\n32N,,180\n32n,c12y168,180\nE95,,120\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name          string
+		aircraftType  string
+		configuration string
+		expected      int
+		found         bool
+	}{
+		{name: "Type", aircraftType: "E95", expected: 120, found: true},
+		{name: "Configuration", aircraftType: "32N", configuration: "C12Y168", expected: 180, found: true},
+		{name: "Unknown configuration falls back to type", aircraftType: "32N", configuration: "C20Y160", expected: 180, found: true},
+		{name: "Unknown type", aircraftType: "388", found: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			seats, found := table.Seats(tt.aircraftType, tt.configuration)
+			if seats != tt.expected || found != tt.found {
+				t.Errorf("Test %s failed: expected %d %v, got %d %v", tt.name, tt.expected, tt.found, seats, found)
+			}
+		})
+	}
+
+	if _, err := ReadSeatTable(strings.NewReader("aircraft_type,configuration,seats\n32N,,many\n")); err == nil {
+		t.Errorf("expected error for invalid seats")
+	}
+}
+
+func TestLoadSeatTable(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "seats.csv")
+	if err := os.WriteFile(path, []byte("aircraft_type,configuration,seats\nE95,,112\nE95,C8Y104,112\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	table, err := LoadSeatTable(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if seats, _ := table.Seats("E95", ""); seats != 112 {
+		t.Errorf("expected the file to replace the default, got %d", seats)
+	}
+	if seats, _ := table.Seats("32N", ""); seats != 180 {
+		t.Errorf("expected the defaults to be kept, got %d", seats)
+	}
+
+	if _, err := LoadSeatTable(filepath.Join(t.TempDir(), "missing.csv")); err != nil {
+		t.Errorf("expected the defaults for a missing file, got %v", err)
+	}
+}
+
+func TestWeeklyCapacities(t *testing.T) {
+	date := func(s string) time.Time {
+		d, _ := time.Parse(dateLayout, s)
+		return d
+	}
+	seats, err := ReadSeatTable(strings.NewReader("aircraft_type,configuration,seats\n32N,,180\nE95,,120\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	records := previewRecords()
+	// Another carrier on the same route is counted apart
+	records = append(records, testRecord("KRK", "FRA", "LX", "1", "12:00", "14:00", "2025-03-31", "2025-04-06", "1......", "221", "LX", "J"))
+
+	weeks := WeeklyCapacities(records, seats, date("2025-03-31"), date("2025-04-13"))
+	expected := []WeeklyCapacity{
+		// LH1365 on Mon, Wed, Fri and LH999 on Tue, Thu, Sat from 1 April
+		{Carrier: "LH", Origin: "KRK", Destination: "FRA", Week: "2025-W14", WeekStart: date("2025-03-31"), Frequencies: 6, Seats: 3 * 180, UnknownSeats: 3},
+		{Carrier: "LH", Origin: "KRK", Destination: "FRA", Week: "2025-W15", WeekStart: date("2025-04-07"), Frequencies: 6, Seats: 3 * 180, UnknownSeats: 3},
+		// Daily but 2 April
+		{Carrier: "LH", Origin: "KRK", Destination: "MUC", Week: "2025-W14", WeekStart: date("2025-03-31"), Frequencies: 6, Seats: 6 * 120},
+		{Carrier: "LH", Origin: "KRK", Destination: "MUC", Week: "2025-W15", WeekStart: date("2025-04-07"), Frequencies: 7, Seats: 7 * 120},
+		{Carrier: "LX", Origin: "KRK", Destination: "FRA", Week: "2025-W14", WeekStart: date("2025-03-31"), Frequencies: 1, UnknownSeats: 1},
+	}
+	if !reflect.DeepEqual(weeks, expected) {
+		t.Errorf("expected %+v, got %+v", expected, weeks)
+	}
+}
+
+func TestCapacityRows(t *testing.T) {
+	start, _ := time.Parse(dateLayout, "2025-03-31")
+	weeks := []WeeklyCapacity{{Carrier: "LH", Origin: "KRK", Destination: "FRA", Week: "2025-W14", WeekStart: start, Frequencies: 6, Seats: 1080}}
+	expected := [][]string{
+		{"Carrier", "From", "To", "Week", "Week start", "Frequencies", "Seats", "Operations without seats"},
+		{"LH", "KRK", "FRA", "2025-W14", "2025-03-31", "6", "1080", "0"},
+	}
+	if got := CapacityRows(weeks, LocaleEN, "2006-01-02"); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+}
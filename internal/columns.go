@@ -0,0 +1,272 @@
+package internal
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type Column struct {
+	Key string
+	// Value renders the column of a record, dates use dateLayout
+	Value func(r ScheduleRecord, dateLayout string) string
+}
+
+// Header returns the translated column header.
+func (c Column) Header(l Locale) string {
+	return T(l, "column."+c.Key)
+}
+
+func textColumn(key string, value func(r ScheduleRecord) string) Column {
+	return Column{Key: key, Value: func(r ScheduleRecord, _ string) string { return value(r) }}
+}
+
+// utcColumn is left blank for records joined across a daylight saving time
+// switch, their UTC times differ between the periods.
+func utcColumn(key string, value func(r ScheduleRecord) string) Column {
+	return textColumn(key, func(r ScheduleRecord) string {
+		if r.MergedDST {
+			return ""
+		}
+		return value(r)
+	})
+}
+
+// shiftColumn shows a change of minutes with its sign, blank without one.
+func shiftColumn(key string, value func(r ScheduleRecord) int) Column {
+	return textColumn(key, func(r ScheduleRecord) string {
+		if value(r) == 0 {
+			return ""
+		}
+		return fmt.Sprintf("%+d", value(r))
+	})
+}
+
+func dateColumn(key string, value func(r ScheduleRecord) time.Time) Column {
+	return Column{Key: key, Value: func(r ScheduleRecord, dateLayout string) string {
+		return value(r).Format(dateLayout)
+	}}
+}
+
+// Columns lists every column available for export.
+var Columns = []Column{
+	textColumn("origin", func(r ScheduleRecord) string { return r.Origin }),
+	textColumn("destination", func(r ScheduleRecord) string { return r.Destination }),
+	textColumn("airline", func(r ScheduleRecord) string { return r.Airline }),
+	textColumn("flight_number", func(r ScheduleRecord) string { return strconv.Itoa(r.FlightNumber) + r.Suffix }),
+	textColumn("departure", func(r ScheduleRecord) string { return r.Departure.WithDayOffset(r.DepartureDateDiff) }),
+	textColumn("arrival", func(r ScheduleRecord) string { return r.Arrival.WithDayOffset(r.ArrivalDateDiff) }),
+	dateColumn("start_date", func(r ScheduleRecord) time.Time { return r.StartDate }),
+	dateColumn("end_date", func(r ScheduleRecord) time.Time { return r.EndDate }),
+	textColumn("days", func(r ScheduleRecord) string { return r.Days.String() }),
+	textColumn("aircraft_type", func(r ScheduleRecord) string { return r.AircraftType }),
+	textColumn("operator", func(r ScheduleRecord) string { return operatorToICAO(r.AircraftOwner) }),
+	textColumn("service_type", func(r ScheduleRecord) string { return r.ServiceType }),
+	textColumn("registration", func(r ScheduleRecord) string { return r.Registration }),
+	textColumn("configuration", func(r ScheduleRecord) string { return r.ConfigurationVersion }),
+	textColumn("operator_name", func(r ScheduleRecord) string {
+		o, _ := References().Operator(r.AircraftOwner)
+		return o.Name
+	}),
+	textColumn("alliance", func(r ScheduleRecord) string {
+		o, _ := References().Operator(r.AircraftOwner)
+		return o.Alliance
+	}),
+	textColumn("aircraft_icao", func(r ScheduleRecord) string {
+		a, _ := References().AircraftType(r.AircraftType)
+		return a.ICAO
+	}),
+	textColumn("aircraft_family", func(r ScheduleRecord) string {
+		a, _ := References().AircraftType(r.AircraftType)
+		return a.Family
+	}),
+	textColumn("wake_category", func(r ScheduleRecord) string {
+		a, _ := References().AircraftType(r.AircraftType)
+		return a.WakeCategory
+	}),
+	utcColumn("departure_utc", func(r ScheduleRecord) string { return r.DepartureUTC.WithDayOffset(r.DepartureUTCDateDiff) }),
+	utcColumn("arrival_utc", func(r ScheduleRecord) string { return r.ArrivalUTC.WithDayOffset(r.ArrivalUTCDateDiff) }),
+	textColumn("departure_date_diff", func(r ScheduleRecord) string { return strconv.Itoa(r.DepartureDateDiff) }),
+	textColumn("arrival_date_diff", func(r ScheduleRecord) string { return strconv.Itoa(r.ArrivalDateDiff) }),
+	utcColumn("departure_variation", func(r ScheduleRecord) string { return strconv.Itoa(r.DepartureVariation) }),
+	utcColumn("arrival_variation", func(r ScheduleRecord) string { return strconv.Itoa(r.ArrivalVariation) }),
+	shiftColumn("dst_shift", func(r ScheduleRecord) int { return r.DSTShift }),
+	shiftColumn("arrival_dst_shift", func(r ScheduleRecord) int { return r.ArrivalDSTShift }),
+	{Key: "exceptions", Value: func(r ScheduleRecord, dateLayout string) string {
+		dates := make([]string, 0, len(r.Exceptions))
+		for _, d := range r.Exceptions {
+			dates = append(dates, d.Format(dateLayout))
+		}
+		return strings.Join(dates, " ")
+	}},
+}
+
+func ColumnByKey(key string) (Column, bool) {
+	for _, c := range Columns {
+		if c.Key == key {
+			return c, true
+		}
+	}
+	return Column{}, false
+}
+
+// ExportTemplate is a named, ordered selection of columns.
+type ExportTemplate struct {
+	Name    string   `json:"name"`
+	Columns []string `json:"columns"`
+}
+
+const DefaultTemplateName = "default"
+
+// DefaultTemplate reproduces the original 12 column export.
+var DefaultTemplate = ExportTemplate{
+	Name: DefaultTemplateName,
+	Columns: []string{
+		"origin", "destination", "airline", "flight_number", "departure", "arrival",
+		"start_date", "end_date", "days", "aircraft_type", "operator", "service_type",
+	},
+}
+
+// ParseColumnList builds an unnamed template from a comma separated list of column keys.
+func ParseColumnList(s string) ExportTemplate {
+	var t ExportTemplate
+	for _, key := range strings.Split(s, ",") {
+		key = strings.TrimSpace(key)
+		if key != "" {
+			t.Columns = append(t.Columns, key)
+		}
+	}
+	return t
+}
+
+func (t ExportTemplate) Validate() error {
+	if len(t.Columns) == 0 {
+		return errors.New("template has no columns")
+	}
+	seen := make(map[string]bool)
+	for _, key := range t.Columns {
+		if _, ok := ColumnByKey(key); !ok {
+			return fmt.Errorf("unknown column %q", key)
+		}
+		if seen[key] {
+			return fmt.Errorf("column %q used more than once", key)
+		}
+		seen[key] = true
+	}
+	return nil
+}
+
+func (t ExportTemplate) Header(l Locale) []string {
+	header := make([]string, 0, len(t.Columns))
+	for _, key := range t.Columns {
+		c, _ := ColumnByKey(key)
+		header = append(header, c.Header(l))
+	}
+	return header
+}
+
+// Render writes the template columns of a record, dates use dateLayout.
+func (t ExportTemplate) Render(r ScheduleRecord, dateLayout string) []string {
+	out := make([]string, 0, len(t.Columns))
+	for _, key := range t.Columns {
+		c, _ := ColumnByKey(key)
+		out = append(out, c.Value(r, dateLayout))
+	}
+	return out
+}
+
+// TemplateStore keeps user defined export templates in a JSON file.
+type TemplateStore struct {
+	path string
+	mu   sync.Mutex
+}
+
+func NewTemplateStore(path string) *TemplateStore {
+	return &TemplateStore{path: path}
+}
+
+func (s *TemplateStore) load() ([]ExportTemplate, error) {
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var templates []ExportTemplate
+	if err := json.Unmarshal(data, &templates); err != nil {
+		return nil, fmt.Errorf("failed to parse templates file: %w", err)
+	}
+	return templates, nil
+}
+
+// List returns the default template followed by all saved templates.
+func (s *TemplateStore) List() ([]ExportTemplate, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	saved, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	return append([]ExportTemplate{DefaultTemplate}, saved...), nil
+}
+
+func (s *TemplateStore) Get(name string) (ExportTemplate, error) {
+	if name == "" || name == DefaultTemplateName {
+		return DefaultTemplate, nil
+	}
+	templates, err := s.List()
+	if err != nil {
+		return ExportTemplate{}, err
+	}
+	for _, t := range templates {
+		if t.Name == name {
+			return t, nil
+		}
+	}
+	return ExportTemplate{}, fmt.Errorf("template %q not found", name)
+}
+
+// Save adds a template or replaces the one with the same name.
+func (s *TemplateStore) Save(t ExportTemplate) error {
+	t.Name = strings.TrimSpace(t.Name)
+	if t.Name == "" {
+		return errors.New("template name is required")
+	}
+	if t.Name == DefaultTemplateName {
+		return errors.New("default template cannot be overwritten")
+	}
+	if err := t.Validate(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	templates, err := s.load()
+	if err != nil {
+		return err
+	}
+	replaced := false
+	for i := range templates {
+		if templates[i].Name == t.Name {
+			templates[i] = t
+			replaced = true
+		}
+	}
+	if !replaced {
+		templates = append(templates, t)
+	}
+
+	data, err := json.MarshalIndent(templates, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(s.path, data, 0o644)
+}
@@ -0,0 +1,199 @@
+package internal
+
+import (
+	"bytes"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExportTemplateValidate(t *testing.T) {
+	tests := []struct {
+		name      string
+		template  ExportTemplate
+		expectErr bool
+	}{
+		{
+			name:      "Default template",
+			template:  DefaultTemplate,
+			expectErr: false,
+		},
+		{
+			name:      "Reordered subset",
+			template:  ExportTemplate{Columns: []string{"flight_number", "origin", "registration"}},
+			expectErr: false,
+		},
+		{
+			name:      "Unknown column",
+			template:  ExportTemplate{Columns: []string{"origin", "gate"}},
+			expectErr: true,
+		},
+		{
+			name:      "Duplicated column",
+			template:  ExportTemplate{Columns: []string{"origin", "origin"}},
+			expectErr: true,
+		},
+		{
+			name:      "No columns",
+			template:  ExportTemplate{},
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.template.Validate()
+			if (err != nil) != tt.expectErr {
+				t.Errorf("Test %s failed: expected error: %v, got: %v", tt.name, tt.expectErr, err)
+			}
+		})
+	}
+}
+
+func TestExportTemplateRender(t *testing.T) {
+	record := testRecord("KRK", "FRA", "LH", "1365", "10:20", "12:05", "2025-03-30", "2025-10-25", "1234567", "32N", "LH", "J")
+	record.Registration = "DAINA"
+
+	template := ParseColumnList("registration, flight_number,origin,start_date,operator")
+	expectedHeaderPL := []string{"Rejestracja", "Numer", "Z", "Od dnia", "Operator"}
+	expectedHeaderEN := []string{"Registration", "Flight", "From", "Start date", "Operator"}
+	expectedRow := []string{"DAINA", "1365", "KRK", "30.03.2025", "DLH"}
+
+	if got := template.Header(LocalePL); !reflect.DeepEqual(got, expectedHeaderPL) {
+		t.Errorf("expected header %v, got %v", expectedHeaderPL, got)
+	}
+	if got := template.Header(LocaleEN); !reflect.DeepEqual(got, expectedHeaderEN) {
+		t.Errorf("expected header %v, got %v", expectedHeaderEN, got)
+	}
+	if got := template.Render(record, "02.01.2006"); !reflect.DeepEqual(got, expectedRow) {
+		t.Errorf("expected row %v, got %v", expectedRow, got)
+	}
+}
+
+func TestTemplateStore(t *testing.T) {
+	store := NewTemplateStore(filepath.Join(t.TempDir(), "templates.json"))
+
+	templates, err := store.List()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(templates) != 1 || templates[0].Name != DefaultTemplateName {
+		t.Fatalf("expected only default template, got %v", templates)
+	}
+
+	short := ExportTemplate{Name: "short", Columns: []string{"flight_number", "departure"}}
+	if err := store.Save(short); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	short.Columns = []string{"departure", "flight_number"}
+	if err := store.Save(short); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got, err := store.Get("short")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(got, short) {
+		t.Errorf("expected %v, got %v", short, got)
+	}
+
+	if templates, _ := store.List(); len(templates) != 2 {
+		t.Errorf("expected 2 templates, got %v", templates)
+	}
+	if err := store.Save(ExportTemplate{Name: DefaultTemplateName, Columns: []string{"origin"}}); err == nil {
+		t.Errorf("expected error when overwriting default template")
+	}
+	if err := store.Save(ExportTemplate{Name: "bad", Columns: []string{"gate"}}); err == nil {
+		t.Errorf("expected error when saving invalid template")
+	}
+	if _, err := store.Get("missing"); err == nil {
+		t.Errorf("expected error for missing template")
+	}
+}
+
+func TestCreateCSVFromResponseTemplate(t *testing.T) {
+	data := []byte(`[{"airline":"LH","flightNumber":1365,"periodOfOperationLT":{"startDate":"30MAR25","endDate":"5APR25","daysOfOperation":"1234567"},"legs":[{"origin":"KRK","destination":"FRA","aircraftOwner":"LH","aircraftType":"32N","registration":"DAINA","aircraftDepartureTimeLT":620,"aircraftArrivalTimeLT":725}]}]`)
+
+	tests := []struct {
+		name     string
+		opts     ExportOptions
+		expected string
+	}{
+		{
+			name:     "Polish locale defaults",
+			opts:     ExportOptions{Locale: LocalePL},
+			expected: "Numer;Rejestracja;Odlot;Od dnia\n1365;DAINA;10:20;30.03.2025\n",
+		},
+		{
+			name:     "English locale defaults",
+			opts:     ExportOptions{Locale: LocaleEN},
+			expected: "Flight,Registration,Departure,Start date\n1365,DAINA,10:20,2025-03-30\n",
+		},
+		{
+			name:     "Polish headers with overridden format",
+			opts:     ExportOptions{Locale: LocalePL, Delimiter: ',', DateLayout: "2006-01-02"},
+			expected: "Numer,Rejestracja,Odlot,Od dnia\n1365,DAINA,10:20,2025-03-30\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			tt.opts.Template = ExportTemplate{Columns: []string{"flight_number", "registration", "departure", "start_date"}}
+			if _, err := CreateCSVFromResponse(&buf, data, tt.opts, nil); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := buf.String(); got != tt.expected {
+				t.Errorf("Test %s failed: expected %q, got %q", tt.name, tt.expected, got)
+			}
+		})
+	}
+
+	var buf bytes.Buffer
+	opts := ExportOptions{Template: ExportTemplate{Columns: []string{"gate"}}}
+	if _, err := CreateCSVFromResponse(&buf, data, opts, nil); err == nil || !strings.Contains(err.Error(), "gate") {
+		t.Errorf("expected unknown column error, got %v", err)
+	}
+}
+
+func TestCreateCSVFromResponseExceptions(t *testing.T) {
+	// Daily flight with 2 April cancelled
+	data := []byte(`[{"airline":"LH","flightNumber":1365,"periodOfOperationLT":{"startDate":"30MAR25","endDate":"1APR25","daysOfOperation":"1234567"},"legs":[{"origin":"KRK","destination":"FRA","aircraftDepartureTimeLT":620,"aircraftArrivalTimeLT":725}]},` +
+		`{"airline":"LH","flightNumber":1365,"periodOfOperationLT":{"startDate":"3APR25","endDate":"12APR25","daysOfOperation":"1234567"},"legs":[{"origin":"KRK","destination":"FRA","aircraftDepartureTimeLT":620,"aircraftArrivalTimeLT":725}]}]`)
+
+	tests := []struct {
+		name       string
+		exceptions bool
+		expected   string
+	}{
+		{
+			name:       "Split periods",
+			exceptions: false,
+			expected:   "Flight,Start date,End date,Days\n1365,2025-03-30,2025-04-12,12.4567\n1365,2025-04-09,2025-04-09,..3....\n",
+		},
+		{
+			name:       "Reported exceptions",
+			exceptions: true,
+			expected:   "Flight,Start date,End date,Days,No operations\n1365,2025-03-30,2025-04-12,1234567,2025-04-02\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			opts := ExportOptions{
+				Exceptions: tt.exceptions,
+				Template:   ExportTemplate{Columns: []string{"flight_number", "start_date", "end_date", "days"}},
+				Locale:     LocaleEN,
+			}
+			if _, err := CreateCSVFromResponse(&buf, data, opts, nil); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := buf.String(); got != tt.expected {
+				t.Errorf("Test %s failed: expected %q, got %q", tt.name, tt.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,383 @@
+package internal
+
+import (
+	"cmp"
+	_ "embed"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:embed data/hubs.csv
+var hubsCSV string
+
+// HubRule bounds the time in minutes between the arrival at a hub and the
+// onward departure of a connection.
+type HubRule struct {
+	MinConnection int `json:"min_connection"`
+	MaxConnection int `json:"max_connection"`
+	// Carriers fly onward from the hub, every carrier of the query when empty
+	Carriers []string `json:"carriers,omitempty"`
+}
+
+// HubTable holds the connection rules of the hubs passengers can change at.
+type HubTable struct {
+	rules map[string]HubRule
+}
+
+// ReadHubTable reads a CSV with hub, min_connection, max_connection and
+// carriers columns and a header row, the carriers separated by spaces.
+func ReadHubTable(r io.Reader) (HubTable, error) {
+	rows, err := readReferenceCSV(r, []string{"hub", "min_connection", "max_connection", "carriers"}, "hub table")
+	if err != nil {
+		return HubTable{}, err
+	}
+	table := HubTable{rules: make(map[string]HubRule)}
+	for i, row := range rows {
+		hub := strings.ToUpper(row[0])
+		if err := ValidateAirport(hub); err != nil {
+			return HubTable{}, fmt.Errorf("invalid hub on line %d: %w", i+2, err)
+		}
+		minConnection, errMin := strconv.Atoi(row[1])
+		maxConnection, errMax := strconv.Atoi(row[2])
+		if errMin != nil || errMax != nil || minConnection < 0 || maxConnection < minConnection {
+			return HubTable{}, fmt.Errorf("invalid connection times %q to %q for hub %s on line %d", row[1], row[2], hub, i+2)
+		}
+		carriers := strings.Fields(strings.ToUpper(row[3]))
+		for _, carrier := range carriers {
+			if len(carrier) != 2 {
+				return HubTable{}, fmt.Errorf("invalid carrier %q for hub %s on line %d", carrier, hub, i+2)
+			}
+		}
+		table.rules[hub] = HubRule{MinConnection: minConnection, MaxConnection: maxConnection, Carriers: carriers}
+	}
+	return table, nil
+}
+
+// DefaultHubTable returns the connection times of the hubs of the carriers,
+// from the embedded reference data.
+func DefaultHubTable() HubTable {
+	table, err := ReadHubTable(strings.NewReader(hubsCSV))
+	if err != nil {
+		panic(fmt.Sprintf("invalid embedded hub data: %v", err))
+	}
+	return table
+}
+
+// LoadHubTable returns the default hub table with the hubs of the CSV file
+// at path added or replacing the defaults. A missing file leaves the
+// defaults.
+func LoadHubTable(path string) (HubTable, error) {
+	table := DefaultHubTable()
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return table, nil
+	}
+	if err != nil {
+		return table, err
+	}
+	defer f.Close()
+
+	custom, err := ReadHubTable(f)
+	if err != nil {
+		return table, err
+	}
+	for hub, rule := range custom.rules {
+		table.rules[hub] = rule
+	}
+	return table, nil
+}
+
+// Rule returns the connection times of the hub.
+func (t HubTable) Rule(hub string) (HubRule, bool) {
+	rule, ok := t.rules[strings.ToUpper(hub)]
+	return rule, ok
+}
+
+// Hubs returns the hubs of the table in alphabetical order.
+func (t HubTable) Hubs() []string {
+	hubs := make([]string, 0, len(t.rules))
+	for hub := range t.rules {
+		hubs = append(hubs, hub)
+	}
+	slices.Sort(hubs)
+	return hubs
+}
+
+// ConnectionQueries returns the queries of the default routes of the
+// carriers from the origin to a hub of the table, and for each of those hubs
+// the queries of the onward flights to the destination by the carriers of
+// the hub, by all the carriers when the hub lists none, so a connection may
+// change carriers.
+func ConnectionQueries(carriers []string, hubs HubTable, origin, destination string, period Season, mode TimeMode) ([]ApiQuery, error) {
+	origin = strings.ToUpper(strings.TrimSpace(origin))
+	destination = strings.ToUpper(strings.TrimSpace(destination))
+	if err := ValidateAirport(destination); err != nil {
+		return nil, err
+	}
+	from, to := DateToSSIM(period.Start.Format(dateLayout)), DateToSSIM(period.End.Format(dateLayout))
+
+	var inbound []ApiQuery
+	var airlines []string
+	reached := make(map[string]bool)
+	for _, carrier := range carriers {
+		code, ok := CarrierCode(strings.ToUpper(strings.TrimSpace(carrier)))
+		if !ok {
+			return nil, fmt.Errorf("unknown carrier %q", carrier)
+		}
+		for _, q := range GetQueryListForAirline(code, from, to, mode) {
+			if !slices.Contains(airlines, q.Airline) {
+				airlines = append(airlines, q.Airline)
+			}
+			if _, ok := hubs.Rule(q.Destination); !ok || q.Origin != origin || q.Destination == destination {
+				continue
+			}
+			inbound = append(inbound, q)
+			reached[q.Destination] = true
+		}
+	}
+	if len(inbound) == 0 {
+		return nil, fmt.Errorf("no route of the carriers leads from %s to a hub", origin)
+	}
+
+	queries := inbound
+	for _, hub := range hubs.Hubs() {
+		if !reached[hub] {
+			continue
+		}
+		onward := airlines
+		if rule, _ := hubs.Rule(hub); len(rule.Carriers) > 0 {
+			onward = rule.Carriers
+		}
+		for _, carrier := range onward {
+			queries = append(queries, ApiQuery{
+				Airline:         carrier,
+				StartDate:       from,
+				EndDate:         to,
+				DaysOfOperation: "1234567",
+				TimeMode:        mode.Query(),
+				Origin:          hub,
+				Destination:     destination,
+			})
+		}
+	}
+	for _, q := range queries {
+		if err := q.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	return queries, nil
+}
+
+// Connection is a journey from the origin to the destination changing at a
+// hub, repeating on the dates of the period the inbound flight departs on.
+type Connection struct {
+	Hub     string `json:"hub"`
+	Inbound Flight `json:"inbound"`
+	Onward  Flight `json:"onward"`
+	// OnwardDateDiff is the number of days the onward flight departs after
+	// the departure date of the inbound flight
+	OnwardDateDiff int       `json:"onward_date_diff"`
+	StartDate      time.Time `json:"start_date"`
+	EndDate        time.Time `json:"end_date"`
+	Days           Weekdays  `json:"days"`
+	// ConnectionMinutes is the time at the hub, JourneyMinutes the time from
+	// the departure at the origin to the arrival at the destination
+	ConnectionMinutes int `json:"connection_minutes"`
+	JourneyMinutes    int `json:"journey_minutes"`
+}
+
+// Origin returns the airport the journey starts at.
+func (c Connection) Origin() string {
+	return c.Inbound.Origin
+}
+
+// Destination returns the airport the journey ends at.
+func (c Connection) Destination() string {
+	return c.Onward.Destination
+}
+
+// ArrivalDateDiff returns the number of days the journey ends after the
+// departure date of the inbound flight.
+func (c Connection) ArrivalDateDiff() int {
+	return c.OnwardDateDiff + arrivalDays(c.Onward)
+}
+
+// arrivalDays returns the number of days the flight arrives after its local
+// departure date.
+func arrivalDays(f Flight) int {
+	days := f.ArrivalDateDiff - f.DepartureDateDiff
+	if days == 0 && int(f.Arrival)-f.ArrivalVariation <= int(f.Departure)-f.DepartureVariation {
+		// Records without the day offset of an arrival after midnight
+		days = 1
+	}
+	return days
+}
+
+// departureDate returns the local date the operation departs on.
+func (op Operation) departureDate() time.Time {
+	return op.Date.AddDate(0, 0, op.Flight.DepartureDateDiff)
+}
+
+// departureUTC and arrivalUTC return the minutes since the Unix epoch the
+// operation departs and arrives at.
+func (op Operation) departureUTC() int {
+	return int(op.departureDate().Unix()/60) + int(op.Flight.Departure) - op.Flight.DepartureVariation
+}
+
+func (op Operation) arrivalUTC() int {
+	return int(op.departureDate().Unix()/60) + arrivalDays(op.Flight)*minutesPerDay + int(op.Flight.Arrival) - op.Flight.ArrivalVariation
+}
+
+type connectionKey struct {
+	inbound, onward Flight
+	onwardDateDiff  int
+}
+
+// BuildConnections finds the flights from the origin to a hub of the table
+// departing in the period and the onward flights from the hub to the
+// destination, of any carrier, leaving within the connection times of the
+// hub. Connections repeating on several dates are combined into periods
+// like the schedule records.
+func BuildConnections(records []ScheduleRecord, origin, destination string, hubs HubTable, from, to time.Time) ([]Connection, error) {
+	origin = strings.ToUpper(strings.TrimSpace(origin))
+	destination = strings.ToUpper(strings.TrimSpace(destination))
+	if origin == "" || destination == "" {
+		return nil, fmt.Errorf("origin and destination are required")
+	}
+	if origin == destination {
+		return nil, fmt.Errorf("origin and destination are both %s", origin)
+	}
+	if to.Before(from) {
+		return nil, fmt.Errorf("period ends before it starts")
+	}
+
+	var arrivals []Operation
+	onward := make(map[string][]Operation)
+	for _, op := range ExpandRecords(records) {
+		if _, ok := hubs.Rule(op.Flight.Destination); ok && op.Flight.Origin == origin {
+			if date := op.departureDate(); !date.Before(from) && !date.After(to) {
+				arrivals = append(arrivals, op)
+			}
+		}
+		if _, ok := hubs.Rule(op.Flight.Origin); ok && op.Flight.Destination == destination {
+			onward[op.Flight.Origin] = append(onward[op.Flight.Origin], op)
+		}
+	}
+	for _, ops := range onward {
+		slices.SortStableFunc(ops, func(a, b Operation) int { return cmp.Compare(a.departureUTC(), b.departureUTC()) })
+	}
+
+	var keys []connectionKey
+	datesByKey := make(map[connectionKey][]time.Time)
+	for _, in := range arrivals {
+		hub := in.Flight.Destination
+		rule, _ := hubs.Rule(hub)
+		arrival := in.arrivalUTC()
+		departures := onward[hub]
+		first := sort.Search(len(departures), func(i int) bool {
+			return departures[i].departureUTC() >= arrival+rule.MinConnection
+		})
+		for _, out := range departures[first:] {
+			if out.departureUTC() > arrival+rule.MaxConnection {
+				break
+			}
+			date := in.departureDate()
+			key := connectionKey{in.Flight, out.Flight, int(out.departureDate().Sub(date).Hours() / 24)}
+			if _, ok := datesByKey[key]; !ok {
+				keys = append(keys, key)
+			}
+			datesByKey[key] = append(datesByKey[key], date)
+		}
+	}
+
+	var connections []Connection
+	for _, key := range keys {
+		dates := datesByKey[key]
+		slices.SortFunc(dates, time.Time.Compare)
+		// Times of the first date, the same on all of them
+		in := Operation{Flight: key.inbound, Date: dates[0].AddDate(0, 0, -key.inbound.DepartureDateDiff)}
+		out := Operation{Flight: key.onward, Date: dates[0].AddDate(0, 0, key.onwardDateDiff-key.onward.DepartureDateDiff)}
+		for _, period := range periodsOf(dates) {
+			connections = append(connections, Connection{
+				Hub:               key.inbound.Destination,
+				Inbound:           key.inbound,
+				Onward:            key.onward,
+				OnwardDateDiff:    key.onwardDateDiff,
+				StartDate:         period.StartDate,
+				EndDate:           period.EndDate,
+				Days:              period.Days,
+				ConnectionMinutes: out.departureUTC() - in.arrivalUTC(),
+				JourneyMinutes:    out.arrivalUTC() - in.departureUTC(),
+			})
+		}
+	}
+	slices.SortStableFunc(connections, func(a, b Connection) int {
+		return cmp.Or(
+			a.StartDate.Compare(b.StartDate),
+			cmp.Compare(a.Inbound.Departure, b.Inbound.Departure),
+			cmp.Compare(a.JourneyMinutes, b.JourneyMinutes),
+			cmp.Compare(a.Days, b.Days),
+		)
+	})
+	return connections, nil
+}
+
+// connectionColumns are the columns of the connection report.
+var connectionColumns = []string{
+	"origin", "hub", "destination", "inbound", "departure", "hub_arrival", "onward", "hub_departure", "arrival",
+	"connection_time", "journey_time", "start_date", "end_date", "days",
+}
+
+// dayTime returns the time followed by the number of days after the
+// departure date it falls on, if any.
+func dayTime(t TimeOfDay, days int) string {
+	if days == 0 {
+		return t.String()
+	}
+	return fmt.Sprintf("%s%+d", t, days)
+}
+
+// ConnectionRows renders the connection report with a header row in the
+// locale. Times later than the departure date carry the days, such as +1.
+func ConnectionRows(connections []Connection, l Locale, dateLayout string) [][]string {
+	header := make([]string, 0, len(connectionColumns))
+	for _, c := range connectionColumns {
+		header = append(header, T(l, "connection."+c))
+	}
+	rows := [][]string{header}
+	for _, c := range connections {
+		rows = append(rows, []string{
+			c.Origin(), c.Hub, c.Destination(),
+			flightDesignator(c.Inbound),
+			c.Inbound.Departure.String(),
+			dayTime(c.Inbound.Arrival, arrivalDays(c.Inbound)),
+			flightDesignator(c.Onward),
+			dayTime(c.Onward.Departure, c.OnwardDateDiff),
+			dayTime(c.Onward.Arrival, c.ArrivalDateDiff()),
+			strconv.Itoa(c.ConnectionMinutes),
+			NumberToTime(int64(c.JourneyMinutes)),
+			c.StartDate.Format(dateLayout),
+			c.EndDate.Format(dateLayout),
+			c.Days.String(),
+		})
+	}
+	return rows
+}
+
+// WriteConnectionsCSV writes the connection report using the locale and format of opts.
+func WriteConnectionsCSV(writer io.Writer, connections []Connection, opts ExportOptions) error {
+	format := opts.format()
+	return writeCSVRows(writer, ConnectionRows(connections, opts.Locale, format.DateLayout), format.Delimiter)
+}
+
+// WriteConnectionsXLSX writes the connection report as a spreadsheet.
+func WriteConnectionsXLSX(writer io.Writer, connections []Connection, opts ExportOptions) error {
+	return WriteXLSX(writer, T(opts.Locale, "connection.sheet"), ConnectionRows(connections, opts.Locale, opts.format().DateLayout))
+}
@@ -0,0 +1,135 @@
+package internal
+
+import (
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHubTable(t *testing.T) {
+	table, err := ReadHubTable(strings.NewReader("hub,min_connection,max_connection,carriers\nfra,45,360,lh lx\nVIE, 25 ,300,\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rule, ok := table.Rule("FRA"); !ok || !reflect.DeepEqual(rule, HubRule{MinConnection: 45, MaxConnection: 360, Carriers: []string{"LH", "LX"}}) {
+		t.Errorf("unexpected rule for FRA %+v", rule)
+	}
+	if !slices.Equal(table.Hubs(), []string{"FRA", "VIE"}) {
+		t.Errorf("unexpected hubs %v", table.Hubs())
+	}
+
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{name: "Invalid airport", input: "hub,min_connection,max_connection,carriers\nEDDF,45,360,\n"},
+		{name: "Invalid minutes", input: "hub,min_connection,max_connection,carriers\nFRA,soon,360,\n"},
+		{name: "Maximum below minimum", input: "hub,min_connection,max_connection,carriers\nFRA,90,60,\n"},
+		{name: "Invalid carrier", input: "hub,min_connection,max_connection,carriers\nFRA,45,360,DLH\n"},
+		{name: "Missing header", input: "FRA,45,360,\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ReadHubTable(strings.NewReader(tt.input)); err == nil {
+				t.Errorf("Test %s failed: expected error", tt.name)
+			}
+		})
+	}
+
+	if _, ok := DefaultHubTable().Rule("MUC"); !ok {
+		t.Errorf("expected MUC in the default hubs")
+	}
+}
+
+func TestConnectionQueries(t *testing.T) {
+	period, _ := ParseSeason("S25")
+	queries, err := ConnectionQueries([]string{"LH", "en"}, DefaultHubTable(), "KRK", "jfk", period, TimeModeLT)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var got []string
+	for _, q := range queries {
+		got = append(got, q.Airline+" "+q.Origin+"-"+q.Destination)
+	}
+	// Every carrier flies onward from every hub reached
+	expected := []string{"LH KRK-FRA", "LH KRK-MUC", "EN KRK-MUC", "LH FRA-JFK", "EN FRA-JFK", "LH MUC-JFK", "EN MUC-JFK"}
+	if !slices.Equal(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+
+	// Another carrier of the hub takes over from the inbound one
+	hubs, _ := ReadHubTable(strings.NewReader("hub,min_connection,max_connection,carriers\nZRH,40,360,LX LH\n"))
+	queries, err = ConnectionQueries([]string{"LX"}, hubs, "KRK", "JFK", period, TimeModeLT)
+	got = nil
+	for _, q := range queries {
+		got = append(got, q.Airline+" "+q.Origin+"-"+q.Destination)
+	}
+	expected = []string{"LX KRK-ZRH", "LX ZRH-JFK", "LH ZRH-JFK"}
+	if err != nil || !slices.Equal(got, expected) {
+		t.Errorf("expected %v, got %v (%v)", expected, got, err)
+	}
+
+	// A hub as the destination is reached directly, not through itself
+	queries, err = ConnectionQueries([]string{"LH"}, DefaultHubTable(), "KRK", "MUC", period, TimeModeLT)
+	if err != nil || len(queries) != 2 || queries[1].Origin != "FRA" {
+		t.Errorf("unexpected queries to a hub %+v: %v", queries, err)
+	}
+
+	if _, err := ConnectionQueries([]string{"XX"}, DefaultHubTable(), "KRK", "JFK", period, TimeModeLT); err == nil {
+		t.Errorf("expected error for an unknown carrier")
+	}
+	if _, err := ConnectionQueries([]string{"LH"}, DefaultHubTable(), "WAW", "JFK", period, TimeModeLT); err == nil {
+		t.Errorf("expected error without routes from the origin")
+	}
+}
+
+func TestBuildConnections(t *testing.T) {
+	date := func(s string) time.Time {
+		d, _ := time.Parse(dateLayout, s)
+		return d
+	}
+	record := func(r ScheduleRecord, departureVariation, arrivalVariation int) ScheduleRecord {
+		r.DepartureVariation, r.ArrivalVariation = departureVariation, arrivalVariation
+		return r
+	}
+	records := []ScheduleRecord{
+		record(testRecord("KRK", "FRA", "LH", "1365", "10:20", "12:05", "2025-03-31", "2025-04-13", "1234567", "32N", "LH", "J"), 120, 120),
+		record(testRecord("KRK", "FRA", "LH", "1367", "20:00", "21:45", "2025-04-01", "2025-04-01", ".2.....", "32N", "LH", "J"), 120, 120),
+		record(testRecord("KRK", "MUC", "EN", "8850", "06:00", "07:10", "2025-03-31", "2025-04-13", "1.3.5..", "E95", "EN", "J"), 120, 120),
+		record(testRecord("FRA", "JFK", "LH", "400", "13:10", "15:40", "2025-03-31", "2025-04-14", "1234567", "388", "LH", "J"), 120, -240),
+		// Leaves too soon after LH1365
+		record(testRecord("FRA", "JFK", "LH", "402", "12:30", "15:00", "2025-03-31", "2025-04-14", "1234567", "346", "LH", "J"), 120, -240),
+		record(testRecord("MUC", "JFK", "LH", "410", "08:00", "10:40", "2025-03-31", "2025-04-13", "1234567", "359", "LH", "J"), 120, -240),
+		// Leaves too late after EN8850
+		record(testRecord("MUC", "JFK", "LH", "412", "15:30", "18:10", "2025-03-31", "2025-04-13", "1234567", "359", "LH", "J"), 120, -240),
+	}
+	hubs, err := ReadHubTable(strings.NewReader("hub,min_connection,max_connection,carriers\nFRA,45,960,\nMUC,30,360,\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	connections, err := BuildConnections(records, "krk", "JFK", hubs, date("2025-03-31"), date("2025-04-13"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var got []string
+	for _, r := range ConnectionRows(connections, LocaleEN, dateLayout)[1:] {
+		got = append(got, strings.Join(r, " "))
+	}
+	expected := []string{
+		"KRK MUC JFK EN8850 06:00 07:10 LH410 08:00 10:40 50 10:40 2025-03-31 2025-04-11 1.3.5..",
+		"KRK FRA JFK LH1365 10:20 12:05 LH400 13:10 15:40 65 11:20 2025-03-31 2025-04-13 1234567",
+		// Overnight at the hub within its 16 hours
+		"KRK FRA JFK LH1367 20:00 21:45 LH402 12:30+1 15:00+1 885 25:00 2025-04-01 2025-04-01 .2.....",
+		"KRK FRA JFK LH1367 20:00 21:45 LH400 13:10+1 15:40+1 925 25:40 2025-04-01 2025-04-01 .2.....",
+	}
+	if !slices.Equal(got, expected) {
+		t.Errorf("expected\n%q\ngot\n%q", expected, got)
+	}
+
+	if _, err := BuildConnections(records, "KRK", "", hubs, date("2025-03-31"), date("2025-04-13")); err == nil {
+		t.Errorf("expected error without destination")
+	}
+}
@@ -0,0 +1,53 @@
+package internal
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCronNext(t *testing.T) {
+	start := time.Date(2025, 3, 28, 10, 17, 30, 0, time.UTC) // Friday
+	tests := []struct {
+		expr     string
+		expected string
+	}{
+		{expr: "* * * * *", expected: "2025-03-28 10:18"},
+		{expr: "*/15 * * * *", expected: "2025-03-28 10:30"},
+		{expr: "30 6 * * *", expected: "2025-03-29 06:30"},
+		{expr: "0 6 * * 1-5", expected: "2025-03-31 06:00"},
+		{expr: "0 0 * * 7", expected: "2025-03-30 00:00"},
+		{expr: "0 12 1 * *", expected: "2025-04-01 12:00"},
+		{expr: "0 8,20 * * *", expected: "2025-03-28 20:00"},
+		{expr: "5/20 10 * * *", expected: "2025-03-28 10:25"},
+		// Either day field matches when both are restricted
+		{expr: "0 0 15 * 1", expected: "2025-03-31 00:00"},
+		{expr: "@daily", expected: "2025-03-29 00:00"},
+		{expr: "@monthly", expected: "2025-04-01 00:00"},
+		{expr: "0 0 29 2 *", expected: "2028-02-29 00:00"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			c, err := ParseCron(tt.expr)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := c.Next(start).Format("2006-01-02 15:04"); got != tt.expected {
+				t.Errorf("expected %s, got %s", tt.expected, got)
+			}
+		})
+	}
+
+	never, _ := ParseCron("0 0 30 2 *")
+	if next := never.Next(start); !next.IsZero() {
+		t.Errorf("expected no run on 30 February, got %v", next)
+	}
+}
+
+func TestParseCronErrors(t *testing.T) {
+	for _, expr := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "* * * 13 *", "* * * * 8", "*/0 * * * *", "5-1 * * * *", "a * * * *"} {
+		if _, err := ParseCron(expr); err == nil {
+			t.Errorf("expected error for %q", expr)
+		}
+	}
+}
@@ -4,8 +4,8 @@ package internal
 import (
 	"encoding/csv"
 	"encoding/json"
-	"fmt"
 	"io"
+	"slices"
 )
 
 // ERROR MESSAGES
@@ -81,70 +81,194 @@ func (f FlightResponse) isApiResponse() {}
 
 // Process Response
 
-// CreateCSVFromResponse can now write to either a file or http.ResponseWriter
-func CreateCSVFromResponse(writer io.Writer, jsonData []byte, separate bool) error {
-	var flightResponses []FlightResponse
-	err := json.Unmarshal(jsonData, &flightResponses)
-	if err != nil {
-		return err
-	}
-
-	// Create a CSV writer using the provided writer
-	csvWriter := csv.NewWriter(writer)
-	defer csvWriter.Flush()
+// ExportOptions controls the shape of the generated CSV.
+type ExportOptions struct {
+	Separate bool
+	// Exceptions lists cancelled dates in an extra column instead of splitting records.
+	Exceptions bool
+	// TimeMode picks local or UTC periods and times, local when empty.
+	TimeMode TimeMode
+	// DayBasis picks whether periods and days follow departure or arrival,
+	// departure when empty.
+	DayBasis DayBasis
+	// MergeDST joins local periods split at a daylight saving time switch and
+	// adds the columns flagging local time changes. Ignored unless times are local.
+	MergeDST bool
+	// Template selects and orders the written columns, DefaultTemplate when empty.
+	Template ExportTemplate
+	// Locale of the headers, it also provides the default delimiter and date layout.
+	Locale     Locale
+	Delimiter  rune
+	DateLayout string
+	// Warnings of the validation of the records, spreadsheets list them on
+	// an extra sheet.
+	Warnings []Warning
+}
 
-	// Write CSV header
-	header := []string{"Z", "Do", "Linia", "Numer", "Odlot", "Przylot", "Od", "Do", "Dni", "Samolot", "Operator", "Typ"}
-	err = csvWriter.Write(header)
-	if err != nil {
-		return err
+func (o ExportOptions) format() LocaleFormat {
+	f := o.Locale.Format()
+	if o.Delimiter != 0 {
+		f.Delimiter = o.Delimiter
+	}
+	if o.DateLayout != "" {
+		f.DateLayout = o.DateLayout
 	}
+	return f
+}
 
-	var csvData [][]string
+// RecordsFromResponse decodes the flattened API response into schedule records.
+func RecordsFromResponse(jsonData []byte, mode TimeMode) ([]ScheduleRecord, error) {
+	var flightResponses []FlightResponse
+	if err := json.Unmarshal(jsonData, &flightResponses); err != nil {
+		return nil, err
+	}
 
-	// Write data to CSV with merging
+	var records []ScheduleRecord
 	for _, d := range flightResponses {
-		csvData = append(csvData, convertFlightResponseToCSVRows(d)...)
-
-		// Sort Records by Od Field.
-		// mergedData, err := MergeRecords(csvData)
-		// if err != nil {
-		// 	return err
-		// }
-	}
-	fmt.Printf(" CSVDATA: %v", csvData)
-	var separatedData [][]string
-	if separate {
-		for _, d := range csvData {
-			rows := SeparateDays(d)
-			separatedData = append(separatedData, rows...)
+		converted, err := convertFlightResponseToRecords(d, mode)
+		if err != nil {
+			return nil, err
 		}
+		records = append(records, converted...)
 	}
+	return records, nil
+}
 
-	SortRecordsByDateCol(separatedData, 6)
-	SortRecordsByDateCol(csvData, 6)
-	fmt.Printf("LEN: %v ", len(separatedData))
-	if separate {
-		separatedData, err = MergeRecords(separatedData)
-		if err != nil {
-			return err
-		}
-		for _, row := range separatedData {
-			if err := csvWriter.Write(row); err != nil {
-				return err
+// NormalizeRecords rebuilds records from the dated operations they describe.
+func NormalizeRecords(records []ScheduleRecord, opts NormalizeOptions) []ScheduleRecord {
+	return CompressOperations(ExpandRecords(records), opts)
+}
+
+func (o ExportOptions) normalizeOptions() NormalizeOptions {
+	return NormalizeOptions{
+		Separate:   o.Separate,
+		Exceptions: o.Exceptions,
+		MergeDST:   o.MergeDST && o.TimeMode.Basis() == TimeModeLT && o.TimeMode != TimeModeBoth,
+	}
+}
+
+// utcColumns maps the local time columns to their UTC counterparts.
+var utcColumns = map[string]string{
+	"departure": "departure_utc",
+	"arrival":   "arrival_utc",
+}
+
+// ExportTemplate returns the selected template adjusted to the options.
+// Local time columns show UTC in UTC mode and are followed by UTC in both
+// mode, the DST shift and exceptions columns are appended when requested but
+// the template does not show them.
+func (o ExportOptions) ExportTemplate() ExportTemplate {
+	template := o.Template
+	if len(template.Columns) == 0 {
+		template = DefaultTemplate
+	}
+	if o.TimeMode == TimeModeUTC || o.TimeMode == TimeModeBoth {
+		var columns []string
+		for _, key := range template.Columns {
+			utc, ok := utcColumns[key]
+			if ok && o.TimeMode == TimeModeBoth {
+				columns = append(columns, key)
 			}
+			if ok && !slices.Contains(template.Columns, utc) {
+				key = utc
+			} else if ok {
+				continue
+			}
+			columns = append(columns, key)
 		}
-	} else {
-		csvData, err = MergeRecords(csvData)
-		if err != nil {
-			return err
-		}
-		for _, row := range csvData {
-			if err := csvWriter.Write(row); err != nil {
-				return err
+		template.Columns = columns
+	}
+	if o.MergeDST {
+		for _, key := range []string{"dst_shift", "arrival_dst_shift"} {
+			if !slices.Contains(template.Columns, key) {
+				template.Columns = append(slices.Clone(template.Columns), key)
 			}
 		}
 	}
+	if o.Exceptions && !slices.Contains(template.Columns, "exceptions") {
+		template.Columns = append(slices.Clone(template.Columns), "exceptions")
+	}
+	return template
+}
+
+// RecordRows renders records with a header row using the template, locale
+// and date layout of opts.
+func RecordRows(records []ScheduleRecord, opts ExportOptions) ([][]string, error) {
+	template := opts.ExportTemplate()
+	if err := template.Validate(); err != nil {
+		return nil, err
+	}
+
+	dateLayout := opts.format().DateLayout
+	rows := [][]string{template.Header(opts.Locale)}
+	for _, r := range records {
+		rows = append(rows, template.Render(r, dateLayout))
+	}
+	return rows, nil
+}
+
+// WriteCSV renders records with the template, locale and format of opts.
+func WriteCSV(writer io.Writer, records []ScheduleRecord, opts ExportOptions) error {
+	rows, err := RecordRows(records, opts)
+	if err != nil {
+		return err
+	}
+	return writeCSVRows(writer, rows, opts.format().Delimiter)
+}
+
+// WriteRecordsXLSX renders records like WriteCSV into a spreadsheet, with
+// the warnings of opts on a second sheet when there are any.
+func WriteRecordsXLSX(writer io.Writer, records []ScheduleRecord, opts ExportOptions) error {
+	rows, err := RecordRows(records, opts)
+	if err != nil {
+		return err
+	}
+	sheets := []Sheet{{Name: T(opts.Locale, "export.sheet"), Rows: rows}}
+	if len(opts.Warnings) > 0 {
+		sheets = append(sheets, Sheet{Name: T(opts.Locale, "warning.sheet"), Rows: WarningRows(opts.Warnings, opts.Locale, opts.format().DateLayout)})
+	}
+	return WriteWorkbook(writer, sheets)
+}
+
+func writeCSVRows(writer io.Writer, rows [][]string, delimiter rune) error {
+	csvWriter := csv.NewWriter(writer)
+	csvWriter.Comma = delimiter
+	if err := csvWriter.WriteAll(rows); err != nil {
+		return err
+	}
+	return csvWriter.Error()
+}
+
+// PrepareRecords decodes the flattened API response and normalizes the
+// records as requested by opts.
+func PrepareRecords(jsonData []byte, opts ExportOptions) ([]ScheduleRecord, error) {
+	records, _, err := PrepareValidatedRecords(jsonData, opts, nil)
+	return records, err
+}
+
+// PrepareValidatedRecords is PrepareRecords checking the records with the
+// rules, raw rules on the records as decoded and the others on the
+// normalized records.
+func PrepareValidatedRecords(jsonData []byte, opts ExportOptions, rules []Rule) ([]ScheduleRecord, []Warning, error) {
+	decoded, err := RecordsFromResponse(jsonData, opts.TimeMode)
+	if err != nil {
+		return nil, nil, err
+	}
+	if opts.DayBasis == DayBasisArrival {
+		for i, r := range decoded {
+			decoded[i] = r.ArrivalBased(opts.TimeMode)
+		}
+	}
+	records := NormalizeRecords(decoded, opts.normalizeOptions())
+	return records, validate(decoded, records, rules), nil
+}
 
-	return nil
+// CreateCSVFromResponse can now write to either a file or http.ResponseWriter,
+// it returns the warnings the rules raise for the written records.
+func CreateCSVFromResponse(writer io.Writer, jsonData []byte, opts ExportOptions, rules []Rule) ([]Warning, error) {
+	records, warnings, err := PrepareValidatedRecords(jsonData, opts, rules)
+	if err != nil {
+		return nil, err
+	}
+	return warnings, WriteCSV(writer, records, opts)
 }
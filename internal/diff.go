@@ -0,0 +1,278 @@
+package internal
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// ChangeKind classifies a difference between two schedules.
+type ChangeKind string
+
+const (
+	ChangeAdded           ChangeKind = "added"
+	ChangeRemoved         ChangeKind = "removed"
+	ChangeRetimed         ChangeKind = "retimed"
+	ChangeAircraft        ChangeKind = "aircraft_changed"
+	ChangeOperator        ChangeKind = "operator_changed"
+	ChangeDays            ChangeKind = "days_changed"
+	ChangePeriodExtended  ChangeKind = "period_extended"
+	ChangePeriodShortened ChangeKind = "period_shortened"
+)
+
+// Route identifies a flight number on a route regardless of its schedule.
+type Route struct {
+	Airline      string `json:"airline"`
+	FlightNumber int    `json:"flight_number"`
+	Suffix       string `json:"suffix,omitempty"`
+	Origin       string `json:"origin"`
+	Destination  string `json:"destination"`
+}
+
+func routeOf(f Flight) Route {
+	return Route{Airline: f.Airline, FlightNumber: f.FlightNumber, Suffix: f.Suffix, Origin: f.Origin, Destination: f.Destination}
+}
+
+// Change is one difference of a flight on a weekday, Weekday is 0 for
+// changes of the whole flight such as its days of operation.
+type Change struct {
+	Kind ChangeKind `json:"kind"`
+	Route
+	Weekday   int       `json:"weekday,omitempty"`
+	StartDate time.Time `json:"start_date"`
+	EndDate   time.Time `json:"end_date"`
+	Old       string    `json:"old,omitempty"`
+	New       string    `json:"new,omitempty"`
+}
+
+// dayKey is a flight on a weekday, the unit schedules are compared by.
+type dayKey struct {
+	Route
+	Weekday int
+}
+
+// operationsByDay indexes the operations of the records by flight and weekday.
+func operationsByDay(records []ScheduleRecord) map[dayKey]map[time.Time]Flight {
+	index := make(map[dayKey]map[time.Time]Flight)
+	for _, op := range ExpandRecords(records) {
+		key := dayKey{Route: routeOf(op.Flight), Weekday: WeekdayOf(op.Date)}
+		if index[key] == nil {
+			index[key] = make(map[time.Time]Flight)
+		}
+		index[key][op.Date] = op.Flight
+	}
+	return index
+}
+
+// DiffRecords compares an old and a new schedule and lists the changes, per
+// flight and weekday, with runs of consecutive weeks reported as one change.
+func DiffRecords(old, new []ScheduleRecord) []Change {
+	oldOps, newOps := operationsByDay(old), operationsByDay(new)
+
+	oldDays, newDays := make(map[Route]Weekdays), make(map[Route]Weekdays)
+	var keys []dayKey
+	for key := range oldOps {
+		oldDays[key.Route] = oldDays[key.Route].Add(key.Weekday)
+		keys = append(keys, key)
+	}
+	for key := range newOps {
+		newDays[key.Route] = newDays[key.Route].Add(key.Weekday)
+		if _, ok := oldOps[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+
+	var changes []Change
+	for route, days := range newDays {
+		if was, ok := oldDays[route]; ok && was != days {
+			start, end := spanOf(newOps, route)
+			changes = append(changes, Change{Kind: ChangeDays, Route: route, StartDate: start, EndDate: end, Old: was.String(), New: days.String()})
+		}
+	}
+
+	for _, key := range keys {
+		before, after := oldOps[key], newOps[key]
+		_, routeBefore := oldDays[key.Route]
+		_, routeAfter := newDays[key.Route]
+		switch {
+		case len(before) == 0:
+			// Weekdays added to an existing flight are reported as a days change
+			if !routeBefore {
+				changes = append(changes, dayChanges(key, ChangeAdded, sortedDates(after), nil)...)
+			}
+		case len(after) == 0:
+			if !routeAfter {
+				changes = append(changes, dayChanges(key, ChangeRemoved, sortedDates(before), nil)...)
+			}
+		default:
+			changes = append(changes, diffDay(key, before, after)...)
+		}
+	}
+
+	slices.SortFunc(changes, func(a, b Change) int {
+		return cmp.Or(
+			cmp.Compare(a.Airline, b.Airline),
+			cmp.Compare(a.FlightNumber, b.FlightNumber),
+			cmp.Compare(a.Suffix, b.Suffix),
+			cmp.Compare(a.Origin, b.Origin),
+			cmp.Compare(a.Destination, b.Destination),
+			cmp.Compare(a.Weekday, b.Weekday),
+			a.StartDate.Compare(b.StartDate),
+			cmp.Compare(a.Kind, b.Kind),
+		)
+	})
+	return changes
+}
+
+// diffDay compares the operations of a flight on a weekday present in both schedules.
+func diffDay(key dayKey, before, after map[time.Time]Flight) []Change {
+	oldDates, newDates := sortedDates(before), sortedDates(after)
+	oldFirst, oldLast := oldDates[0], oldDates[len(oldDates)-1]
+	newFirst, newLast := newDates[0], newDates[len(newDates)-1]
+
+	var changes []Change
+	oldPeriod := oldFirst.Format(dateLayout) + " - " + oldLast.Format(dateLayout)
+	newPeriod := newFirst.Format(dateLayout) + " - " + newLast.Format(dateLayout)
+	if newFirst.Before(oldFirst) || newLast.After(oldLast) {
+		changes = append(changes, Change{Kind: ChangePeriodExtended, Route: key.Route, Weekday: key.Weekday, StartDate: newFirst, EndDate: newLast, Old: oldPeriod, New: newPeriod})
+	}
+	if newFirst.After(oldFirst) || newLast.Before(oldLast) {
+		changes = append(changes, Change{Kind: ChangePeriodShortened, Route: key.Route, Weekday: key.Weekday, StartDate: newFirst, EndDate: newLast, Old: oldPeriod, New: newPeriod})
+	}
+
+	// Operations added or cancelled within the period both schedules cover
+	var added, removed []time.Time
+	for _, date := range newDates {
+		if _, ok := before[date]; !ok && !date.Before(oldFirst) && !date.After(oldLast) {
+			added = append(added, date)
+		}
+	}
+	for _, date := range oldDates {
+		if _, ok := after[date]; !ok && !date.Before(newFirst) && !date.After(newLast) {
+			removed = append(removed, date)
+		}
+	}
+	changes = append(changes, dayChanges(key, ChangeAdded, added, nil)...)
+	changes = append(changes, dayChanges(key, ChangeRemoved, removed, nil)...)
+
+	var common []time.Time
+	for _, date := range newDates {
+		if _, ok := before[date]; ok {
+			common = append(common, date)
+		}
+	}
+	attributes := []struct {
+		kind  ChangeKind
+		value func(f Flight) string
+	}{
+		{ChangeRetimed, func(f Flight) string {
+			return f.Departure.String() + "-" + f.Arrival.WithDayOffset(f.ArrivalDateDiff-f.DepartureDateDiff)
+		}},
+		{ChangeAircraft, func(f Flight) string { return f.AircraftType }},
+		{ChangeOperator, func(f Flight) string { return f.AircraftOwner }},
+	}
+	for _, attr := range attributes {
+		changes = append(changes, dayChanges(key, attr.kind, common, func(date time.Time) (string, string, bool) {
+			o, n := attr.value(before[date]), attr.value(after[date])
+			return o, n, o != n
+		})...)
+	}
+	return changes
+}
+
+// dayChanges groups dates into changes covering runs of consecutive weeks.
+// Without compare every date is a change, otherwise compare gives the old and
+// new values of a date and whether they differ.
+func dayChanges(key dayKey, kind ChangeKind, dates []time.Time, compare func(time.Time) (string, string, bool)) []Change {
+	var changes []Change
+	for _, date := range dates {
+		var o, n string
+		if compare != nil {
+			var changed bool
+			if o, n, changed = compare(date); !changed {
+				continue
+			}
+		}
+		if len(changes) > 0 {
+			last := &changes[len(changes)-1]
+			if last.EndDate.AddDate(0, 0, 7).Equal(date) && last.Old == o && last.New == n {
+				last.EndDate = date
+				continue
+			}
+		}
+		changes = append(changes, Change{Kind: kind, Route: key.Route, Weekday: key.Weekday, StartDate: date, EndDate: date, Old: o, New: n})
+	}
+	return changes
+}
+
+func sortedDates(ops map[time.Time]Flight) []time.Time {
+	dates := make([]time.Time, 0, len(ops))
+	for date := range ops {
+		dates = append(dates, date)
+	}
+	slices.SortFunc(dates, func(a, b time.Time) int { return a.Compare(b) })
+	return dates
+}
+
+// spanOf returns the first and last operation of the route over all weekdays.
+func spanOf(ops map[dayKey]map[time.Time]Flight, route Route) (time.Time, time.Time) {
+	var first, last time.Time
+	for key, dates := range ops {
+		if key.Route != route {
+			continue
+		}
+		for date := range dates {
+			if first.IsZero() || date.Before(first) {
+				first = date
+			}
+			if date.After(last) {
+				last = date
+			}
+		}
+	}
+	return first, last
+}
+
+// changeColumns are the columns of the changes report.
+var changeColumns = []string{"kind", "flight", "origin", "destination", "weekday", "start_date", "end_date", "old", "new"}
+
+// ChangeRows renders the changes report with a header row in the locale.
+func ChangeRows(changes []Change, l Locale, dateLayout string) [][]string {
+	header := make([]string, 0, len(changeColumns))
+	for _, c := range changeColumns {
+		header = append(header, T(l, "diff."+c))
+	}
+	rows := [][]string{header}
+	for _, c := range changes {
+		weekday := ""
+		if c.Weekday != 0 {
+			weekday = strconv.Itoa(c.Weekday)
+		}
+		rows = append(rows, []string{
+			T(l, "diff.kind."+string(c.Kind)),
+			fmt.Sprintf("%s%d%s", c.Airline, c.FlightNumber, c.Suffix),
+			c.Origin,
+			c.Destination,
+			weekday,
+			c.StartDate.Format(dateLayout),
+			c.EndDate.Format(dateLayout),
+			c.Old,
+			c.New,
+		})
+	}
+	return rows
+}
+
+// WriteChangesCSV writes the changes report using the locale and format of opts.
+func WriteChangesCSV(writer io.Writer, changes []Change, opts ExportOptions) error {
+	format := opts.format()
+	return writeCSVRows(writer, ChangeRows(changes, opts.Locale, format.DateLayout), format.Delimiter)
+}
+
+// WriteChangesXLSX writes the changes report as a spreadsheet.
+func WriteChangesXLSX(writer io.Writer, changes []Change, opts ExportOptions) error {
+	return WriteXLSX(writer, T(opts.Locale, "diff.sheet"), ChangeRows(changes, opts.Locale, opts.format().DateLayout))
+}
@@ -0,0 +1,99 @@
+package internal
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDiffRecords(t *testing.T) {
+	old := []ScheduleRecord{
+		testRecord("KRK", "FRA", "LH", "1365", "10:20", "12:05", "2025-03-30", "2025-04-26", "1234567", "32N", "LH", "J"),
+		testRecord("KRK", "MUC", "LH", "1367", "07:00", "08:10", "2025-03-31", "2025-04-21", "1......", "E95", "LH", "J"),
+		testRecord("KRK", "FRA", "LH", "1369", "18:00", "19:45", "2025-04-01", "2025-04-22", ".2.....", "32N", "LH", "J"),
+	}
+	new := []ScheduleRecord{
+		// Weekends dropped, Wednesdays extended
+		testRecord("KRK", "FRA", "LH", "1365", "10:20", "12:05", "2025-03-30", "2025-04-26", "..345..", "32N", "LH", "J"),
+		testRecord("KRK", "FRA", "LH", "1365", "10:20", "12:05", "2025-04-30", "2025-05-07", "..3....", "32N", "LH", "J"),
+		// Mondays retimed from 14 April
+		testRecord("KRK", "FRA", "LH", "1365", "10:20", "12:05", "2025-03-31", "2025-04-07", "1......", "32N", "LH", "J"),
+		testRecord("KRK", "FRA", "LH", "1365", "10:30", "12:15", "2025-04-14", "2025-04-21", "1......", "32N", "LH", "J"),
+		// Equipment swap on a single Tuesday
+		testRecord("KRK", "FRA", "LH", "1365", "10:20", "12:05", "2025-04-01", "2025-04-01", ".2.....", "32N", "LH", "J"),
+		testRecord("KRK", "FRA", "LH", "1365", "10:20", "12:05", "2025-04-08", "2025-04-08", ".2.....", "320", "LH", "J"),
+		testRecord("KRK", "FRA", "LH", "1365", "10:20", "12:05", "2025-04-15", "2025-04-22", ".2.....", "32N", "LH", "J"),
+		// Starts a week later, last flight operated by CityLine
+		testRecord("KRK", "MUC", "LH", "1367", "07:00", "08:10", "2025-04-07", "2025-04-14", "1......", "E95", "LH", "J"),
+		testRecord("KRK", "MUC", "LH", "1367", "07:00", "08:10", "2025-04-21", "2025-04-21", "1......", "E95", "CL", "J"),
+		testRecord("KRK", "VIE", "LH", "1371", "12:00", "13:10", "2025-04-04", "2025-04-25", "....5..", "E95", "LH", "J"),
+	}
+
+	date := func(s string) time.Time {
+		d, _ := time.Parse(dateLayout, s)
+		return d
+	}
+	route := func(number int, destination string) Route {
+		return Route{Airline: "LH", FlightNumber: number, Origin: "KRK", Destination: destination}
+	}
+	expected := []Change{
+		{Kind: ChangeDays, Route: route(1365, "FRA"), StartDate: date("2025-03-31"), EndDate: date("2025-05-07"), Old: "1234567", New: "12345.."},
+		{Kind: ChangeRetimed, Route: route(1365, "FRA"), Weekday: 1, StartDate: date("2025-04-14"), EndDate: date("2025-04-21"), Old: "10:20-12:05", New: "10:30-12:15"},
+		{Kind: ChangeAircraft, Route: route(1365, "FRA"), Weekday: 2, StartDate: date("2025-04-08"), EndDate: date("2025-04-08"), Old: "32N", New: "320"},
+		{Kind: ChangePeriodExtended, Route: route(1365, "FRA"), Weekday: 3, StartDate: date("2025-04-02"), EndDate: date("2025-05-07"), Old: "2025-04-02 - 2025-04-23", New: "2025-04-02 - 2025-05-07"},
+		{Kind: ChangePeriodShortened, Route: route(1367, "MUC"), Weekday: 1, StartDate: date("2025-04-07"), EndDate: date("2025-04-21"), Old: "2025-03-31 - 2025-04-21", New: "2025-04-07 - 2025-04-21"},
+		{Kind: ChangeOperator, Route: route(1367, "MUC"), Weekday: 1, StartDate: date("2025-04-21"), EndDate: date("2025-04-21"), Old: "LH", New: "CL"},
+		{Kind: ChangeRemoved, Route: route(1369, "FRA"), Weekday: 2, StartDate: date("2025-04-01"), EndDate: date("2025-04-22")},
+		{Kind: ChangeAdded, Route: route(1371, "VIE"), Weekday: 5, StartDate: date("2025-04-04"), EndDate: date("2025-04-25")},
+	}
+
+	changes := DiffRecords(old, new)
+	if !reflect.DeepEqual(changes, expected) {
+		t.Errorf("expected:\n%v\ngot:\n%v", expected, changes)
+	}
+	if changes := DiffRecords(old, old); len(changes) != 0 {
+		t.Errorf("expected no changes between equal schedules, got %v", changes)
+	}
+}
+
+func TestDiffRecordsCancellations(t *testing.T) {
+	old := []ScheduleRecord{
+		testRecord("KRK", "FRA", "LH", "1365", "10:20", "12:05", "2025-04-07", "2025-05-05", "1......", "32N", "LH", "J"),
+	}
+	new := []ScheduleRecord{
+		withExceptions(testRecord("KRK", "FRA", "LH", "1365", "10:20", "12:05", "2025-04-07", "2025-05-05", "1......", "32N", "LH", "J"), "2025-04-21", "2025-04-28"),
+	}
+
+	changes := DiffRecords(old, new)
+	if len(changes) != 1 || changes[0].Kind != ChangeRemoved || changes[0].StartDate.Format(dateLayout) != "2025-04-21" || changes[0].EndDate.Format(dateLayout) != "2025-04-28" {
+		t.Errorf("expected cancelled weeks as one removal, got %v", changes)
+	}
+	if changes := DiffRecords(new, old); len(changes) != 1 || changes[0].Kind != ChangeAdded {
+		t.Errorf("expected reinstated weeks as an addition, got %v", changes)
+	}
+}
+
+func TestWriteChangesCSV(t *testing.T) {
+	changes := []Change{
+		{Kind: ChangeAircraft, Route: Route{Airline: "LH", FlightNumber: 1365, Origin: "KRK", Destination: "FRA"}, Weekday: 2,
+			StartDate: time.Date(2025, 4, 8, 0, 0, 0, 0, time.UTC), EndDate: time.Date(2025, 4, 8, 0, 0, 0, 0, time.UTC), Old: "32N", New: "320"},
+	}
+	var buf bytes.Buffer
+	if err := WriteChangesCSV(&buf, changes, ExportOptions{Locale: LocaleEN}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := "Change,Flight,From,To,Day,Start date,End date,Old,New\nAircraft changed,LH1365,KRK,FRA,2,2025-04-08,2025-04-08,32N,320\n"
+	if got := buf.String(); got != expected {
+		t.Errorf("expected %q, got %q", expected, got)
+	}
+
+	buf.Reset()
+	if err := WriteChangesCSV(&buf, changes, ExportOptions{Locale: LocalePL}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := buf.String(); !strings.HasPrefix(got, "Zmiana;Lot;") || !strings.Contains(got, "Zmiana samolotu;LH1365;KRK;FRA;2;08.04.2025") {
+		t.Errorf("unexpected Polish report %q", got)
+	}
+}
@@ -0,0 +1,151 @@
+package internal
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// FetchStatus is the state of a background fetch.
+type FetchStatus string
+
+const (
+	FetchRunning FetchStatus = "running"
+	FetchDone    FetchStatus = "done"
+	FetchFailed  FetchStatus = "failed"
+)
+
+// FetchResult is what a background fetch produced.
+type FetchResult struct {
+	Records  []ScheduleRecord
+	Warnings []Warning
+	// Snapshot is the ID the records were stored under, 0 when not stored
+	Snapshot uint64
+}
+
+// FetchJob is a fetch run in the background because it takes longer than a
+// request may wait, with its result once done.
+type FetchJob struct {
+	ID         uint64      `json:"id"`
+	Status     FetchStatus `json:"status"`
+	StartedAt  time.Time   `json:"started_at"`
+	FinishedAt *time.Time  `json:"finished_at,omitempty"`
+	Snapshot   uint64      `json:"snapshot,omitempty"`
+	Error      string      `json:"error,omitempty"`
+	Result     FetchResult `json:"-"`
+
+	key  string
+	done chan struct{}
+}
+
+// FetchJobs runs fetches in the background, one per key at a time, and keeps
+// their results for a while so clients can poll for them.
+type FetchJobs struct {
+	mu     sync.Mutex
+	ctx    context.Context
+	nextID uint64
+	jobs   map[uint64]*FetchJob
+	byKey  map[string]*FetchJob
+	// retention is how long finished jobs are kept
+	retention time.Duration
+	now       func() time.Time
+}
+
+// NewFetchJobs returns the jobs running until ctx is done, keeping finished
+// ones for retention.
+func NewFetchJobs(ctx context.Context, retention time.Duration) *FetchJobs {
+	return &FetchJobs{
+		ctx:       ctx,
+		jobs:      make(map[uint64]*FetchJob),
+		byKey:     make(map[string]*FetchJob),
+		retention: retention,
+		now:       time.Now,
+	}
+}
+
+// Start returns the job of the key, starting run in the background unless a
+// job of the key is running or done within the retention. A failed job is
+// handed out once more so its error is reported, then the key starts over.
+func (f *FetchJobs) Start(key string, run func(ctx context.Context) (FetchResult, error)) FetchJob {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.prune()
+
+	if job, ok := f.byKey[key]; ok {
+		if job.Status == FetchFailed {
+			delete(f.byKey, key)
+		}
+		return *job
+	}
+
+	f.nextID++
+	job := &FetchJob{ID: f.nextID, Status: FetchRunning, StartedAt: f.now().UTC(), key: key, done: make(chan struct{})}
+	f.jobs[job.ID] = job
+	f.byKey[key] = job
+	go func() {
+		result, err := run(f.ctx)
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		finished := f.now().UTC()
+		job.FinishedAt = &finished
+		if err != nil {
+			job.Status = FetchFailed
+			job.Error = err.Error()
+		} else {
+			job.Status = FetchDone
+			job.Result = result
+			job.Snapshot = result.Snapshot
+		}
+		close(job.done)
+	}()
+	return *job
+}
+
+// Get returns the job with the ID.
+func (f *FetchJobs) Get(id uint64) (FetchJob, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	job, ok := f.jobs[id]
+	if !ok {
+		return FetchJob{}, false
+	}
+	return *job, true
+}
+
+// Wait waits up to timeout for the job to finish and returns it, still
+// running when the time is up or ctx is done first. A failed job is
+// reported once, the next Start of its key runs the fetch again.
+func (f *FetchJobs) Wait(ctx context.Context, job FetchJob, timeout time.Duration) FetchJob {
+	if job.done != nil {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		select {
+		case <-job.done:
+		case <-timer.C:
+		case <-ctx.Done():
+		}
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	current, ok := f.jobs[job.ID]
+	if !ok {
+		return job
+	}
+	if current.Status == FetchFailed && f.byKey[current.key] == current {
+		delete(f.byKey, current.key)
+	}
+	return *current
+}
+
+// prune forgets jobs finished longer than the retention ago.
+func (f *FetchJobs) prune() {
+	for id, job := range f.jobs {
+		if job.FinishedAt == nil || f.now().Sub(*job.FinishedAt) < f.retention {
+			continue
+		}
+		delete(f.jobs, id)
+		if f.byKey[job.key] == job {
+			delete(f.byKey, job.key)
+		}
+	}
+}
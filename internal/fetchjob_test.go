@@ -0,0 +1,59 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestFetchJobs(t *testing.T) {
+	jobs := NewFetchJobs(context.Background(), time.Minute)
+	runs := 0
+	fail := func(context.Context) (FetchResult, error) {
+		runs++
+		return FetchResult{}, errors.New("connection refused")
+	}
+
+	job := jobs.Wait(context.Background(), jobs.Start("LH", fail), time.Second)
+	if job.Status != FetchFailed || job.Error != "connection refused" || job.FinishedAt == nil {
+		t.Fatalf("expected failed fetch, got %+v", job)
+	}
+	// The failure was reported, the key fetches again
+	ok := func(context.Context) (FetchResult, error) {
+		runs++
+		return FetchResult{Snapshot: 7}, nil
+	}
+	job = jobs.Wait(context.Background(), jobs.Start("LH", ok), time.Second)
+	if job.Status != FetchDone || job.Snapshot != 7 || runs != 2 {
+		t.Fatalf("expected done fetch after %d runs, got %+v", runs, job)
+	}
+	// Done fetches are kept for the retention
+	if again := jobs.Start("LH", ok); again.ID != job.ID || runs != 2 {
+		t.Errorf("expected the finished fetch, got %+v", again)
+	}
+	jobs.now = func() time.Time { return time.Now().Add(2 * time.Minute) }
+	jobs.Wait(context.Background(), jobs.Start("LH", ok), time.Second)
+	if runs != 3 {
+		t.Errorf("expected a new fetch after the retention, got %d runs", runs)
+	}
+	if _, found := jobs.Get(job.ID); found {
+		t.Errorf("expected fetch %d to be forgotten", job.ID)
+	}
+}
+
+func TestFetchJobsCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	jobs := NewFetchJobs(ctx, time.Minute)
+	job := jobs.Start("LH", func(ctx context.Context) (FetchResult, error) {
+		<-ctx.Done()
+		return FetchResult{}, ctx.Err()
+	})
+	if job = jobs.Wait(context.Background(), job, time.Millisecond); job.Status != FetchRunning {
+		t.Fatalf("expected running fetch, got %+v", job)
+	}
+	cancel()
+	if job = jobs.Wait(context.Background(), job, time.Second); job.Status != FetchFailed {
+		t.Errorf("expected cancelled fetch to fail, got %+v", job)
+	}
+}
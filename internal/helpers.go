@@ -1,12 +1,11 @@
 package internal
 
 import (
-	"log"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
 	"time"
-	"unicode"
 )
 
 func FlattenJSON(data []byte) []byte {
@@ -115,144 +114,30 @@ func NumberToTime(n int64) string {
 
 	return hoursStr + ":" + minutesStr
 }
-func DaysOfOperation(s string) string {
-	return strings.ReplaceAll(s, " ", ".")
-}
-
-func moreThanOneNumberReg(s string) bool {
-	digitCount := 0
-	for _, char := range s {
-		if unicode.IsDigit(char) {
-			digitCount++
-			if digitCount > 1 {
-				return true
-			}
-		}
-	}
-	return false
-}
-
-func SeparateDays(r []string) [][]string {
-	newLines := [][]string{}
-	check := string(r[8])
-	if moreThanOneNumberReg(check) {
-		var days []int
-		// Check if contains
-		if strings.Contains(check, "1") {
-			days = append(days, 1)
-		}
-		if strings.Contains(check, "2") {
-			days = append(days, 2)
-		}
-		if strings.Contains(check, "3") {
-			days = append(days, 3)
-		}
-		if strings.Contains(check, "4") {
-			days = append(days, 4)
-		}
-		if strings.Contains(check, "5") {
-			days = append(days, 5)
-		}
-		if strings.Contains(check, "6") {
-			days = append(days, 6)
-		}
-		if strings.Contains(check, "7") {
-			days = append(days, 7)
-		}
-
-		newLines = performSeparation(r, days)
-		return newLines
-	} else {
-		newLines = append(newLines, r)
-		return newLines
-	}
-
-}
-
-func performSeparation(record []string, weekdays []int) [][]string {
-	var separatedRecords [][]string
-
-	startDate := record[6]
-	endDate := record[7]
-
-	startDateTime, _ := time.Parse("2006-01-02", startDate)
-	endDateTime, _ := time.Parse("2006-01-02", endDate)
-
-	startWeekday := int(startDateTime.Weekday())
-	if startWeekday == 0 {
-		startWeekday = 7
-	}
-	endWeekday := int(endDateTime.Weekday())
-	if endWeekday == 0 {
-		endWeekday = 7
-	}
-	for _, targetWeekday := range weekdays {
-		// Create a copy of the original record
-		newRecord := make([]string, len(record))
-		copy(newRecord, record)
-
-		// Calculate days to adjust for start date
-		daysToAdjustStart := 0
-		if targetWeekday < startWeekday {
-			daysToAdjustStart = 7 - (startWeekday - targetWeekday)
-		} else if targetWeekday > startWeekday {
-			daysToAdjustStart = targetWeekday - startWeekday
-		}
-
-		// Calculate days to adjust for end date
-		daysToAdjustEnd := 0
-		if targetWeekday > endWeekday {
-			daysToAdjustEnd = (targetWeekday - endWeekday) - 7
-		} else if targetWeekday < endWeekday {
-			daysToAdjustEnd = targetWeekday - endWeekday
-		}
-
-		// Create weekday marker (e.g., "...3....")
-		weekdayMarker := strings.Repeat(".", targetWeekday-1) +
-			strconv.Itoa(targetWeekday) +
-			strings.Repeat(".", 7-targetWeekday)
-
-		// Update the record with new values
-		newRecord[8] = weekdayMarker
-		if daysToAdjustStart != 0 {
-			newRecord[6] = startDateTime.AddDate(0, 0, daysToAdjustStart).Format("2006-01-02")
-		}
-		if daysToAdjustEnd != 0 {
-			newRecord[7] = endDateTime.AddDate(0, 0, daysToAdjustEnd).Format("2006-01-02")
-		}
-
-		separatedRecords = append(separatedRecords, newRecord)
-	}
-
-	return separatedRecords
-}
 
+// operatorToICAO returns the ICAO code of the operator from the reference
+// data, unknown operators are returned as they are.
 func operatorToICAO(operator string) string {
-	operatorMap := map[string]string{
-		"2L": "OAW",
-		"BT": "BTI",
-		"LX": "SWR",
-		"CL": "CLH",
-		"EN": "DLA",
-		"LH": "DLH",
-		"OS": "AUA",
-		"SN": "BEL",
-		"TF": "BRX",
+	if o, ok := References().Operator(operator); ok && o.ICAO != "" {
+		return o.ICAO
 	}
+	return operator
+}
 
-	_, exists := operatorMap[operator]
+// Carriers lists the supported carriers in the order of their query codes.
+var Carriers = []string{"LH", "OS", "LX", "SN", "EN"}
 
-	if !exists {
-		return operator
-	} else {
-		return operatorMap[operator]
-	}
+// CarrierCode returns the code GetQueryListForAirline expects for the
+// carrier, ok is false for carriers not supported.
+func CarrierCode(carrier string) (code int, ok bool) {
+	i := slices.Index(Carriers, carrier)
+	return i, i >= 0
 }
 
 // Querying for specific Airline should output specyfic Querylist
 // Code: 0 - LH || 1 - OS || 2 - LX || 3 - SN || 4 - EN
 // beg && end format in SSIM date format DDMMMYY eg. 15MAR25
-func GetQueryListForAirline(code int, beg, end string) (QueryList []ApiQuery) {
+func GetQueryListForAirline(code int, beg, end string, mode TimeMode) (QueryList []ApiQuery) {
 	switch code {
 	case 0:
 		return []ApiQuery{
@@ -261,7 +146,7 @@ func GetQueryListForAirline(code int, beg, end string) (QueryList []ApiQuery) {
 				StartDate:       beg,
 				EndDate:         end,
 				DaysOfOperation: "1234567",
-				TimeMode:        "LT",
+				TimeMode:        mode.Query(),
 				Origin:          "KRK",
 				Destination:     "FRA",
 			},
@@ -270,7 +155,7 @@ func GetQueryListForAirline(code int, beg, end string) (QueryList []ApiQuery) {
 				StartDate:       beg,
 				EndDate:         end,
 				DaysOfOperation: "1234567",
-				TimeMode:        "LT",
+				TimeMode:        mode.Query(),
 				Origin:          "KRK",
 				Destination:     "MUC",
 			},
@@ -282,7 +167,7 @@ func GetQueryListForAirline(code int, beg, end string) (QueryList []ApiQuery) {
 				StartDate:       beg,
 				EndDate:         end,
 				DaysOfOperation: "1234567",
-				TimeMode:        "LT",
+				TimeMode:        mode.Query(),
 				Origin:          "KRK",
 				Destination:     "VIE",
 			},
@@ -294,7 +179,7 @@ func GetQueryListForAirline(code int, beg, end string) (QueryList []ApiQuery) {
 				StartDate:       beg,
 				EndDate:         end,
 				DaysOfOperation: "1234567",
-				TimeMode:        "LT",
+				TimeMode:        mode.Query(),
 				Origin:          "KRK",
 				Destination:     "ZRH",
 			},
@@ -306,7 +191,7 @@ func GetQueryListForAirline(code int, beg, end string) (QueryList []ApiQuery) {
 				StartDate:       beg,
 				EndDate:         end,
 				DaysOfOperation: "1234567",
-				TimeMode:        "LT",
+				TimeMode:        mode.Query(),
 				Origin:          "KRK",
 				Destination:     "BRU",
 			},
@@ -318,7 +203,7 @@ func GetQueryListForAirline(code int, beg, end string) (QueryList []ApiQuery) {
 				StartDate:       beg,
 				EndDate:         end,
 				DaysOfOperation: "1234567",
-				TimeMode:        "LT",
+				TimeMode:        mode.Query(),
 				Origin:          "KRK",
 				Destination:     "MUC",
 			},
@@ -329,127 +214,69 @@ func GetQueryListForAirline(code int, beg, end string) (QueryList []ApiQuery) {
 	}
 }
 
-func AreValidForMerge(record1, record2 []string) (bool, error) {
-	columnsToCompare := []int{0, 1, 2, 3, 4, 5, 8, 9, 10, 11}
-
-	for _, col := range columnsToCompare {
-		if record1[col] != record2[col] {
-
-			return false, nil
-		}
+// Records of the same flight on disjoint weekdays can be combined into one
+// record operating on the union of the days, as long as the widened period
+// does not add any dates neither of them operated on.
+func AreValidForDayMerge(record1, record2 ScheduleRecord) bool {
+	if !record1.sameFlight(record2) || !record1.Days.Intersect(record2.Days).IsEmpty() {
+		return false
 	}
-	// Compare dates
-	record1To := record1[7]
-	record2From := record2[6]
+	merged := PerformDayMerge(record1, record2)
+	return coversSameDates(record1, merged) && coversSameDates(record2, merged)
+}
 
-	dateOne, err := time.Parse("2006-01-02", record1To)
-	if err != nil {
-		return false, err
+// coversSameDates checks that the days of record operate on the same first and
+// last dates within the period of merged as within their own period.
+func coversSameDates(record, merged ScheduleRecord) bool {
+	merged.Days = record.Days
+	first, ok := record.FirstOperation()
+	if !ok {
+		return false
 	}
-	dateOne = dateOne.AddDate(0, 0, 7)
+	last, _ := record.LastOperation()
+	mergedFirst, _ := merged.FirstOperation()
+	mergedLast, _ := merged.LastOperation()
+	return first.Equal(mergedFirst) && last.Equal(mergedLast)
+}
 
-	dateTwo, err := time.Parse("2006-01-02", record2From)
-	if err != nil {
-		return false, err
+func PerformDayMerge(record1, record2 ScheduleRecord) ScheduleRecord {
+	merged := record1
+	merged.Days = record1.Days.Union(record2.Days)
+	if len(record2.Exceptions) > 0 {
+		merged.Exceptions = append(slices.Clone(record1.Exceptions), record2.Exceptions...)
+		slices.SortFunc(merged.Exceptions, func(a, b time.Time) int { return a.Compare(b) })
 	}
-
-	if dateOne.Compare(dateTwo) == 0 {
-		return true, nil
+	if record2.StartDate.Before(merged.StartDate) {
+		merged.StartDate = record2.StartDate
 	}
-	return false, nil
-
-}
-
-func PerformMerge(record1, record2 []string) []string {
-	temp := make([]string, len(record1))
-	copy(temp, record1)
-
-	temp[7] = record2[7]
-
-	return temp
-}
-
-// Helper function to convert FlightResponse to CSV rows
-func convertFlightResponseToCSVRows(d FlightResponse) [][]string {
-	var csvRows [][]string
-
-	flightNumberWrite := strconv.Itoa(d.FlightNumber)
-	startTimeWrite := NumberToTime(d.Legs[0].AircraftDepartureTimeLT)
-	endTimeWrite := NumberToTime(d.Legs[0].AircraftArrivalTimeLT)
-	startDateWrite := SSIMtoDate(d.PeriodOfOperationLT.StartDate)
-	endDateWrite := SSIMtoDate(d.PeriodOfOperationLT.EndDate)
-	daysOfOperationWrite := DaysOfOperation(d.PeriodOfOperationLT.DaysOfOperation)
-	operator := operatorToICAO(d.Legs[0].AircraftOwner)
-
-	row := []string{
-		d.Legs[0].Origin,
-		d.Legs[0].Destination,
-		d.Airline,
-		flightNumberWrite,
-		startTimeWrite,
-		endTimeWrite,
-		startDateWrite,
-		endDateWrite,
-		daysOfOperationWrite,
-		d.Legs[0].AircraftType,
-		operator,
-		d.Legs[0].ServiceType,
+	if record2.EndDate.After(merged.EndDate) {
+		merged.EndDate = record2.EndDate
 	}
-
-	csvRows = append(csvRows, row)
-	return csvRows
+	return merged
 }
 
-func MergeRecords(records [][]string) ([][]string, error) {
-	if len(records) <= 1 {
-		return records, nil
-	}
-
-	mergedRecords := [][]string{} // Resulting list of merged records
-	merged := make(map[int]bool)  // Tracks indices of merged records
-
-	for i := 0; i < len(records); i++ {
-		if merged[i] { // Skip already merged records
-			continue
-		}
-
-		currentRecord := records[i]
-		for j := 0; j < len(records); j++ {
-			if i == j || merged[j] { // Skip self and already merged records
-				continue
-			}
-
-			// Check if the records can be merged
-			validFlag, err := AreValidForMerge(currentRecord, records[j])
-			if err != nil {
-				return nil, err
-			}
-
-			if validFlag {
-				currentRecord = PerformMerge(currentRecord, records[j]) // Merge the records
-				merged[j] = true                                        // Mark the merged record
+// MergeDays combines records of the same flight operating on different
+// weekdays over the same weeks into a single multi-day record.
+func MergeDays(records []ScheduleRecord) []ScheduleRecord {
+	var merged []ScheduleRecord
+	for _, r := range records {
+		combined := false
+		for i := range merged {
+			if AreValidForDayMerge(merged[i], r) {
+				merged[i] = PerformDayMerge(merged[i], r)
+				combined = true
+				break
 			}
 		}
-
-		// Add the merged record to the result
-		mergedRecords = append(mergedRecords, currentRecord)
+		if !combined {
+			merged = append(merged, r)
+		}
 	}
-
-	return mergedRecords, nil
+	return merged
 }
 
-func SortRecordsByDateCol(data [][]string, columnIndex int) {
-	sort.Slice(data, func(i, j int) bool {
-		dateI, errI := time.Parse("2006-01-02", data[i][columnIndex])
-		dateJ, errJ := time.Parse("2006-01-02", data[j][columnIndex])
-
-		// If parsing fails, maintain original order
-		if errI != nil || errJ != nil {
-			log.Printf("Parsing for Sorting Failed because: %v and %v", errI, errJ)
-			return false
-		}
-
-		// Compare dates
-		return dateI.Before(dateJ)
+func SortRecordsByStartDate(records []ScheduleRecord) {
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].StartDate.Before(records[j].StartDate)
 	})
 }
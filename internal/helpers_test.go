@@ -2,7 +2,10 @@ package internal
 
 import (
 	"bytes"
+	"reflect"
+	"strconv"
 	"testing"
+	"time"
 )
 
 func TestFlattenJSON(t *testing.T) {
@@ -256,215 +259,33 @@ func TestNumberToTime(t *testing.T) {
 	}
 }
 
-func TestDaysOfOperation(t *testing.T) {
-	tests := []struct {
-		name     string
-		input    string
-		expected string
-	}{{
-		name:     "Valid test - input always good",
-		input:    "123   7",
-		expected: "123...7",
-	}, {
-		name:     "Valid test - edges",
-		input:    " 23456 ",
-		expected: ".23456.",
-	},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			result := DaysOfOperation(tt.input)
-			if result != tt.expected {
-				t.Errorf("Test %s failed: expected %s, got %s", tt.name, tt.expected, result)
-			}
-		})
-	}
-}
-
-func TestMoreThanOneDigit(t *testing.T) {
-	tests := []struct {
-		name     string
-		input    string
-		expected bool
-	}{
-		{
-			name:     "Single digit",
-			input:    "1......",
-			expected: false,
-		},
-		{
-			name:     "Two digits",
-			input:    "12.....",
-			expected: true,
-		},
-		{
-			name:     "Two digits on edges",
-			input:    "1.....7",
-			expected: true,
-		},
-		{
-			name:     "All digits",
-			input:    "1234567",
-			expected: true,
-		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			result := moreThanOneNumberReg(tt.input)
-
-			if result != tt.expected {
-				t.Errorf("Test %s failed: expected: %v, got: %v", tt.name, tt.expected, result)
-			}
-		})
-	}
-}
-func TestSeparateDays(t *testing.T) {
-	tests := []struct {
-		name     string
-		input    []string
-		expected [][]string
-	}{
-		{
-			name:  "Standard week pattern with single day",
-			input: []string{"KRK", "FRA", "LH", "1365", "10:20", "12:05", "2024-10-29", "2024-12-10", ".2.....", "320", "DLH", "J"},
-			expected: [][]string{
-				{"KRK", "FRA", "LH", "1365", "10:20", "12:05", "2024-10-29", "2024-12-10", ".2.....", "320", "DLH", "J"},
-			},
-		},
-		{
-			name:  "Multiple days in week",
-			input: []string{"KRK", "FRA", "LH", "1365", "10:20", "12:05", "2024-10-21", "2024-12-29", "1.3.5.7", "320", "DLH", "J"},
-			expected: [][]string{
-				{"KRK", "FRA", "LH", "1365", "10:20", "12:05", "2024-10-21", "2024-12-23", "1......", "320", "DLH", "J"},
-				{"KRK", "FRA", "LH", "1365", "10:20", "12:05", "2024-10-23", "2024-12-25", "..3....", "320", "DLH", "J"},
-				{"KRK", "FRA", "LH", "1365", "10:20", "12:05", "2024-10-25", "2024-12-27", "....5..", "320", "DLH", "J"},
-				{"KRK", "FRA", "LH", "1365", "10:20", "12:05", "2024-10-27", "2024-12-29", "......7", "320", "DLH", "J"},
-			},
-		},
-		{
-			name:  "All days of week",
-			input: []string{"KRK", "FRA", "LH", "1365", "10:20", "12:05", "2024-10-21", "2024-12-29", "1234567", "320", "DLH", "J"},
-			expected: [][]string{
-				{"KRK", "FRA", "LH", "1365", "10:20", "12:05", "2024-10-21", "2024-12-23", "1......", "320", "DLH", "J"},
-				{"KRK", "FRA", "LH", "1365", "10:20", "12:05", "2024-10-22", "2024-12-24", ".2.....", "320", "DLH", "J"},
-				{"KRK", "FRA", "LH", "1365", "10:20", "12:05", "2024-10-23", "2024-12-25", "..3....", "320", "DLH", "J"},
-				{"KRK", "FRA", "LH", "1365", "10:20", "12:05", "2024-10-24", "2024-12-26", "...4...", "320", "DLH", "J"},
-				{"KRK", "FRA", "LH", "1365", "10:20", "12:05", "2024-10-25", "2024-12-27", "....5..", "320", "DLH", "J"},
-				{"KRK", "FRA", "LH", "1365", "10:20", "12:05", "2024-10-26", "2024-12-28", ".....6.", "320", "DLH", "J"},
-				{"KRK", "FRA", "LH", "1365", "10:20", "12:05", "2024-10-27", "2024-12-29", "......7", "320", "DLH", "J"},
-			},
-		},
-		{
-			name:  "Weekday pairs",
-			input: []string{"KRK", "FRA", "LH", "1365", "10:20", "12:05", "2024-10-21", "2024-12-29", "12.45.7", "320", "DLH", "J"},
-			expected: [][]string{
-				{"KRK", "FRA", "LH", "1365", "10:20", "12:05", "2024-10-21", "2024-12-23", "1......", "320", "DLH", "J"},
-				{"KRK", "FRA", "LH", "1365", "10:20", "12:05", "2024-10-22", "2024-12-24", ".2.....", "320", "DLH", "J"},
-				{"KRK", "FRA", "LH", "1365", "10:20", "12:05", "2024-10-24", "2024-12-26", "...4...", "320", "DLH", "J"},
-				{"KRK", "FRA", "LH", "1365", "10:20", "12:05", "2024-10-25", "2024-12-27", "....5..", "320", "DLH", "J"},
-				{"KRK", "FRA", "LH", "1365", "10:20", "12:05", "2024-10-27", "2024-12-29", "......7", "320", "DLH", "J"},
-			},
-		},
-		{
-			name:  "Edge Case Weekday",
-			input: []string{"MUC", "KRK", "LH", "1624", "14:40", "16:00", "2025-03-30", "2025-05-18", "1234567", "E95", "DLA", "J"},
-			expected: [][]string{
-				{"MUC", "KRK", "LH", "1624", "14:40", "16:00", "2025-03-31", "2025-05-12", "1......", "E95", "DLA", "J"},
-				{"MUC", "KRK", "LH", "1624", "14:40", "16:00", "2025-04-01", "2025-05-13", ".2.....", "E95", "DLA", "J"},
-				{"MUC", "KRK", "LH", "1624", "14:40", "16:00", "2025-04-02", "2025-05-14", "..3....", "E95", "DLA", "J"},
-				{"MUC", "KRK", "LH", "1624", "14:40", "16:00", "2025-04-03", "2025-05-15", "...4...", "E95", "DLA", "J"},
-				{"MUC", "KRK", "LH", "1624", "14:40", "16:00", "2025-04-04", "2025-05-16", "....5..", "E95", "DLA", "J"},
-				{"MUC", "KRK", "LH", "1624", "14:40", "16:00", "2025-04-05", "2025-05-17", ".....6.", "E95", "DLA", "J"},
-				{"MUC", "KRK", "LH", "1624", "14:40", "16:00", "2025-03-30", "2025-05-18", "......7", "E95", "DLA", "J"},
-			},
-		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			result := SeparateDays(tt.input)
-			if !equal(result, tt.expected) {
-				t.Errorf("\nTest %s failed:\nexpected: %v\ngot: %v", tt.name, tt.expected, result)
-			}
-		})
-	}
-}
-
-// Helper function to check equality of 2D slices
-func equal(a, b [][]string) bool {
-	if len(a) != len(b) {
-		return false
-	}
-	for i := range a {
-		if len(a[i]) != len(b[i]) {
-			return false
-		}
-		for j := range a[i] {
-			if a[i][j] != b[i][j] {
-				return false
-			}
-		}
-	}
-	return true
-}
-
-func TestPerformSeparation(t *testing.T) {
-	tests := []struct {
-		name     string
-		row      []string
-		days     []int
-		expected [][]string
-	}{
-		{
-			name: "Valid case with normal dates",
-			row:  []string{"data1", "data2", "data3", "data4", "data5", "data6", "2023-05-01", "2023-05-07", "1.3.5.."},
-			days: []int{1, 3, 5},
-			expected: [][]string{
-				{"data1", "data2", "data3", "data4", "data5", "data6", "2023-05-01", "2023-05-01", "1......"},
-				{"data1", "data2", "data3", "data4", "data5", "data6", "2023-05-03", "2023-05-03", "..3...."},
-				{"data1", "data2", "data3", "data4", "data5", "data6", "2023-05-05", "2023-05-05", "....5.."},
-			},
-		},
-		{
-			name: "Edge case valid",
-			row:  []string{"data1", "data2", "data3", "data4", "data5", "data6", "2024-05-06", "2024-05-26", "1.....7"},
-			days: []int{1, 7},
-			expected: [][]string{
-				{"data1", "data2", "data3", "data4", "data5", "data6", "2024-05-06", "2024-05-20", "1......"},
-				{"data1", "data2", "data3", "data4", "data5", "data6", "2024-05-12", "2024-05-26", "......7"},
-			},
-		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			result := performSeparation(tt.row, tt.days)
-			if !equal2d(result, tt.expected) {
-				t.Errorf("Test %s failed: expected %v, got %v", tt.name, tt.expected, result)
-			}
-		})
+// testRecord builds a record from the columns of the default export:
+// origin, destination, airline, flight, departure, arrival, from, to, days, aircraft, owner, service type.
+func testRecord(fields ...string) ScheduleRecord {
+	flightNumber, _ := strconv.Atoi(fields[3])
+	departure, _ := ParseTimeOfDay(fields[4])
+	arrival, _ := ParseTimeOfDay(fields[5])
+	startDate, _ := time.Parse(dateLayout, fields[6])
+	endDate, _ := time.Parse(dateLayout, fields[7])
+	days, _ := ParseWeekdays(fields[8])
+	return ScheduleRecord{
+		Flight: Flight{
+			Origin:        fields[0],
+			Destination:   fields[1],
+			Airline:       fields[2],
+			FlightNumber:  flightNumber,
+			Departure:     departure,
+			Arrival:       arrival,
+			AircraftType:  fields[9],
+			AircraftOwner: fields[10],
+			ServiceType:   fields[11],
+		},
+		StartDate: startDate,
+		EndDate:   endDate,
+		Days:      days,
 	}
 }
 
-// Helper function to check equality of 2D slices
-func equal2d(a, b [][]string) bool {
-	if len(a) != len(b) {
-		return false
-	}
-	for i := range a {
-		if len(a[i]) != len(b[i]) {
-			return false
-		}
-		for j := range a[i] {
-			if a[i][j] != b[i][j] {
-				return false
-			}
-		}
-	}
-	return true
-}
 func TestOperatorToICAO(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -630,7 +451,7 @@ func TestGetQueryListForAirline(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := GetQueryListForAirline(tt.code, tt.beg, tt.end)
+			result := GetQueryListForAirline(tt.code, tt.beg, tt.end, TimeModeLT)
 			if len(result) != len(tt.expected) {
 				t.Errorf("Test %s failed: expected %v, got %v", tt.name, tt.expected, result)
 				return
@@ -646,164 +467,22 @@ func TestGetQueryListForAirline(t *testing.T) {
 	}
 }
 
-// Rework
-func TestAreValidForMerge(t *testing.T) {
-	tests := []struct {
-		name      string
-		record1   []string
-		record2   []string
-		expected  bool
-		expectErr bool
-	}{
-		{
-			name: "Valid merge",
-			record1: []string{
-				"1", "ABC", "XYZ", "123", "456", "789", "2024-01-01", "2024-01-08", "Mon", "Tue", "Wed", "Thu",
-			},
-			record2: []string{
-				"1", "ABC", "XYZ", "123", "456", "789", "2024-01-15", "2024-01-22", "Mon", "Tue", "Wed", "Thu",
-			},
-			expected:  true,
-			expectErr: false,
-		},
-		{
-			name: "Invalid merge - different columns",
-			record1: []string{
-				"1", "ABC", "XYZ", "123", "456", "789", "2024-01-01", "2024-01-08", "Mon", "Tue", "Wed", "Thu",
-			},
-			record2: []string{
-				"2", "DEF", "XYZ", "123", "456", "789", "2024-01-08", "2024-01-15", "Mon", "Tue", "Wed", "Thu",
-			},
-			expected:  false,
-			expectErr: false,
-		},
-		{
-			name: "Invalid merge - different dates",
-			record1: []string{
-				"1", "ABC", "XYZ", "123", "456", "789", "2024-01-01", "2024-01-08", "Mon", "Tue", "Wed", "Thu",
-			},
-			record2: []string{
-				"1", "ABC", "XYZ", "123", "456", "789", "2024-01-10", "2024-01-15", "Mon", "Tue", "Wed", "Thu",
-			},
-			expected:  false,
-			expectErr: false,
-		},
-		{
-			name: "Invalid date format",
-			record1: []string{
-				"1", "ABC", "XYZ", "123", "456", "789", "2024-01-01", "2024-01-08", "Mon", "Tue", "Wed", "Thu",
-			},
-			record2: []string{
-				"1", "ABC", "XYZ", "123", "456", "789", "invalid-date", "2024-01-15", "Mon", "Tue", "Wed", "Thu",
-			},
-			expected:  false,
-			expectErr: true,
-		},
-		{
-			name: "Same data, same dates, valid merge",
-			record1: []string{
-				"1", "ABC", "XYZ", "123", "456", "789", "2024-01-01", "2024-01-08", "Mon", "Tue", "Wed", "Thu",
-			},
-			record2: []string{
-				"1", "ABC", "XYZ", "123", "456", "789", "2024-01-15", "2024-01-22", "Mon", "Tue", "Wed", "Thu",
-			},
-			expected:  true,
-			expectErr: false,
-		},
+func TestCarrierCode(t *testing.T) {
+	if code, ok := CarrierCode("LX"); code != 2 || !ok {
+		t.Errorf("expected code 2 for LX, got %d %v", code, ok)
 	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			result, err := AreValidForMerge(tt.record1, tt.record2)
-
-			// Check if error state matches expectations
-			if (err != nil) != tt.expectErr {
-				t.Errorf("Test %s failed: expected error: %v, got error: %v", tt.name, tt.expectErr, err)
-			}
-
-			// Compare the result to the expected value
-			if result != tt.expected {
-				t.Errorf("Test %s failed: expected: %v, got: %v", tt.name, tt.expected, result)
-			}
-		})
+	// An unknown carrier must not fall back to the routes of LH
+	if code, ok := CarrierCode("XX"); ok || len(GetQueryListForAirline(code, "", "", TimeModeLT)) != 0 {
+		t.Errorf("expected no routes for an unknown carrier, got code %d %v", code, ok)
 	}
 }
-func TestPerformMerge(t *testing.T) {
-	tests := []struct {
-		name     string
-		record1  []string
-		record2  []string
-		expected []string
-	}{
-		{
-			name: "Merge records with matching columns and valid dates.",
-			record1: []string{
-				"1", "ABC", "XYZ", "123", "456", "789", "2024-01-01", "2024-01-08", "Mon", "Tue", "Wed", "Thu",
-			},
-			record2: []string{
-				"1", "ABC", "XYZ", "123", "456", "789", "2024-01-15", "2024-01-22", "Mon", "Tue", "Wed", "Thu",
-			},
-			expected: []string{
-				"1", "ABC", "XYZ", "123", "456", "789", "2024-01-01", "2024-01-22", "Mon", "Tue", "Wed", "Thu",
-			},
-		},
-		{
-			name: "Merge records with different data but same structure",
-			record1: []string{
-				"2", "DEF", "ABC", "111", "222", "333", "2024-01-01", "2024-01-08", "Mon", "Tue", "Wed", "Thu",
-			},
-			record2: []string{
-				"2", "DEF", "ABC", "111", "222", "333", "2024-01-08", "2024-01-15", "Mon", "Tue", "Wed", "Thu",
-			},
-			expected: []string{
-				"2", "DEF", "ABC", "111", "222", "333", "2024-01-01", "2024-01-15", "Mon", "Tue", "Wed", "Thu",
-			},
-		},
-		{
-			name: "Merge records with identical dates",
-			record1: []string{
-				"3", "XYZ", "DEF", "456", "789", "101", "2024-02-01", "2024-02-08", "Mon", "Tue", "Wed", "Thu",
-			},
-			record2: []string{
-				"3", "XYZ", "DEF", "456", "789", "101", "2024-02-08", "2024-02-15", "Mon", "Tue", "Wed", "Thu",
-			},
-			expected: []string{
-				"3", "XYZ", "DEF", "456", "789", "101", "2024-02-01", "2024-02-15", "Mon", "Tue", "Wed", "Thu",
-			},
-		},
-		{
-			name: "Merge with unchanged date but different data",
-			record1: []string{
-				"4", "GHI", "JKL", "555", "666", "777", "2024-03-01", "2024-03-08", "Mon", "Tue", "Wed", "Thu",
-			},
-			record2: []string{
-				"4", "GHI", "JKL", "555", "666", "777", "2024-03-08", "2024-03-15", "Mon", "Tue", "Wed", "Thu",
-			},
-			expected: []string{
-				"4", "GHI", "JKL", "555", "666", "777", "2024-03-01", "2024-03-15", "Mon", "Tue", "Wed", "Thu",
-			},
-		},
-	}
 
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			result := PerformMerge(tt.record1, tt.record2)
-
-			// Check if result matches expected
-			for i, v := range result {
-				if v != tt.expected[i] {
-					t.Errorf("Test %s failed: expected: %v, got: %v", tt.name, tt.expected, result)
-					break
-				}
-			}
-		})
-	}
-}
-func TestConvertFlightResponseToCSVRows(t *testing.T) {
+func TestConvertFlightResponseToRecords(t *testing.T) {
 	tests := []struct {
-		name     string
-		input    FlightResponse
-		expected [][]string
+		name      string
+		input     FlightResponse
+		expected  []ScheduleRecord
+		expectErr bool
 	}{
 		{
 			name: "Basic Flight Response",
@@ -827,10 +506,8 @@ func TestConvertFlightResponseToCSVRows(t *testing.T) {
 					DaysOfOperation: "1234567",
 				},
 			},
-			expected: [][]string{
-				{
-					"KRK", "FRA", "LH", "123", "10:50", "15:00", "2024-01-01", "2024-01-31", "1234567", "A320", "OAW", "Regular",
-				},
+			expected: []ScheduleRecord{
+				testRecord("KRK", "FRA", "LH", "123", "10:50", "15:00", "2024-01-01", "2024-01-31", "1234567", "A320", "OAW", "Regular"),
 			},
 		},
 		{
@@ -852,153 +529,192 @@ func TestConvertFlightResponseToCSVRows(t *testing.T) {
 				PeriodOfOperationLT: PeriodOfOperation{
 					StartDate:       "5FEB24",
 					EndDate:         "10FEB24",
-					DaysOfOperation: "1357",
+					DaysOfOperation: "1 3 5 7",
+				},
+			},
+			expected: []ScheduleRecord{
+				testRecord("MUC", "ZRH", "LX", "456", "10:50", "15:50", "2024-02-05", "2024-02-10", "1.3.5.7", "A321", "SWR", "VIP"),
+			},
+		},
+		{
+			name: "Flight with extended leg fields",
+			input: FlightResponse{
+				Airline:      "LH",
+				FlightNumber: 1364,
+				Legs: []Leg{
+					{
+						Origin:                         "FRA",
+						Destination:                    "KRK",
+						AircraftOwner:                  "LH",
+						AircraftType:                   "32N",
+						AircraftConfigurationVersion:   "C12Y168",
+						Registration:                   "DAINA",
+						ServiceType:                    "J",
+						AircraftDepartureTimeLT:        1420,
+						AircraftDepartureTimeUTC:       1300,
+						AircraftDepartureTimeVariation: 120,
+						AircraftArrivalTimeLT:          10,
+						AircraftArrivalTimeUTC:         1330,
+						AircraftArrivalTimeDateDiffLT:  1,
+						AircraftArrivalTimeVariation:   120,
+					},
+				},
+				PeriodOfOperationLT: PeriodOfOperation{
+					StartDate:       "30MAR25",
+					EndDate:         "25OCT25",
+					DaysOfOperation: "1234567",
 				},
 			},
-			expected: [][]string{
+			expected: []ScheduleRecord{
 				{
-					"MUC", "ZRH", "LX", "456", "10:50", "15:50", "2024-02-05", "2024-02-10", "1357", "A321", "SWR", "VIP",
+					Flight: Flight{
+						Origin:               "FRA",
+						Destination:          "KRK",
+						Airline:              "LH",
+						FlightNumber:         1364,
+						Departure:            1420,
+						Arrival:              10,
+						DepartureUTC:         1300,
+						ArrivalUTC:           1330,
+						ArrivalDateDiff:      1,
+						DepartureVariation:   120,
+						ArrivalVariation:     120,
+						AircraftType:         "32N",
+						AircraftOwner:        "LH",
+						ServiceType:          "J",
+						Registration:         "DAINA",
+						ConfigurationVersion: "C12Y168",
+					},
+					StartDate: time.Date(2025, 3, 30, 0, 0, 0, 0, time.UTC),
+					EndDate:   time.Date(2025, 10, 25, 0, 0, 0, 0, time.UTC),
+					Days:      NewWeekdays(1, 2, 3, 4, 5, 6, 7),
+				},
+			},
+		},
+		{
+			name: "Invalid period",
+			input: FlightResponse{
+				Airline:      "LH",
+				FlightNumber: 1,
+				Legs:         []Leg{{Origin: "KRK", Destination: "FRA"}},
+				PeriodOfOperationLT: PeriodOfOperation{
+					StartDate: "1XYZ24",
+					EndDate:   "31JAN24",
 				},
 			},
+			expectErr: true,
+		},
+		{
+			name: "No legs",
+			input: FlightResponse{
+				Airline:      "LH",
+				FlightNumber: 1,
+			},
+			expectErr: true,
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := convertFlightResponseToCSVRows(tt.input)
-
-			// Compare result with expected
-			if len(result) != len(tt.expected) {
-				t.Errorf("Test %s failed: expected %v, got %v", tt.name, tt.expected, result)
+			result, err := convertFlightResponseToRecords(tt.input, TimeModeLT)
+			if (err != nil) != tt.expectErr {
+				t.Errorf("Test %s failed: expected error: %v, got: %v", tt.name, tt.expectErr, err)
 				return
 			}
-
-			for i, row := range result {
-				for j, val := range row {
-					if val != tt.expected[i][j] {
-						t.Errorf("Test %s failed: expected %v at row %d, column %d, got %v", tt.name, tt.expected[i][j], i, j, val)
-					}
-				}
+			if !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("Test %s failed: expected %v, got %v", tt.name, tt.expected, result)
 			}
 		})
 	}
 }
-func TestMergeRecords(t *testing.T) {
+func TestMergeDays(t *testing.T) {
 	tests := []struct {
-		name      string
-		input     [][]string
-		expected  [][]string
-		expectErr bool
+		name     string
+		input    []ScheduleRecord
+		expected []ScheduleRecord
 	}{
 		{
-			name: "Valid merge of two records",
-			input: [][]string{
-				{"KRK", "FRA", "LH", "123", "02:00", "02:30", "2024-01-01", "2024-01-08", "1234567", "A320", "OAW", "Regular"},
-				{"KRK", "FRA", "LH", "123", "02:00", "02:30", "2024-01-15", "2024-01-22", "1234567", "A320", "OAW", "Regular"},
+			name: "Aligned weekdays",
+			input: []ScheduleRecord{
+				testRecord("KRK", "FRA", "LH", "1365", "10:20", "12:05", "2024-01-01", "2024-01-29", "1......", "320", "DLH", "J"),
+				testRecord("KRK", "FRA", "LH", "1365", "10:20", "12:05", "2024-01-03", "2024-01-31", "..3....", "320", "DLH", "J"),
+				testRecord("KRK", "FRA", "LH", "1365", "10:20", "12:05", "2024-01-05", "2024-01-26", "....5..", "320", "DLH", "J"),
+			},
+			expected: []ScheduleRecord{
+				testRecord("KRK", "FRA", "LH", "1365", "10:20", "12:05", "2024-01-01", "2024-01-31", "1.3.5..", "320", "DLH", "J"),
+			},
+		},
+		{
+			name: "Weekday starting a week later",
+			input: []ScheduleRecord{
+				testRecord("KRK", "FRA", "LH", "1365", "10:20", "12:05", "2024-01-01", "2024-01-29", "1......", "320", "DLH", "J"),
+				testRecord("KRK", "FRA", "LH", "1365", "10:20", "12:05", "2024-01-10", "2024-01-31", "..3....", "320", "DLH", "J"),
 			},
-			expected: [][]string{
-				{"KRK", "FRA", "LH", "123", "02:00", "02:30", "2024-01-01", "2024-01-22", "1234567", "A320", "OAW", "Regular"},
+			expected: []ScheduleRecord{
+				testRecord("KRK", "FRA", "LH", "1365", "10:20", "12:05", "2024-01-01", "2024-01-29", "1......", "320", "DLH", "J"),
+				testRecord("KRK", "FRA", "LH", "1365", "10:20", "12:05", "2024-01-10", "2024-01-31", "..3....", "320", "DLH", "J"),
 			},
-			expectErr: false,
 		},
 		{
-			name: "Non-mergeable records",
-			input: [][]string{
-				{"KRK", "FRA", "LH", "123", "02:00", "02:30", "2024-01-01", "2024-01-31", "1234567", "A320", "OAW", "Regular"},
-				{"MUC", "ZRH", "LX", "456", "03:00", "03:30", "2024-02-01", "2024-02-28", "1357", "A321", "SWR", "VIP"},
+			name: "Different times",
+			input: []ScheduleRecord{
+				testRecord("KRK", "FRA", "LH", "1365", "10:20", "12:05", "2024-01-01", "2024-01-29", "1......", "320", "DLH", "J"),
+				testRecord("KRK", "FRA", "LH", "1365", "11:20", "13:05", "2024-01-03", "2024-01-31", "..3....", "320", "DLH", "J"),
 			},
-			expected: [][]string{
-				{"KRK", "FRA", "LH", "123", "02:00", "02:30", "2024-01-01", "2024-01-31", "1234567", "A320", "OAW", "Regular"},
-				{"MUC", "ZRH", "LX", "456", "03:00", "03:30", "2024-02-01", "2024-02-28", "1357", "A321", "SWR", "VIP"},
+			expected: []ScheduleRecord{
+				testRecord("KRK", "FRA", "LH", "1365", "10:20", "12:05", "2024-01-01", "2024-01-29", "1......", "320", "DLH", "J"),
+				testRecord("KRK", "FRA", "LH", "1365", "11:20", "13:05", "2024-01-03", "2024-01-31", "..3....", "320", "DLH", "J"),
 			},
-			expectErr: false,
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result, err := MergeRecords(tt.input)
-			if (err != nil) != tt.expectErr {
-				t.Errorf("expected error: %v, got: %v", tt.expectErr, err)
-				return
-			}
-
-			if len(result) != len(tt.expected) {
-				t.Errorf("expected: %v, got: %v", tt.expected, result)
-				return
-			}
-
-			for i, row := range result {
-				for j, val := range row {
-					if val != tt.expected[i][j] {
-						t.Errorf("at row %d, column %d: expected %v, got %v", i, j, tt.expected[i][j], val)
-					}
-				}
+			result := MergeDays(tt.input)
+			if !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("Test %s failed: expected: %v, got: %v", tt.name, tt.expected, result)
 			}
 		})
 	}
 }
 
-// rework
-func TestSortRecordsByDateCol(t *testing.T) {
+func TestSortRecordsByStartDate(t *testing.T) {
+	jan1 := testRecord("KRK", "FRA", "LH", "1", "10:00", "11:00", "2024-01-01", "2024-01-01", "1......", "320", "DLH", "J")
+	jan2 := testRecord("KRK", "FRA", "LH", "2", "10:00", "11:00", "2024-01-02", "2024-01-02", ".2.....", "320", "DLH", "J")
+	jan3 := testRecord("KRK", "FRA", "LH", "3", "10:00", "11:00", "2024-01-03", "2024-01-03", "..3....", "320", "DLH", "J")
+
 	tests := []struct {
 		name     string
-		input    [][]string
-		expected [][]string
+		input    []ScheduleRecord
+		expected []ScheduleRecord
 	}{
 		{
-			name: "Valid dates in ascending order",
-			input: [][]string{
-				{"KRK", "FRA", "2024-01-03"},
-				{"KRK", "FRA", "2024-01-01"},
-				{"KRK", "FRA", "2024-01-02"},
-			},
-			expected: [][]string{
-				{"KRK", "FRA", "2024-01-01"},
-				{"KRK", "FRA", "2024-01-02"},
-				{"KRK", "FRA", "2024-01-03"},
-			},
+			name:     "Valid dates in ascending order",
+			input:    []ScheduleRecord{jan3, jan1, jan2},
+			expected: []ScheduleRecord{jan1, jan2, jan3},
 		},
 		{
-			name: "Already sorted records",
-			input: [][]string{
-				{"KRK", "FRA", "2024-01-01"},
-				{"KRK", "FRA", "2024-01-02"},
-				{"KRK", "FRA", "2024-01-03"},
-			},
-			expected: [][]string{
-				{"KRK", "FRA", "2024-01-01"},
-				{"KRK", "FRA", "2024-01-02"},
-				{"KRK", "FRA", "2024-01-03"},
-			},
+			name:     "Already sorted records",
+			input:    []ScheduleRecord{jan1, jan2, jan3},
+			expected: []ScheduleRecord{jan1, jan2, jan3},
 		},
 		{
 			name:     "Empty list",
-			input:    [][]string{},
-			expected: [][]string{},
+			input:    []ScheduleRecord{},
+			expected: []ScheduleRecord{},
 		},
 		{
-			name: "Single record",
-			input: [][]string{
-				{"KRK", "FRA", "2024-01-01"},
-			},
-			expected: [][]string{
-				{"KRK", "FRA", "2024-01-01"},
-			},
+			name:     "Single record",
+			input:    []ScheduleRecord{jan1},
+			expected: []ScheduleRecord{jan1},
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			SortRecordsByDateCol(tt.input, 2)
-
-			// Check if the result matches the expected output
-			for i := range tt.input {
-				for j := range tt.input[i] {
-					if tt.input[i][j] != tt.expected[i][j] {
-						t.Errorf("Test %s failed: expected %v, got %v", tt.name, tt.expected, tt.input)
-					}
-				}
+			SortRecordsByStartDate(tt.input)
+			if !reflect.DeepEqual(tt.input, tt.expected) {
+				t.Errorf("Test %s failed: expected %v, got %v", tt.name, tt.expected, tt.input)
 			}
 		})
 	}
@@ -0,0 +1,562 @@
+package internal
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type Locale string
+
+const (
+	LocalePL Locale = "pl"
+	LocaleEN Locale = "en"
+
+	DefaultLocale = LocalePL
+)
+
+var Locales = []Locale{LocalePL, LocaleEN}
+
+// LocaleFormat holds the CSV conventions of a locale.
+type LocaleFormat struct {
+	Delimiter  rune
+	DateLayout string
+}
+
+var localeFormats = map[Locale]LocaleFormat{
+	// Polish Excel expects semicolons since comma is the decimal separator
+	LocalePL: {Delimiter: ';', DateLayout: "02.01.2006"},
+	LocaleEN: {Delimiter: ',', DateLayout: "2006-01-02"},
+}
+
+func (l Locale) Format() LocaleFormat {
+	if f, ok := localeFormats[l]; ok {
+		return f
+	}
+	return localeFormats[DefaultLocale]
+}
+
+func isSupportedLocale(l Locale) bool {
+	for _, s := range Locales {
+		if s == l {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseLocale picks the locale from an explicit option first and falls back
+// to the Accept-Language header, honouring its q-values.
+func ParseLocale(option, acceptLanguage string) Locale {
+	if l := Locale(strings.ToLower(strings.TrimSpace(option))); isSupportedLocale(l) {
+		return l
+	}
+
+	best := DefaultLocale
+	bestQ := -1.0
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		base, _, _ := strings.Cut(strings.ToLower(tag), "-")
+		if l := Locale(base); isSupportedLocale(l) && q > bestQ {
+			best, bestQ = l, q
+		}
+	}
+	return best
+}
+
+// T returns the translated message for key, formatted with args.
+// Missing translations fall back to the default locale and then to the key.
+func T(l Locale, key string, args ...any) string {
+	msg, ok := messages[l][key]
+	if !ok {
+		msg, ok = messages[DefaultLocale][key]
+	}
+	if !ok {
+		msg = key
+	}
+	if len(args) > 0 {
+		return fmt.Sprintf(msg, args...)
+	}
+	return msg
+}
+
+// Messages returns every translation with the given key prefix, used to hand
+// strings over to the page scripts.
+func Messages(l Locale, prefix string) map[string]string {
+	out := make(map[string]string)
+	for key := range messages[DefaultLocale] {
+		if strings.HasPrefix(key, prefix) {
+			out[strings.TrimPrefix(key, prefix)] = T(l, key)
+		}
+	}
+	return out
+}
+
+var messages = map[Locale]map[string]string{
+	LocalePL: {
+		"column.origin":              "Z",
+		"column.destination":         "Do",
+		"column.airline":             "Linia",
+		"column.flight_number":       "Numer",
+		"column.departure":           "Odlot",
+		"column.arrival":             "Przylot",
+		"column.start_date":          "Od dnia",
+		"column.end_date":            "Do dnia",
+		"column.days":                "Dni",
+		"column.aircraft_type":       "Samolot",
+		"column.operator":            "Operator",
+		"column.service_type":        "Typ",
+		"column.registration":        "Rejestracja",
+		"column.configuration":       "Konfiguracja",
+		"column.operator_name":       "Nazwa operatora",
+		"column.alliance":            "Sojusz",
+		"column.aircraft_icao":       "Samolot ICAO",
+		"column.aircraft_family":     "Rodzina samolotu",
+		"column.wake_category":       "Kategoria turbulencji",
+		"column.departure_utc":       "Odlot UTC",
+		"column.arrival_utc":         "Przylot UTC",
+		"column.departure_date_diff": "Zmiana dnia odlotu",
+		"column.arrival_date_diff":   "Zmiana dnia przylotu",
+		"column.departure_variation": "Różnica czasu odlotu",
+		"column.arrival_variation":   "Różnica czasu przylotu",
+		"column.exceptions":          "Brak operacji",
+		"column.dst_shift":           "Zmiana godziny odlotu po zmianie czasu (min)",
+		"column.arrival_dst_shift":   "Zmiana godziny przylotu po zmianie czasu (min)",
+
+		"diff.kind":                  "Zmiana",
+		"diff.flight":                "Lot",
+		"diff.origin":                "Z",
+		"diff.destination":           "Do",
+		"diff.weekday":               "Dzień",
+		"diff.start_date":            "Od dnia",
+		"diff.end_date":              "Do dnia",
+		"diff.old":                   "Było",
+		"diff.new":                   "Jest",
+		"diff.sheet":                 "Zmiany",
+		"export.sheet":               "Rozkład",
+		"diff.kind.added":            "Dodany",
+		"diff.kind.removed":          "Usunięty",
+		"diff.kind.retimed":          "Zmiana godzin",
+		"diff.kind.aircraft_changed": "Zmiana samolotu",
+		"diff.kind.operator_changed": "Zmiana operatora",
+		"diff.kind.days_changed":     "Zmiana dni",
+		"diff.kind.period_extended":  "Wydłużony okres",
+		"diff.kind.period_shortened": "Skrócony okres",
+
+		"capacity.carrier":       "Linia",
+		"capacity.origin":        "Z",
+		"capacity.destination":   "Do",
+		"capacity.week":          "Tydzień",
+		"capacity.week_start":    "Od dnia",
+		"capacity.frequencies":   "Częstotliwość",
+		"capacity.seats":         "Miejsca",
+		"capacity.unknown_seats": "Operacje bez liczby miejsc",
+		"capacity.sheet":         "Przepustowość",
+
+		"movements.day":        "Dzień",
+		"movements.kind":       "Ruch",
+		"movements.departures": "Odloty",
+		"movements.arrivals":   "Przyloty",
+		"movements.total":      "Razem",
+		"movements.sheet":      "Ruch",
+		"movements.title":      "Ruch na lotnisku %s",
+
+		"timeline.title": "Siatka rejsów",
+
+		"rotation.station":              "Lotnisko",
+		"rotation.inbound":              "Lot przylotowy",
+		"rotation.origin":               "Z",
+		"rotation.arrival":              "Przylot",
+		"rotation.outbound":             "Lot odlotowy",
+		"rotation.destination":          "Do",
+		"rotation.departure":            "Odlot",
+		"rotation.ground_time":          "Czas na ziemi (min)",
+		"rotation.aircraft_type":        "Samolot",
+		"rotation.aircraft_owner":       "Operator",
+		"rotation.registration":         "Rejestracja",
+		"rotation.start_date":           "Od dnia",
+		"rotation.end_date":             "Do dnia",
+		"rotation.days":                 "Dni",
+		"rotation.note":                 "Uwagi",
+		"rotation.note.short":           "Za krótki postój",
+		"rotation.note.night_stop":      "Nocleg po przylocie",
+		"rotation.note.first_departure": "Odlot po noclegu",
+		"rotation.sheet":                "Rotacje",
+
+		"connection.origin":          "Z",
+		"connection.hub":             "Port przesiadkowy",
+		"connection.destination":     "Do",
+		"connection.inbound":         "Lot dowozowy",
+		"connection.departure":       "Odlot",
+		"connection.hub_arrival":     "Przylot do portu",
+		"connection.onward":          "Lot dalszy",
+		"connection.hub_departure":   "Odlot z portu",
+		"connection.arrival":         "Przylot",
+		"connection.connection_time": "Przesiadka (min)",
+		"connection.journey_time":    "Czas podróży",
+		"connection.start_date":      "Od dnia",
+		"connection.end_date":        "Do dnia",
+		"connection.days":            "Dni",
+		"connection.sheet":           "Połączenia",
+
+		"blocktime.carrier":       "Przewoźnik",
+		"blocktime.origin":        "Z",
+		"blocktime.destination":   "Do",
+		"blocktime.aircraft_type": "Typ samolotu",
+		"blocktime.season":        "Sezon",
+		"blocktime.operations":    "Operacje",
+		"blocktime.min":           "Min. czas lotu",
+		"blocktime.median":        "Mediana czasu lotu",
+		"blocktime.max":           "Maks. czas lotu",
+		"blocktime.median_change": "Zmiana mediany (min)",
+		"blocktime.sheet":         "Czasy lotu",
+
+		"warning.column.rule":                   "Reguła",
+		"warning.column.flight":                 "Lot",
+		"warning.column.origin":                 "Z",
+		"warning.column.destination":            "Do",
+		"warning.column.start_date":             "Od dnia",
+		"warning.column.end_date":               "Do dnia",
+		"warning.column.message":                "Opis",
+		"warning.sheet":                         "Ostrzeżenia",
+		"warning.title":                         "Ostrzeżenia walidacji",
+		"warning.rule.invalid_period":           "Błędny okres",
+		"warning.rule.arrival_before_departure": "Przylot przed odlotem",
+		"warning.rule.block_time":               "Czas lotu",
+		"warning.rule.unknown_aircraft":         "Nieznany typ samolotu",
+		"warning.rule.duplicate_flight":         "Zdublowany lot",
+		"warning.rule.empty_route":              "Pusta trasa",
+		"warning.rule.unknown_airport":          "Nieznane lotnisko",
+		"warning.invalid_period":                "Okres kończy się przed początkiem",
+		"warning.arrival_before_departure":      "Przylot %s przed odlotem %s bez zmiany dnia",
+		"warning.block_time":                    "Nieprawdopodobny czas lotu %s min, mediana dla pary miast to %s min",
+		"warning.unknown_aircraft":              "Nieznany typ samolotu %s",
+		"warning.duplicate_flight":              "Numer lotu odlatuje więcej niż raz dziennie w %s dniach",
+		"warning.empty_route":                   "Brak lotów na trasie",
+		"warning.unknown_airport":               "Lotniska %s nie ma w danych referencyjnych, jego czasy lokalne nie są sprawdzane",
+
+		"notify.subject": "Zmiany w rozkładzie %s (%s): %d",
+		"notify.body":    "Zmiany między migawkami %d i %d:",
+
+		"error.method_not_allowed": "Niedozwolona metoda",
+		"error.form":               "Błędne dane formularza",
+		"error.template":           "Błędny szablon eksportu: %v",
+		"error.route":              "Błędna trasa: %v",
+		"error.carrier":            "Nieobsługiwany przewoźnik: %q",
+		"error.dates":              "Błędne daty: %v",
+		"error.csv":                "Błąd podczas tworzenia CSV: %v",
+		"error.internal":           "Błąd wewnętrzny: %v",
+		"error.snapshot_not_found": "Nie znaleziono migawki rozkładu",
+		"error.diff":               "Błędne parametry porównania: %v",
+		"error.parameter":          "Błędny parametr: %v",
+		"error.fetch":              "Błąd pobierania rozkładu: %v",
+		"error.not_found":          "Nie znaleziono",
+
+		"page.title":               "Rozkładacz",
+		"page.intro1":              "Celem pobrania rozkładu wybranego przewoźnika w zadanym przedziale czasowym, wybierz odpowiednie pola ponizej.",
+		"page.intro2":              "Na ten moment rozkładacz pozwala na pobranie jednego rozkładu jednej linii w pojedynczym zapytaniu.",
+		"page.intro3":              "Czas pobrania rozkładu wynosi ok. 20-30 sekund i jest ograniczony przez limit API Lufthansy.",
+		"page.loading":             "Pobieranie...",
+		"page.carrier":             "Linia Lotnicza:",
+		"page.date_range":          "Zakres dat:",
+		"page.date_from":           "OD:",
+		"page.date_to":             "DO:",
+		"page.season":              "Lub wybierz sezon:",
+		"page.separate":            "Odseparuj dni rozkładu",
+		"page.exceptions":          "Pokaż odwołane dni zamiast dzielić okresy",
+		"page.template":            "Szablon eksportu:",
+		"page.template_name":       "Nazwa szablonu",
+		"page.template_save":       "Zapisz szablon",
+		"page.format":              "Format pliku:",
+		"page.delimiter":           "Separator",
+		"page.delimiter_auto":      "Domyślny (;)",
+		"page.date_format":         "Format daty",
+		"page.date_format_auto":    "Domyślny (DD.MM.RRRR)",
+		"page.time_mode":           "Czas",
+		"page.time_mode_lt":        "lokalny",
+		"page.time_mode_both":      "lokalny i UTC",
+		"page.day_basis":           "Dni według",
+		"page.day_basis_departure": "odlotu",
+		"page.day_basis_arrival":   "przylotu",
+		"page.download":            "Pobierz rozkład",
+		"page.language":            "Język:",
+		"page.preview":             "Podgląd",
+		"js.date_from_required":    "Proszę wybrać datę początkową",
+		"js.date_to_required":      "Proszę wybrać datę końcową",
+		"js.date_range_invalid":    "Data końcowa nie może być wcześniejsza niż początkowa",
+		"js.download_failed":       "Nie udało się pobrać pliku. Spróbuj ponownie.",
+		"js.download_button":       "POBIERZ ROZKŁAD",
+		"js.template_save_error":   "Nie udało się zapisać szablonu: ",
+		"preview.title":            "Podgląd rozkładu",
+		"preview.carrier":          "Linia",
+		"preview.period":           "Okres",
+		"preview.fetched":          "Pobrano",
+		"preview.shown":            "Pokazano %d z %d rekordów",
+		"preview.empty":            "Brak rekordów",
+		"preview.flight":           "Numer lotu",
+		"preview.weekday":          "Dzień tygodnia",
+		"preview.any":              "Dowolny",
+		"preview.aircraft":         "Samolot",
+		"preview.date":             "Data",
+		"preview.filter":           "Filtruj",
+		"preview.clear":            "Wyczyść",
+		"preview.operations":       "Operacje w dniach tygodnia",
+		"preview.download":         "Pobierz",
+		"preview.capacity":         "Przepustowość (XLSX)",
+		"preview.blocktimes":       "Czasy lotu (XLSX)",
+		"preview.movements":        "Ruch na lotnisku",
+		"preview.airport":          "Lotnisko",
+		"preview.bucket":           "Przedział",
+		"preview.by":               "Według",
+		"preview.show":             "Pokaż",
+		"preview.rotations":        "Rotacje samolotów",
+		"preview.min_turnaround":   "Min. postój (min)",
+		"preview.timeline":         "Siatka rejsów",
+		"preview.color":            "Kolor wg",
+		"preview.back":             "Nowe zapytanie",
+		"weekday.1":                "Pn",
+		"weekday.2":                "Wt",
+		"weekday.3":                "Śr",
+		"weekday.4":                "Cz",
+		"weekday.5":                "Pt",
+		"weekday.6":                "So",
+		"weekday.7":                "Nd",
+	},
+	LocaleEN: {
+		"column.origin":              "From",
+		"column.destination":         "To",
+		"column.airline":             "Airline",
+		"column.flight_number":       "Flight",
+		"column.departure":           "Departure",
+		"column.arrival":             "Arrival",
+		"column.start_date":          "Start date",
+		"column.end_date":            "End date",
+		"column.days":                "Days",
+		"column.aircraft_type":       "Aircraft",
+		"column.operator":            "Operator",
+		"column.service_type":        "Service type",
+		"column.registration":        "Registration",
+		"column.configuration":       "Configuration",
+		"column.operator_name":       "Operator name",
+		"column.alliance":            "Alliance",
+		"column.aircraft_icao":       "Aircraft ICAO",
+		"column.aircraft_family":     "Aircraft family",
+		"column.wake_category":       "Wake category",
+		"column.departure_utc":       "Departure UTC",
+		"column.arrival_utc":         "Arrival UTC",
+		"column.departure_date_diff": "Departure day change",
+		"column.arrival_date_diff":   "Arrival day change",
+		"column.departure_variation": "Departure UTC offset",
+		"column.arrival_variation":   "Arrival UTC offset",
+		"column.exceptions":          "No operations",
+		"column.dst_shift":           "Departure change at DST switch (min)",
+		"column.arrival_dst_shift":   "Arrival change at DST switch (min)",
+
+		"diff.kind":                  "Change",
+		"diff.flight":                "Flight",
+		"diff.origin":                "From",
+		"diff.destination":           "To",
+		"diff.weekday":               "Day",
+		"diff.start_date":            "Start date",
+		"diff.end_date":              "End date",
+		"diff.old":                   "Old",
+		"diff.new":                   "New",
+		"diff.sheet":                 "Changes",
+		"export.sheet":               "Schedule",
+		"diff.kind.added":            "Added",
+		"diff.kind.removed":          "Removed",
+		"diff.kind.retimed":          "Retimed",
+		"diff.kind.aircraft_changed": "Aircraft changed",
+		"diff.kind.operator_changed": "Operator changed",
+		"diff.kind.days_changed":     "Days changed",
+		"diff.kind.period_extended":  "Period extended",
+		"diff.kind.period_shortened": "Period shortened",
+
+		"capacity.carrier":       "Carrier",
+		"capacity.origin":        "From",
+		"capacity.destination":   "To",
+		"capacity.week":          "Week",
+		"capacity.week_start":    "Week start",
+		"capacity.frequencies":   "Frequencies",
+		"capacity.seats":         "Seats",
+		"capacity.unknown_seats": "Operations without seats",
+		"capacity.sheet":         "Capacity",
+
+		"movements.day":        "Day",
+		"movements.kind":       "Movement",
+		"movements.departures": "Departures",
+		"movements.arrivals":   "Arrivals",
+		"movements.total":      "Total",
+		"movements.sheet":      "Movements",
+		"movements.title":      "Movements at %s",
+
+		"timeline.title": "Schedule timeline",
+
+		"rotation.station":              "Station",
+		"rotation.inbound":              "Inbound",
+		"rotation.origin":               "From",
+		"rotation.arrival":              "Arrival",
+		"rotation.outbound":             "Outbound",
+		"rotation.destination":          "To",
+		"rotation.departure":            "Departure",
+		"rotation.ground_time":          "Ground time (min)",
+		"rotation.aircraft_type":        "Aircraft",
+		"rotation.aircraft_owner":       "Operator",
+		"rotation.registration":         "Registration",
+		"rotation.start_date":           "Start date",
+		"rotation.end_date":             "End date",
+		"rotation.days":                 "Days",
+		"rotation.note":                 "Note",
+		"rotation.note.short":           "Turnaround too short",
+		"rotation.note.night_stop":      "Night stop after arrival",
+		"rotation.note.first_departure": "Departure after night stop",
+		"rotation.sheet":                "Rotations",
+
+		"connection.origin":          "From",
+		"connection.hub":             "Hub",
+		"connection.destination":     "To",
+		"connection.inbound":         "Inbound",
+		"connection.departure":       "Departure",
+		"connection.hub_arrival":     "Hub arrival",
+		"connection.onward":          "Onward",
+		"connection.hub_departure":   "Hub departure",
+		"connection.arrival":         "Arrival",
+		"connection.connection_time": "Connection (min)",
+		"connection.journey_time":    "Journey time",
+		"connection.start_date":      "Start date",
+		"connection.end_date":        "End date",
+		"connection.days":            "Days",
+		"connection.sheet":           "Connections",
+
+		"blocktime.carrier":       "Carrier",
+		"blocktime.origin":        "From",
+		"blocktime.destination":   "To",
+		"blocktime.aircraft_type": "Aircraft type",
+		"blocktime.season":        "Season",
+		"blocktime.operations":    "Operations",
+		"blocktime.min":           "Min block time",
+		"blocktime.median":        "Median block time",
+		"blocktime.max":           "Max block time",
+		"blocktime.median_change": "Median change (min)",
+		"blocktime.sheet":         "Block times",
+
+		"warning.column.rule":                   "Rule",
+		"warning.column.flight":                 "Flight",
+		"warning.column.origin":                 "From",
+		"warning.column.destination":            "To",
+		"warning.column.start_date":             "Start date",
+		"warning.column.end_date":               "End date",
+		"warning.column.message":                "Message",
+		"warning.sheet":                         "Warnings",
+		"warning.title":                         "Validation warnings",
+		"warning.rule.invalid_period":           "Invalid period",
+		"warning.rule.arrival_before_departure": "Arrival before departure",
+		"warning.rule.block_time":               "Block time",
+		"warning.rule.unknown_aircraft":         "Unknown aircraft type",
+		"warning.rule.duplicate_flight":         "Duplicate flight",
+		"warning.rule.empty_route":              "Empty route",
+		"warning.rule.unknown_airport":          "Unknown airport",
+		"warning.invalid_period":                "Period ends before it starts",
+		"warning.arrival_before_departure":      "Arrival %s before departure %s without a day change",
+		"warning.block_time":                    "Implausible block time of %s min, the city pair median is %s min",
+		"warning.unknown_aircraft":              "Unknown aircraft type %s",
+		"warning.duplicate_flight":              "Flight number departs more than once a day on %s days",
+		"warning.empty_route":                   "No flights found on the route",
+		"warning.unknown_airport":               "Airport %s is missing from the reference data, its local times are not checked",
+
+		"notify.subject": "%s schedule changes (%s): %d",
+		"notify.body":    "Changes between snapshots %d and %d:",
+
+		"error.method_not_allowed": "Method not allowed",
+		"error.form":               "Invalid form data",
+		"error.template":           "Invalid export template: %v",
+		"error.route":              "Invalid route: %v",
+		"error.carrier":            "Unsupported carrier: %q",
+		"error.dates":              "Invalid dates: %v",
+		"error.csv":                "Error creating CSV: %v",
+		"error.internal":           "Internal error: %v",
+		"error.snapshot_not_found": "Snapshot not found",
+		"error.diff":               "Invalid diff parameters: %v",
+		"error.parameter":          "Invalid parameter: %v",
+		"error.fetch":              "Error fetching schedule: %v",
+		"error.not_found":          "Not found",
+
+		"page.title":               "Schedule Downloader",
+		"page.intro1":              "To download the schedule of a carrier for a given period, fill in the fields below.",
+		"page.intro2":              "Currently a single request downloads the schedule of one carrier.",
+		"page.intro3":              "Downloading takes about 20-30 seconds because of the Lufthansa API rate limit.",
+		"page.loading":             "Downloading...",
+		"page.carrier":             "Carrier:",
+		"page.date_range":          "Date range:",
+		"page.date_from":           "FROM:",
+		"page.date_to":             "TO:",
+		"page.season":              "Or choose a season:",
+		"page.separate":            "Separate days of operation",
+		"page.exceptions":          "List cancelled dates instead of splitting periods",
+		"page.template":            "Export template:",
+		"page.template_name":       "Template name",
+		"page.template_save":       "Save template",
+		"page.format":              "File format:",
+		"page.delimiter":           "Delimiter",
+		"page.delimiter_auto":      "Default (,)",
+		"page.date_format":         "Date format",
+		"page.date_format_auto":    "Default (YYYY-MM-DD)",
+		"page.time_mode":           "Times",
+		"page.time_mode_lt":        "local",
+		"page.time_mode_both":      "local and UTC",
+		"page.day_basis":           "Days by",
+		"page.day_basis_departure": "departure",
+		"page.day_basis_arrival":   "arrival",
+		"page.download":            "Download schedule",
+		"page.language":            "Language:",
+		"page.preview":             "Preview",
+		"js.date_from_required":    "Please choose a start date",
+		"js.date_to_required":      "Please choose an end date",
+		"js.date_range_invalid":    "The end date cannot be before the start date",
+		"js.download_failed":       "Downloading the file failed. Please try again.",
+		"js.download_button":       "DOWNLOAD SCHEDULE",
+		"js.template_save_error":   "Saving the template failed: ",
+		"preview.title":            "Schedule preview",
+		"preview.carrier":          "Carrier",
+		"preview.period":           "Period",
+		"preview.fetched":          "Fetched",
+		"preview.shown":            "Showing %d of %d records",
+		"preview.empty":            "No records",
+		"preview.flight":           "Flight number",
+		"preview.weekday":          "Weekday",
+		"preview.any":              "Any",
+		"preview.aircraft":         "Aircraft",
+		"preview.date":             "Date",
+		"preview.filter":           "Filter",
+		"preview.clear":            "Clear",
+		"preview.operations":       "Operations per weekday",
+		"preview.download":         "Download",
+		"preview.capacity":         "Capacity (XLSX)",
+		"preview.blocktimes":       "Block times (XLSX)",
+		"preview.movements":        "Airport movements",
+		"preview.airport":          "Airport",
+		"preview.bucket":           "Bucket",
+		"preview.by":               "By",
+		"preview.show":             "Show",
+		"preview.rotations":        "Aircraft rotations",
+		"preview.min_turnaround":   "Min. turnaround (min)",
+		"preview.timeline":         "Schedule timeline",
+		"preview.color":            "Colour by",
+		"preview.back":             "New query",
+		"weekday.1":                "Mon",
+		"weekday.2":                "Tue",
+		"weekday.3":                "Wed",
+		"weekday.4":                "Thu",
+		"weekday.5":                "Fri",
+		"weekday.6":                "Sat",
+		"weekday.7":                "Sun",
+	},
+}
@@ -0,0 +1,76 @@
+package internal
+
+import "testing"
+
+func TestParseLocale(t *testing.T) {
+	tests := []struct {
+		name           string
+		option         string
+		acceptLanguage string
+		expected       Locale
+	}{
+		{
+			name:     "Explicit option",
+			option:   "en",
+			expected: LocaleEN,
+		},
+		{
+			name:           "Option wins over header",
+			option:         "PL",
+			acceptLanguage: "en-GB,en;q=0.9",
+			expected:       LocalePL,
+		},
+		{
+			name:           "Header with region",
+			acceptLanguage: "en-US,en;q=0.9",
+			expected:       LocaleEN,
+		},
+		{
+			name:           "Header q-values",
+			acceptLanguage: "en;q=0.5,pl;q=0.8,de",
+			expected:       LocalePL,
+		},
+		{
+			name:           "Unsupported languages",
+			option:         "de",
+			acceptLanguage: "de-DE,fr;q=0.7",
+			expected:       DefaultLocale,
+		},
+		{
+			name:     "Nothing given",
+			expected: DefaultLocale,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := ParseLocale(tt.option, tt.acceptLanguage)
+			if result != tt.expected {
+				t.Errorf("Test %s failed: expected %v, got %v", tt.name, tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestTranslations(t *testing.T) {
+	// Every key has to exist in all locales
+	for _, l := range Locales {
+		for key := range messages[DefaultLocale] {
+			if _, ok := messages[l][key]; !ok {
+				t.Errorf("missing %s translation for %s", l, key)
+			}
+		}
+		for _, c := range Columns {
+			if _, ok := messages[l]["column."+c.Key]; !ok {
+				t.Errorf("missing %s header for column %s", l, c.Key)
+			}
+		}
+	}
+
+	if got := T(LocaleEN, "error.csv", "boom"); got != "Error creating CSV: boom" {
+		t.Errorf("unexpected formatted message: %s", got)
+	}
+	if got := T(LocaleEN, "no.such.key"); got != "no.such.key" {
+		t.Errorf("expected key fallback, got %s", got)
+	}
+}
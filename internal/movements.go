@@ -0,0 +1,227 @@
+package internal
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MovementBuckets are the bucket lengths in minutes a movement profile can
+// be built with.
+var MovementBuckets = []int{15, 30, 60}
+
+// Movements counts the departures and arrivals of a day per time bucket.
+type Movements struct {
+	Departures []int `json:"departures"`
+	Arrivals   []int `json:"arrivals"`
+}
+
+func newMovements(buckets int) Movements {
+	return Movements{Departures: make([]int, buckets), Arrivals: make([]int, buckets)}
+}
+
+func (m Movements) add(other Movements) {
+	for i := range m.Departures {
+		m.Departures[i] += other.Departures[i]
+		m.Arrivals[i] += other.Arrivals[i]
+	}
+}
+
+// Totals returns the departures and arrivals of each bucket together.
+func (m Movements) Totals() []int {
+	totals := make([]int, len(m.Departures))
+	for i := range totals {
+		totals[i] = m.Departures[i] + m.Arrivals[i]
+	}
+	return totals
+}
+
+// DayMovements are the movements on a date.
+type DayMovements struct {
+	Date time.Time `json:"date"`
+	Movements
+}
+
+// WeekdayMovements are the movements on all dates of a weekday.
+type WeekdayMovements struct {
+	Weekday int `json:"weekday"`
+	Movements
+}
+
+// MovementProfile counts the departures from and arrivals at an airport per
+// local time bucket for each date of a period and each weekday.
+type MovementProfile struct {
+	Airport       string    `json:"airport"`
+	BucketMinutes int       `json:"bucket_minutes"`
+	From          time.Time `json:"from"`
+	To            time.Time `json:"to"`
+	// Days lists every date from From to To, also those without movements
+	Days []DayMovements `json:"days"`
+	// Weekdays sums the days of each weekday, Monday first
+	Weekdays []WeekdayMovements `json:"weekdays"`
+}
+
+// BuildMovementProfile expands the records into dated operations and counts
+// their movements at the airport in the period by the local date and time
+// they take place at.
+func BuildMovementProfile(records []ScheduleRecord, airport string, bucketMinutes int, from, to time.Time) (MovementProfile, error) {
+	airport = strings.ToUpper(strings.TrimSpace(airport))
+	if airport == "" {
+		return MovementProfile{}, fmt.Errorf("airport is required")
+	}
+	if !slices.Contains(MovementBuckets, bucketMinutes) {
+		return MovementProfile{}, fmt.Errorf("invalid bucket of %d minutes", bucketMinutes)
+	}
+	if to.Before(from) {
+		return MovementProfile{}, fmt.Errorf("period ends before it starts")
+	}
+	buckets := minutesPerDay / bucketMinutes
+
+	profile := MovementProfile{Airport: airport, BucketMinutes: bucketMinutes, From: from, To: to}
+	index := make(map[time.Time]int)
+	for date := from; !date.After(to); date = date.AddDate(0, 0, 1) {
+		index[date] = len(profile.Days)
+		profile.Days = append(profile.Days, DayMovements{Date: date, Movements: newMovements(buckets)})
+	}
+	count := func(date time.Time, offset int, at TimeOfDay, counts func(Movements) []int) {
+		bucket := int(at) / bucketMinutes
+		if bucket < 0 || bucket >= buckets {
+			return
+		}
+		if i, ok := index[date.AddDate(0, 0, offset)]; ok {
+			counts(profile.Days[i].Movements)[bucket]++
+		}
+	}
+	for _, op := range ExpandRecords(records) {
+		if op.Flight.Origin == airport {
+			count(op.Date, op.Flight.DepartureDateDiff, op.Flight.Departure, func(m Movements) []int { return m.Departures })
+		}
+		if op.Flight.Destination == airport {
+			count(op.Date, op.Flight.ArrivalDateDiff, op.Flight.Arrival, func(m Movements) []int { return m.Arrivals })
+		}
+	}
+
+	for day := 1; day <= 7; day++ {
+		profile.Weekdays = append(profile.Weekdays, WeekdayMovements{Weekday: day, Movements: newMovements(buckets)})
+	}
+	for _, d := range profile.Days {
+		profile.Weekdays[WeekdayOf(d.Date)-1].add(d.Movements)
+	}
+	return profile, nil
+}
+
+// bucketStart returns the local time the bucket starts at.
+func (p MovementProfile) bucketStart(i int) TimeOfDay {
+	return TimeOfDay(i * p.BucketMinutes)
+}
+
+// movementSeries is a labelled row of a profile table or chart.
+type movementSeries struct {
+	label string
+	Movements
+}
+
+// series returns the days of the profile, or its weekdays, labelled in the locale.
+func (p MovementProfile) series(l Locale, dateLayout string, byWeekday bool) []movementSeries {
+	var out []movementSeries
+	if byWeekday {
+		for _, d := range p.Weekdays {
+			out = append(out, movementSeries{T(l, "weekday."+strconv.Itoa(d.Weekday)), d.Movements})
+		}
+		return out
+	}
+	for _, d := range p.Days {
+		out = append(out, movementSeries{d.Date.Format(dateLayout), d.Movements})
+	}
+	return out
+}
+
+// MovementRows renders the profile as a heatmap table with a header row in
+// the locale: a row of departures, arrivals and their total for each day, or
+// each weekday, and a column for each bucket.
+func MovementRows(p MovementProfile, l Locale, dateLayout string, byWeekday bool) [][]string {
+	header := []string{T(l, "movements.day"), T(l, "movements.kind")}
+	for i := range minutesPerDay / p.BucketMinutes {
+		header = append(header, p.bucketStart(i).String())
+	}
+	rows := [][]string{header}
+	for _, s := range p.series(l, dateLayout, byWeekday) {
+		for _, kind := range []struct {
+			key    string
+			counts []int
+		}{{"departures", s.Departures}, {"arrivals", s.Arrivals}, {"total", s.Totals()}} {
+			row := []string{s.label, T(l, "movements."+kind.key)}
+			for _, n := range kind.counts {
+				row = append(row, strconv.Itoa(n))
+			}
+			rows = append(rows, row)
+		}
+	}
+	return rows
+}
+
+// WriteMovementsCSV writes the profile table using the locale and format of opts.
+func WriteMovementsCSV(writer io.Writer, p MovementProfile, byWeekday bool, opts ExportOptions) error {
+	format := opts.format()
+	return writeCSVRows(writer, MovementRows(p, opts.Locale, format.DateLayout, byWeekday), format.Delimiter)
+}
+
+// WriteMovementsXLSX writes the profile table as a spreadsheet.
+func WriteMovementsXLSX(writer io.Writer, p MovementProfile, byWeekday bool, opts ExportOptions) error {
+	return WriteXLSX(writer, T(opts.Locale, "movements.sheet"), MovementRows(p, opts.Locale, opts.format().DateLayout, byWeekday))
+}
+
+// Layout of the movement chart in pixels.
+const (
+	chartLabelWidth = 90
+	chartHeaderSize = 40
+	chartGridWidth  = 768
+	chartRowHeight  = 22
+)
+
+// WriteMovementsSVG draws the total movements of the profile as a heatmap
+// with a row for each day, or each weekday, and a column for each bucket.
+func WriteMovementsSVG(writer io.Writer, p MovementProfile, byWeekday bool, opts ExportOptions) error {
+	series := p.series(opts.Locale, opts.format().DateLayout, byWeekday)
+	buckets := minutesPerDay / p.BucketMinutes
+	cellWidth := float64(chartGridWidth) / float64(buckets)
+	width := chartLabelWidth + chartGridWidth + 10
+	height := chartHeaderSize + len(series)*chartRowHeight + 10
+
+	peak := 0
+	for _, s := range series {
+		for _, n := range s.Totals() {
+			peak = max(peak, n)
+		}
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" font-family="sans-serif" font-size="11">`, width, height, width, height)
+	title := xmlEscape(T(opts.Locale, "movements.title", p.Airport))
+	fmt.Fprintf(&b, `<title>%s</title><text x="0" y="14" font-size="13" font-weight="bold">%s</text>`, title, title)
+	// Hour labels above the grid
+	for i := 0; i < buckets; i += 60 / p.BucketMinutes {
+		if hour := i * p.BucketMinutes / 60; hour%2 == 0 {
+			fmt.Fprintf(&b, `<text x="%.1f" y="%d">%02d</text>`, chartLabelWidth+float64(i)*cellWidth, chartHeaderSize-6, hour)
+		}
+	}
+	for row, s := range series {
+		y := chartHeaderSize + row*chartRowHeight
+		fmt.Fprintf(&b, `<text x="0" y="%d">%s</text>`, y+chartRowHeight-7, xmlEscape(s.label))
+		for i, n := range s.Totals() {
+			opacity := 0.0
+			if peak > 0 {
+				opacity = float64(n) / float64(peak)
+			}
+			fmt.Fprintf(&b, `<rect x="%.1f" y="%d" width="%.1f" height="%d" fill="#3f4e7f" fill-opacity="%.2f" stroke="#e5e7eb"><title>%s %s: %d (%d/%d)</title></rect>`,
+				chartLabelWidth+float64(i)*cellWidth, y, cellWidth, chartRowHeight, opacity,
+				xmlEscape(s.label), p.bucketStart(i), n, s.Departures[i], s.Arrivals[i])
+		}
+	}
+	b.WriteString(`</svg>`)
+	_, err := io.WriteString(writer, b.String())
+	return err
+}
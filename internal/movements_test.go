@@ -0,0 +1,145 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBuildMovementProfile(t *testing.T) {
+	date := func(s string) time.Time {
+		d, _ := time.Parse(dateLayout, s)
+		return d
+	}
+	records := []ScheduleRecord{
+		// Departs KRK at 10:20 on Mon, Wed, Fri
+		testRecord("KRK", "FRA", "LH", "1365", "10:20", "12:05", "2025-03-30", "2025-04-26", "1.3.5..", "32N", "LH", "J"),
+		// Arrives at KRK at 09:40 daily
+		testRecord("FRA", "KRK", "LH", "1364", "08:05", "09:40", "2025-03-30", "2025-04-26", "1234567", "32N", "LH", "J"),
+		// Departs at 23:50 and lands at KRK the next day
+		testRecord("MUC", "KRK", "LH", "1620", "23:50", "00:55", "2025-04-01", "2025-04-01", ".2.....", "E95", "CL", "J"),
+		// Does not touch KRK
+		testRecord("MUC", "FRA", "LH", "100", "07:00", "08:00", "2025-03-30", "2025-04-26", "1234567", "320", "LH", "J"),
+	}
+	records[2].ArrivalDateDiff = 1
+
+	profile, err := BuildMovementProfile(records, "krk", 60, date("2025-03-31"), date("2025-04-06"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if profile.Airport != "KRK" || len(profile.Days) != 7 || len(profile.Days[0].Departures) != 24 {
+		t.Fatalf("unexpected profile %+v", profile)
+	}
+
+	tests := []struct {
+		name       string
+		movements  Movements
+		departures map[int]int
+		arrivals   map[int]int
+	}{
+		{name: "Monday", movements: profile.Days[0].Movements, departures: map[int]int{10: 1}, arrivals: map[int]int{9: 1}},
+		{name: "Tuesday", movements: profile.Days[1].Movements, arrivals: map[int]int{9: 1}},
+		{name: "Wednesday with the overnight arrival", movements: profile.Days[2].Movements, departures: map[int]int{10: 1}, arrivals: map[int]int{0: 1, 9: 1}},
+		{name: "Weekday sums", movements: profile.Weekdays[2].Movements, departures: map[int]int{10: 1}, arrivals: map[int]int{0: 1, 9: 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for hour := range 24 {
+				if got := tt.movements.Departures[hour]; got != tt.departures[hour] {
+					t.Errorf("Test %s failed: expected %d departures at %02d, got %d", tt.name, tt.departures[hour], hour, got)
+				}
+				if got := tt.movements.Arrivals[hour]; got != tt.arrivals[hour] {
+					t.Errorf("Test %s failed: expected %d arrivals at %02d, got %d", tt.name, tt.arrivals[hour], hour, got)
+				}
+			}
+		})
+	}
+
+	quarters, err := BuildMovementProfile(records, "KRK", 15, date("2025-03-31"), date("2025-03-31"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// 09:40 falls in the 09:30 bucket, 10:20 in the 10:15 one
+	if quarters.Days[0].Arrivals[38] != 1 || quarters.Days[0].Departures[41] != 1 {
+		t.Errorf("unexpected quarter hour buckets %+v", quarters.Days[0])
+	}
+
+	// A time past midnight in the data is skipped instead of overflowing the buckets
+	late := testRecord("KRK", "FRA", "LH", "1367", "10:20", "12:05", "2025-03-31", "2025-03-31", "1......", "32N", "LH", "J")
+	late.Departure = minutesPerDay + 30
+	skipped, err := BuildMovementProfile([]ScheduleRecord{late}, "KRK", 60, date("2025-03-31"), date("2025-03-31"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for hour, got := range skipped.Days[0].Departures {
+		if got != 0 {
+			t.Errorf("expected no departures at %02d, got %d", hour, got)
+		}
+	}
+
+	if _, err := BuildMovementProfile(records, "KRK", 45, date("2025-03-31"), date("2025-04-06")); err == nil {
+		t.Errorf("expected error for a 45 minute bucket")
+	}
+	if _, err := BuildMovementProfile(records, "", 60, date("2025-03-31"), date("2025-04-06")); err == nil {
+		t.Errorf("expected error without airport")
+	}
+}
+
+func TestMovementRows(t *testing.T) {
+	date, _ := time.Parse(dateLayout, "2025-03-31")
+	records := []ScheduleRecord{testRecord("KRK", "FRA", "LH", "1365", "10:20", "12:05", "2025-03-31", "2025-03-31", "1......", "32N", "LH", "J")}
+	profile, err := BuildMovementProfile(records, "KRK", 60, date, date)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	rows := MovementRows(profile, LocaleEN, dateLayout, true)
+	if len(rows) != 1+7*3 || len(rows[0]) != 2+24 || rows[0][2] != "00:00" || rows[0][25] != "23:00" {
+		t.Fatalf("unexpected table shape %v", rows[0])
+	}
+	expected := []string{"Mon", "Departures", "0", "0", "0", "0", "0", "0", "0", "0", "0", "0", "1"}
+	if !slices.Equal(rows[1][:13], expected) {
+		t.Errorf("expected %v, got %v", expected, rows[1][:13])
+	}
+	if rows[3][1] != "Total" || rows[3][12] != "1" {
+		t.Errorf("unexpected total row %v", rows[3])
+	}
+
+	if rows := MovementRows(profile, LocalePL, dateLayout, false); len(rows) != 1+3 || rows[1][0] != "2025-03-31" {
+		t.Errorf("unexpected daily table %v", rows)
+	}
+}
+
+func TestWriteMovementsSVG(t *testing.T) {
+	date, _ := time.Parse(dateLayout, "2025-03-31")
+	records := []ScheduleRecord{testRecord("KRK", "FRA", "LH", "1365", "10:20", "12:05", "2025-03-31", "2025-04-06", "1234567", "32N", "LH", "J")}
+	profile, err := BuildMovementProfile(records, "KRK", 30, date, date.AddDate(0, 0, 6))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteMovementsSVG(&buf, profile, true, ExportOptions{Locale: LocaleEN}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	svg := buf.String()
+	decoder := xml.NewDecoder(strings.NewReader(svg))
+	for {
+		if _, err := decoder.Token(); err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatalf("invalid SVG: %v", err)
+		}
+	}
+	if cells := strings.Count(svg, "<rect"); cells != 7*48 {
+		t.Errorf("expected %d cells, got %d", 7*48, cells)
+	}
+	if !strings.Contains(svg, "Movements at KRK") || !strings.Contains(svg, `fill-opacity="1.00" stroke="#e5e7eb"><title>Mon 10:00: 1 (1/0)</title>`) {
+		t.Errorf("unexpected chart %.300s", svg)
+	}
+}
@@ -0,0 +1,106 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// Notification reports the changes found by a scheduled fetch.
+type Notification struct {
+	Job          string   `json:"job"`
+	Carrier      string   `json:"carrier"`
+	FromSnapshot uint64   `json:"from_snapshot"`
+	ToSnapshot   uint64   `json:"to_snapshot"`
+	Changes      []Change `json:"changes"`
+	Locale       Locale   `json:"-"`
+}
+
+// Notifier delivers notifications, e.g. by email or to a webhook.
+type Notifier interface {
+	Notify(ctx context.Context, n Notification) error
+}
+
+// WebhookNotifier posts notifications as JSON to a URL.
+type WebhookNotifier struct {
+	URL string `json:"url"`
+	// Headers are added to every request, e.g. an authorization token
+	Headers map[string]string `json:"headers,omitempty"`
+
+	Client *http.Client `json:"-"`
+}
+
+func (wh WebhookNotifier) Notify(ctx context.Context, n Notification) error {
+	body, err := json.Marshal(n)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, wh.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range wh.Headers {
+		req.Header.Set(k, v)
+	}
+
+	client := wh.Client
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("webhook %s: %w", wh.URL, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook %s: unexpected status %s", wh.URL, resp.Status)
+	}
+	return nil
+}
+
+// SMTPNotifier emails notifications with the changes report in the body.
+type SMTPNotifier struct {
+	// Addr of the server as host:port
+	Addr     string   `json:"addr"`
+	From     string   `json:"from"`
+	To       []string `json:"to"`
+	Username string   `json:"username,omitempty"`
+	Password string   `json:"password,omitempty"`
+}
+
+func (m SMTPNotifier) Notify(_ context.Context, n Notification) error {
+	var auth smtp.Auth
+	if m.Username != "" {
+		host, _, _ := strings.Cut(m.Addr, ":")
+		auth = smtp.PlainAuth("", m.Username, m.Password, host)
+	}
+	if err := smtp.SendMail(m.Addr, auth, m.From, m.To, m.message(n)); err != nil {
+		return fmt.Errorf("smtp %s: %w", m.Addr, err)
+	}
+	return nil
+}
+
+func (m SMTPNotifier) message(n Notification) []byte {
+	subject := T(n.Locale, "notify.subject", n.Carrier, n.Job, len(n.Changes))
+
+	var report bytes.Buffer
+	writeCSVRows(&report, ChangeRows(n.Changes, n.Locale, n.Locale.Format().DateLayout), n.Locale.Format().Delimiter)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", m.From)
+	fmt.Fprintf(&b, "To: %s\r\n", strings.Join(m.To, ", "))
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	b.WriteString(T(n.Locale, "notify.body", n.FromSnapshot, n.ToSnapshot))
+	b.WriteString("\r\n\r\n")
+	b.WriteString(strings.ReplaceAll(report.String(), "\n", "\r\n"))
+	return []byte(b.String())
+}
@@ -0,0 +1,127 @@
+package internal
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testNotification() Notification {
+	return Notification{
+		Job:          "krk",
+		Carrier:      "LH",
+		FromSnapshot: 1,
+		ToSnapshot:   2,
+		Locale:       LocaleEN,
+		Changes: []Change{
+			{Kind: ChangeAdded, Route: Route{Airline: "LH", FlightNumber: 1371, Origin: "KRK", Destination: "VIE"}, Weekday: 5,
+				StartDate: time.Date(2025, 4, 4, 0, 0, 0, 0, time.UTC), EndDate: time.Date(2025, 4, 25, 0, 0, 0, 0, time.UTC)},
+		},
+	}
+}
+
+func TestWebhookNotifier(t *testing.T) {
+	var received Notification
+	var token string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token = r.Header.Get("Authorization")
+		json.NewDecoder(r.Body).Decode(&received)
+	}))
+	defer server.Close()
+
+	notifier := WebhookNotifier{URL: server.URL, Headers: map[string]string{"Authorization": "Bearer secret"}}
+	if err := notifier.Notify(context.Background(), testNotification()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if token != "Bearer secret" {
+		t.Errorf("expected configured header, got %q", token)
+	}
+	if received.Job != "krk" || len(received.Changes) != 1 || received.Changes[0].FlightNumber != 1371 {
+		t.Errorf("unexpected payload %+v", received)
+	}
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer failing.Close()
+	if err := (WebhookNotifier{URL: failing.URL}).Notify(context.Background(), testNotification()); err == nil {
+		t.Errorf("expected error for failing webhook")
+	}
+}
+
+// fakeSMTPServer accepts a single message and sends its data on the channel.
+func fakeSMTPServer(t *testing.T) (string, <-chan string) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	messages := make(chan string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		reply := func(s string) { conn.Write([]byte(s + "\r\n")) }
+
+		reply("220 localhost ready")
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			switch cmd := strings.ToUpper(strings.TrimSpace(line)); {
+			case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+				reply("250 localhost")
+			case cmd == "DATA":
+				reply("354 send data")
+				var data strings.Builder
+				for {
+					l, err := r.ReadString('\n')
+					if err != nil || l == ".\r\n" {
+						break
+					}
+					data.WriteString(l)
+				}
+				messages <- data.String()
+				reply("250 queued")
+			case cmd == "QUIT":
+				reply("221 bye")
+				return
+			default:
+				reply("250 ok")
+			}
+		}
+	}()
+	return ln.Addr().String(), messages
+}
+
+func TestSMTPNotifier(t *testing.T) {
+	addr, messages := fakeSMTPServer(t)
+	notifier := SMTPNotifier{Addr: addr, From: "goro@example.com", To: []string{"ops@example.com"}}
+	if err := notifier.Notify(context.Background(), testNotification()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	msg := <-messages
+	for _, want := range []string{
+		"To: ops@example.com",
+		"Subject: LH schedule changes (krk): 1",
+		"Changes between snapshots 1 and 2:",
+		"Added,LH1371,KRK,VIE,5,2025-04-04,2025-04-25,,",
+	} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("expected %q in message:\n%s", want, msg)
+		}
+	}
+}
@@ -0,0 +1,280 @@
+package internal
+
+import (
+	"cmp"
+	"slices"
+	"time"
+)
+
+// Operation is a single dated flight.
+type Operation struct {
+	Flight Flight
+	Date   time.Time
+}
+
+// NormalizeOptions controls how operations are compressed back into records.
+type NormalizeOptions struct {
+	// Separate keeps one record per weekday instead of combining weekdays.
+	Separate bool
+	// Exceptions bridges gaps in a weekly series and lists the missing dates
+	// on the record instead of starting a new record after every gap.
+	Exceptions bool
+	// MergeDST compresses periods with the same local times on both sides of
+	// a daylight saving time switch into one record. Only meaningful for
+	// local times, such records are marked as MergedDST.
+	MergeDST bool
+}
+
+// ExpandRecords lists every dated operation described by the records.
+// Overlapping records describing the same flight on the same date yield one operation.
+func ExpandRecords(records []ScheduleRecord) []Operation {
+	var operations []Operation
+	seen := make(map[Operation]bool)
+
+	for _, r := range records {
+		for date := r.StartDate; !date.After(r.EndDate); date = date.AddDate(0, 0, 1) {
+			if !r.OperatesOn(date) {
+				continue
+			}
+			op := Operation{Flight: r.Flight, Date: date}
+			if seen[op] {
+				continue
+			}
+			seen[op] = true
+			operations = append(operations, op)
+		}
+	}
+	return operations
+}
+
+// CompressOperations rebuilds the smallest set of records describing the
+// operations. Each weekday of a flight becomes a record per run of
+// consecutive weeks, any gap starts a new record unless exceptions are
+// reported, then a single record spans the weekday series. Without
+// opts.Separate weekday records covering the same weeks are combined into
+// multi-day records, with exceptions reported also those missing a single
+// week at either end.
+func CompressOperations(operations []Operation, opts NormalizeOptions) []ScheduleRecord {
+	var keys []Flight
+	datesByKey := make(map[Flight][]time.Time)
+	// Flight of the earliest operation of every key
+	flightByKey := make(map[Flight]Operation)
+	// Operations whose UTC times differ from those of the earliest one
+	var utcChanges []Operation
+	for _, op := range operations {
+		key := op.Flight
+		if opts.MergeDST {
+			if k, ok := dstNeutralFlight(op); ok {
+				key = k
+			}
+		}
+		if first, ok := flightByKey[key]; !ok {
+			keys = append(keys, key)
+			flightByKey[key] = op
+		} else if op.Date.Before(first.Date) {
+			flightByKey[key] = op
+		}
+		datesByKey[key] = append(datesByKey[key], op.Date)
+	}
+	if opts.MergeDST {
+		for _, op := range operations {
+			key, ok := dstNeutralFlight(op)
+			if ok && flightByKey[key].Flight != op.Flight {
+				utcChanges = append(utcChanges, Operation{Flight: key, Date: op.Date})
+			}
+		}
+	}
+
+	var records []ScheduleRecord
+	for _, key := range keys {
+		flight := flightByKey[key].Flight
+		dates := datesByKey[key]
+		slices.SortFunc(dates, func(a, b time.Time) int { return a.Compare(b) })
+
+		var runs []ScheduleRecord
+		for day := 1; day <= 7; day++ {
+			runs = append(runs, weeklyRuns(flight, day, dates, opts.Exceptions)...)
+		}
+		SortRecordsByStartDate(runs)
+		if !opts.Separate {
+			runs = MergeDays(runs)
+			if opts.Exceptions {
+				runs = bridgeDays(runs)
+			}
+		}
+		for i := range runs {
+			runs[i].MergedDST = slices.ContainsFunc(utcChanges, func(op Operation) bool {
+				return op.Flight == key && runs[i].OperatesOn(op.Date)
+			})
+		}
+		records = append(records, runs...)
+	}
+
+	sortRecords(records)
+	markDSTShifts(records)
+	return records
+}
+
+// dstNeutralFlight returns the flight without its UTC times and offsets when
+// the offsets are the ones of the airport time zones on the date, so they
+// only differ between operations because of daylight saving time.
+func dstNeutralFlight(op Operation) (Flight, bool) {
+	f := op.Flight
+	departure := op.Date.AddDate(0, 0, f.DepartureDateDiff)
+	if CheckVariation(f.Origin, departure, f.Departure, f.DepartureVariation) != nil {
+		return Flight{}, false
+	}
+	arrival := op.Date.AddDate(0, 0, f.ArrivalDateDiff)
+	if CheckVariation(f.Destination, arrival, f.Arrival, f.ArrivalVariation) != nil {
+		return Flight{}, false
+	}
+	f.DepartureUTC, f.ArrivalUTC = 0, 0
+	f.DepartureUTCDateDiff, f.ArrivalUTCDateDiff = 0, 0
+	f.DepartureVariation, f.ArrivalVariation = 0, 0
+	return f, true
+}
+
+// markDSTShifts sets DSTShift and ArrivalDSTShift on records whose local
+// departure or arrival differs from the previous period of the same flight
+// when a daylight saving time switch at the origin or destination lies
+// between them. records have to be sorted by start date.
+func markDSTShifts(records []ScheduleRecord) {
+	for i := range records {
+		r := &records[i]
+		for j := i - 1; j >= 0; j-- {
+			prev := records[j]
+			if !sameRoute(prev, *r) || !prev.EndDate.Before(r.StartDate) || prev.Days.Intersect(r.Days).IsEmpty() {
+				continue
+			}
+			if crossesDST(prev.Origin, prev.EndDate, r.StartDate) || crossesDST(prev.Destination, prev.EndDate, r.StartDate) {
+				r.DSTShift = wrapMinutes(int(r.Departure) - int(prev.Departure))
+				r.ArrivalDSTShift = wrapMinutes(int(r.Arrival) - int(prev.Arrival))
+			}
+			break
+		}
+	}
+}
+
+// sameRoute reports whether both records are the same flight number on the same route.
+func sameRoute(a, b ScheduleRecord) bool {
+	return a.Airline == b.Airline && a.FlightNumber == b.FlightNumber && a.Suffix == b.Suffix &&
+		a.Origin == b.Origin && a.Destination == b.Destination
+}
+
+// crossesDST reports whether the UTC offset of the airport changes after
+// from and up to to. Unknown airports never do.
+func crossesDST(code string, from, to time.Time) bool {
+	transitions, err := DSTTransitions(code, from.AddDate(0, 0, 1), to)
+	return err == nil && len(transitions) > 0
+}
+
+// wrapMinutes brings a difference of times of day into the -12h..+12h range.
+func wrapMinutes(m int) int {
+	m = ((m % minutesPerDay) + minutesPerDay) % minutesPerDay
+	if m > minutesPerDay/2 {
+		m -= minutesPerDay
+	}
+	return m
+}
+
+// weeklyRuns builds a record for every run of consecutive weeks the flight
+// operates on the given weekday. When bridge is set a single record spans
+// all the dates and the skipped weeks become its exceptions.
+// dates have to be sorted.
+func weeklyRuns(flight Flight, day int, dates []time.Time, bridge bool) []ScheduleRecord {
+	var runs []ScheduleRecord
+	var current ScheduleRecord
+	open := false
+
+	for _, date := range dates {
+		if WeekdayOf(date) != day {
+			continue
+		}
+		if open && bridge {
+			for missing := current.EndDate.AddDate(0, 0, 7); missing.Before(date); missing = missing.AddDate(0, 0, 7) {
+				current.Exceptions = append(current.Exceptions, missing)
+			}
+			current.EndDate = date
+			continue
+		}
+		if open && current.EndDate.AddDate(0, 0, 7).Equal(date) {
+			current.EndDate = date
+			continue
+		}
+		if open {
+			runs = append(runs, current)
+		}
+		current = ScheduleRecord{Flight: flight, StartDate: date, EndDate: date, Days: NewWeekdays(day)}
+		open = true
+	}
+	if open {
+		runs = append(runs, current)
+	}
+	return runs
+}
+
+// bridgeDays combines records of the same flight on different weekdays whose
+// periods differ by at most one week of each weekday at either end, the
+// dates a record misses within the combined period become its exceptions.
+// Records further apart keep a different weekly pattern and stay apart.
+func bridgeDays(records []ScheduleRecord) []ScheduleRecord {
+	var merged []ScheduleRecord
+	for _, r := range records {
+		combined := false
+		for i := range merged {
+			if m, ok := bridgeRecords(merged[i], r); ok {
+				merged[i] = m
+				combined = true
+				break
+			}
+		}
+		if !combined {
+			merged = append(merged, r)
+		}
+	}
+	return merged
+}
+
+func bridgeRecords(a, b ScheduleRecord) (ScheduleRecord, bool) {
+	if !a.sameFlight(b) || !a.Days.Intersect(b.Days).IsEmpty() {
+		return ScheduleRecord{}, false
+	}
+	merged := PerformDayMerge(a, b)
+	merged.Exceptions = slices.Clone(merged.Exceptions)
+	for _, r := range []ScheduleRecord{a, b} {
+		for date := merged.StartDate; date.Before(r.StartDate); date = date.AddDate(0, 0, 1) {
+			if !r.Days.OperatesOn(date) {
+				continue
+			}
+			if date.AddDate(0, 0, 7).Before(r.StartDate) {
+				return ScheduleRecord{}, false
+			}
+			merged.Exceptions = append(merged.Exceptions, date)
+		}
+		for date := merged.EndDate; date.After(r.EndDate); date = date.AddDate(0, 0, -1) {
+			if !r.Days.OperatesOn(date) {
+				continue
+			}
+			if date.AddDate(0, 0, -7).After(r.EndDate) {
+				return ScheduleRecord{}, false
+			}
+			merged.Exceptions = append(merged.Exceptions, date)
+		}
+	}
+	slices.SortFunc(merged.Exceptions, func(a, b time.Time) int { return a.Compare(b) })
+	return merged, true
+}
+
+// sortRecords orders records by start date, then flight and weekdays so the
+// output does not depend on the order of the API responses.
+func sortRecords(records []ScheduleRecord) {
+	slices.SortStableFunc(records, func(a, b ScheduleRecord) int {
+		return cmp.Or(
+			a.StartDate.Compare(b.StartDate),
+			cmp.Compare(a.Airline, b.Airline),
+			cmp.Compare(a.FlightNumber, b.FlightNumber),
+			cmp.Compare(a.Origin, b.Origin),
+			cmp.Compare(a.Days, b.Days),
+		)
+	})
+}
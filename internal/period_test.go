@@ -0,0 +1,288 @@
+package internal
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestExpandRecords(t *testing.T) {
+	records := []ScheduleRecord{
+		testRecord("KRK", "FRA", "LH", "1365", "10:20", "12:05", "2024-01-01", "2024-01-14", "1.3....", "320", "DLH", "J"),
+		// Overlaps the first record on 2024-01-08 and 2024-01-10
+		testRecord("KRK", "FRA", "LH", "1365", "10:20", "12:05", "2024-01-08", "2024-01-15", "1.3....", "320", "DLH", "J"),
+	}
+
+	operations := ExpandRecords(records)
+
+	expectedDates := []string{"2024-01-01", "2024-01-03", "2024-01-08", "2024-01-10", "2024-01-15"}
+	if len(operations) != len(expectedDates) {
+		t.Fatalf("expected %d operations, got %v", len(expectedDates), operations)
+	}
+	for i, op := range operations {
+		if got := op.Date.Format(dateLayout); got != expectedDates[i] {
+			t.Errorf("operation %d: expected %s, got %s", i, expectedDates[i], got)
+		}
+		if op.Flight != records[0].Flight {
+			t.Errorf("operation %d: unexpected flight %v", i, op.Flight)
+		}
+	}
+}
+
+func TestNormalizeRecords(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    []ScheduleRecord
+		separate bool
+		expected []ScheduleRecord
+	}{
+		{
+			name: "Consecutive periods are joined",
+			input: []ScheduleRecord{
+				testRecord("KRK", "FRA", "LH", "123", "02:00", "02:30", "2024-01-01", "2024-01-07", "1234567", "A320", "OAW", "Regular"),
+				testRecord("KRK", "FRA", "LH", "123", "02:00", "02:30", "2024-01-08", "2024-01-14", "1234567", "A320", "OAW", "Regular"),
+			},
+			expected: []ScheduleRecord{
+				testRecord("KRK", "FRA", "LH", "123", "02:00", "02:30", "2024-01-01", "2024-01-14", "1234567", "A320", "OAW", "Regular"),
+			},
+		},
+		{
+			name: "Consecutive periods separated per weekday",
+			input: []ScheduleRecord{
+				testRecord("KRK", "FRA", "LH", "123", "02:00", "02:30", "2024-01-01", "2024-01-07", "1.3....", "A320", "OAW", "Regular"),
+				testRecord("KRK", "FRA", "LH", "123", "02:00", "02:30", "2024-01-08", "2024-01-21", "1.3....", "A320", "OAW", "Regular"),
+			},
+			separate: true,
+			expected: []ScheduleRecord{
+				testRecord("KRK", "FRA", "LH", "123", "02:00", "02:30", "2024-01-01", "2024-01-15", "1......", "A320", "OAW", "Regular"),
+				testRecord("KRK", "FRA", "LH", "123", "02:00", "02:30", "2024-01-03", "2024-01-17", "..3....", "A320", "OAW", "Regular"),
+			},
+		},
+		{
+			name: "Gap between periods",
+			input: []ScheduleRecord{
+				testRecord("KRK", "FRA", "LH", "123", "02:00", "02:30", "2024-01-01", "2024-01-07", "1234567", "A320", "OAW", "Regular"),
+				testRecord("KRK", "FRA", "LH", "123", "02:00", "02:30", "2024-01-15", "2024-01-21", "1234567", "A320", "OAW", "Regular"),
+			},
+			expected: []ScheduleRecord{
+				testRecord("KRK", "FRA", "LH", "123", "02:00", "02:30", "2024-01-01", "2024-01-07", "1234567", "A320", "OAW", "Regular"),
+				testRecord("KRK", "FRA", "LH", "123", "02:00", "02:30", "2024-01-15", "2024-01-21", "1234567", "A320", "OAW", "Regular"),
+			},
+		},
+		{
+			name: "Overlapping input",
+			input: []ScheduleRecord{
+				testRecord("KRK", "FRA", "LH", "123", "02:00", "02:30", "2024-01-01", "2024-01-14", "1234567", "A320", "OAW", "Regular"),
+				testRecord("KRK", "FRA", "LH", "123", "02:00", "02:30", "2024-01-08", "2024-01-21", "1234567", "A320", "OAW", "Regular"),
+			},
+			expected: []ScheduleRecord{
+				testRecord("KRK", "FRA", "LH", "123", "02:00", "02:30", "2024-01-01", "2024-01-21", "1234567", "A320", "OAW", "Regular"),
+			},
+		},
+		{
+			name: "Single cancelled day",
+			input: []ScheduleRecord{
+				testRecord("KRK", "FRA", "LH", "123", "02:00", "02:30", "2024-01-01", "2024-01-09", "1234567", "A320", "OAW", "Regular"),
+				testRecord("KRK", "FRA", "LH", "123", "02:00", "02:30", "2024-01-11", "2024-01-21", "1234567", "A320", "OAW", "Regular"),
+			},
+			expected: []ScheduleRecord{
+				testRecord("KRK", "FRA", "LH", "123", "02:00", "02:30", "2024-01-01", "2024-01-21", "12.4567", "A320", "OAW", "Regular"),
+				testRecord("KRK", "FRA", "LH", "123", "02:00", "02:30", "2024-01-03", "2024-01-03", "..3....", "A320", "OAW", "Regular"),
+				testRecord("KRK", "FRA", "LH", "123", "02:00", "02:30", "2024-01-17", "2024-01-17", "..3....", "A320", "OAW", "Regular"),
+			},
+		},
+		{
+			name: "Different flights stay apart",
+			input: []ScheduleRecord{
+				testRecord("KRK", "FRA", "LH", "123", "02:00", "02:30", "2024-01-01", "2024-01-31", "1234567", "A320", "OAW", "Regular"),
+				testRecord("MUC", "ZRH", "LX", "456", "03:00", "03:30", "2024-02-01", "2024-02-28", "1.3.5.7", "A321", "SWR", "VIP"),
+			},
+			expected: []ScheduleRecord{
+				testRecord("KRK", "FRA", "LH", "123", "02:00", "02:30", "2024-01-01", "2024-01-31", "1234567", "A320", "OAW", "Regular"),
+				testRecord("MUC", "ZRH", "LX", "456", "03:00", "03:30", "2024-02-02", "2024-02-28", "1.3.5.7", "A321", "SWR", "VIP"),
+			},
+		},
+		{
+			name:     "No records",
+			input:    nil,
+			expected: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := NormalizeRecords(tt.input, NormalizeOptions{Separate: tt.separate})
+			if !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("Test %s failed:\nexpected: %v\ngot: %v", tt.name, tt.expected, result)
+			}
+		})
+	}
+}
+
+// withExceptions returns r with the given exception dates.
+func withExceptions(r ScheduleRecord, dates ...string) ScheduleRecord {
+	for _, d := range dates {
+		date, _ := time.Parse(dateLayout, d)
+		r.Exceptions = append(r.Exceptions, date)
+	}
+	return r
+}
+
+func TestNormalizeRecordsExceptions(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    []ScheduleRecord
+		separate bool
+		expected []ScheduleRecord
+	}{
+		{
+			name: "Single cancelled day",
+			input: []ScheduleRecord{
+				testRecord("KRK", "FRA", "LH", "123", "02:00", "02:30", "2024-01-01", "2024-01-09", "1234567", "A320", "OAW", "Regular"),
+				testRecord("KRK", "FRA", "LH", "123", "02:00", "02:30", "2024-01-11", "2024-01-21", "1234567", "A320", "OAW", "Regular"),
+			},
+			expected: []ScheduleRecord{
+				withExceptions(testRecord("KRK", "FRA", "LH", "123", "02:00", "02:30", "2024-01-01", "2024-01-21", "1234567", "A320", "OAW", "Regular"), "2024-01-10"),
+			},
+		},
+		{
+			name: "Cancelled weeks separated per weekday",
+			input: []ScheduleRecord{
+				testRecord("KRK", "FRA", "LH", "123", "02:00", "02:30", "2024-01-01", "2024-01-10", "1.3....", "A320", "OAW", "Regular"),
+				testRecord("KRK", "FRA", "LH", "123", "02:00", "02:30", "2024-01-22", "2024-01-31", "1.3....", "A320", "OAW", "Regular"),
+			},
+			separate: true,
+			expected: []ScheduleRecord{
+				withExceptions(testRecord("KRK", "FRA", "LH", "123", "02:00", "02:30", "2024-01-01", "2024-01-29", "1......", "A320", "OAW", "Regular"), "2024-01-15"),
+				withExceptions(testRecord("KRK", "FRA", "LH", "123", "02:00", "02:30", "2024-01-03", "2024-01-31", "..3....", "A320", "OAW", "Regular"), "2024-01-17"),
+			},
+		},
+		{
+			name: "Disjoint weekday patterns",
+			input: []ScheduleRecord{
+				testRecord("KRK", "FRA", "LH", "123", "02:00", "02:30", "2025-04-07", "2025-04-27", "12345..", "A320", "OAW", "Regular"),
+				testRecord("KRK", "FRA", "LH", "123", "02:00", "02:30", "2025-05-03", "2025-05-25", ".....67", "A320", "OAW", "Regular"),
+			},
+			expected: []ScheduleRecord{
+				testRecord("KRK", "FRA", "LH", "123", "02:00", "02:30", "2025-04-07", "2025-04-25", "12345..", "A320", "OAW", "Regular"),
+				testRecord("KRK", "FRA", "LH", "123", "02:00", "02:30", "2025-05-03", "2025-05-25", ".....67", "A320", "OAW", "Regular"),
+			},
+		},
+		{
+			name: "Cancelled week of one weekday",
+			input: []ScheduleRecord{
+				testRecord("KRK", "FRA", "LH", "123", "02:00", "02:30", "2025-04-07", "2025-04-13", "12345..", "A320", "OAW", "Regular"),
+				testRecord("KRK", "FRA", "LH", "123", "02:00", "02:30", "2025-04-14", "2025-04-20", ".2345..", "A320", "OAW", "Regular"),
+				testRecord("KRK", "FRA", "LH", "123", "02:00", "02:30", "2025-04-21", "2025-04-27", "12345..", "A320", "OAW", "Regular"),
+			},
+			expected: []ScheduleRecord{
+				withExceptions(testRecord("KRK", "FRA", "LH", "123", "02:00", "02:30", "2025-04-07", "2025-04-25", "12345..", "A320", "OAW", "Regular"), "2025-04-14"),
+			},
+		},
+		{
+			name: "No gaps",
+			input: []ScheduleRecord{
+				testRecord("KRK", "FRA", "LH", "123", "02:00", "02:30", "2024-01-01", "2024-01-21", "1234567", "A320", "OAW", "Regular"),
+			},
+			expected: []ScheduleRecord{
+				testRecord("KRK", "FRA", "LH", "123", "02:00", "02:30", "2024-01-01", "2024-01-21", "1234567", "A320", "OAW", "Regular"),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := NormalizeRecords(tt.input, NormalizeOptions{Separate: tt.separate, Exceptions: true})
+			if !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("Test %s failed:\nexpected: %v\ngot: %v", tt.name, tt.expected, result)
+			}
+			// Expanding the result has to give back the same operations
+			if !reflect.DeepEqual(len(ExpandRecords(result)), len(ExpandRecords(tt.input))) {
+				t.Errorf("Test %s failed: operations changed after normalization", tt.name)
+			}
+		})
+	}
+}
+
+// withUTC returns r with UTC times derived from the given offsets.
+func withUTC(r ScheduleRecord, departureVariation, arrivalVariation int) ScheduleRecord {
+	r.DepartureVariation, r.ArrivalVariation = departureVariation, arrivalVariation
+	r.DepartureUTC = r.Departure - TimeOfDay(departureVariation)
+	r.ArrivalUTC = r.Arrival - TimeOfDay(arrivalVariation)
+	return r
+}
+
+func TestNormalizeRecordsDST(t *testing.T) {
+	winter := withUTC(testRecord("KRK", "FRA", "LH", "1365", "10:20", "12:05", "2025-03-23", "2025-03-29", "1234567", "32N", "LH", "J"), 60, 60)
+	summer := withUTC(testRecord("KRK", "FRA", "LH", "1365", "10:20", "12:05", "2025-03-30", "2025-04-05", "1234567", "32N", "LH", "J"), 120, 120)
+	// Same UTC times all season, so the local times move an hour later in summer
+	summerFixedUTC := withUTC(testRecord("KRK", "FRA", "LH", "1365", "11:20", "13:05", "2025-03-30", "2025-04-05", "1234567", "32N", "LH", "J"), 120, 120)
+	// Offsets not matching the time zones are a real change, not a DST switch
+	summerWrongOffset := withUTC(testRecord("KRK", "FRA", "LH", "1365", "10:20", "12:05", "2025-03-30", "2025-04-05", "1234567", "32N", "LH", "J"), 60, 60)
+	summerWrongOffset.DepartureUTC += 30
+	// Only the arrival moves, 15 minutes later
+	summerLaterArrival := withUTC(testRecord("KRK", "FRA", "LH", "1365", "10:20", "12:20", "2025-03-30", "2025-04-05", "1234567", "32N", "LH", "J"), 120, 120)
+
+	merged := winter
+	merged.EndDate = summer.EndDate
+	merged.MergedDST = true
+	shifted := summerFixedUTC
+	shifted.DSTShift, shifted.ArrivalDSTShift = 60, 60
+	arrivalShifted := summerLaterArrival
+	arrivalShifted.ArrivalDSTShift = 15
+
+	tests := []struct {
+		name     string
+		input    []ScheduleRecord
+		mergeDST bool
+		expected []ScheduleRecord
+	}{
+		{
+			name:     "Split at the switch by default",
+			input:    []ScheduleRecord{winter, summer},
+			expected: []ScheduleRecord{winter, summer},
+		},
+		{
+			name:     "Same local times merged",
+			input:    []ScheduleRecord{summer, winter},
+			mergeDST: true,
+			expected: []ScheduleRecord{merged},
+		},
+		{
+			name:     "Local time change flagged",
+			input:    []ScheduleRecord{winter, summerFixedUTC},
+			mergeDST: true,
+			expected: []ScheduleRecord{winter, shifted},
+		},
+		{
+			name:     "Arrival change flagged",
+			input:    []ScheduleRecord{winter, summerLaterArrival},
+			mergeDST: true,
+			expected: []ScheduleRecord{winter, arrivalShifted},
+		},
+		{
+			name:     "Offsets not matching time zone kept apart",
+			input:    []ScheduleRecord{winter, summerWrongOffset},
+			mergeDST: true,
+			expected: []ScheduleRecord{winter, summerWrongOffset},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := NormalizeRecords(tt.input, NormalizeOptions{MergeDST: tt.mergeDST})
+			if !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("Test %s failed:\nexpected: %v\ngot: %v", tt.name, tt.expected, result)
+			}
+		})
+	}
+
+	// The UTC times of the joined periods differ, so they are not shown
+	utc := ParseColumnList("departure,departure_utc,arrival_utc,departure_variation")
+	if got := utc.Render(merged, dateLayout); !reflect.DeepEqual(got, []string{"10:20", "", "", ""}) {
+		t.Errorf("expected blank UTC columns for the joined periods, got %v", got)
+	}
+	if got := utc.Render(winter, dateLayout); !reflect.DeepEqual(got, []string{"10:20", "09:20", "11:05", "60"}) {
+		t.Errorf("expected UTC columns, got %v", got)
+	}
+}
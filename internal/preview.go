@@ -0,0 +1,97 @@
+package internal
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RecordFilter narrows the records shown in a preview, zero fields match
+// every record.
+type RecordFilter struct {
+	// FlightNumber is matched against the number with its suffix, e.g. "1365"
+	FlightNumber string
+	Weekday      int
+	AircraftType string
+	// Date keeps the records operating on the day
+	Date time.Time
+}
+
+// Match reports whether the record passes every set field of the filter.
+func (f RecordFilter) Match(r ScheduleRecord) bool {
+	if f.FlightNumber != "" && !strings.EqualFold(strconv.Itoa(r.FlightNumber)+r.Suffix, f.FlightNumber) {
+		return false
+	}
+	if f.Weekday != 0 && !r.Days.Has(f.Weekday) {
+		return false
+	}
+	if f.AircraftType != "" && !strings.EqualFold(r.AircraftType, f.AircraftType) {
+		return false
+	}
+	return f.Date.IsZero() || r.OperatesOn(f.Date)
+}
+
+// FilterRecords returns the records matching the filter in their order.
+func FilterRecords(records []ScheduleRecord, f RecordFilter) []ScheduleRecord {
+	var out []ScheduleRecord
+	for _, r := range records {
+		if f.Match(r) {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+// numericColumns compare by value instead of by their rendered text, times
+// include their day offset.
+var numericColumns = map[string]func(r ScheduleRecord) int{
+	"flight_number":       func(r ScheduleRecord) int { return r.FlightNumber },
+	"departure":           func(r ScheduleRecord) int { return int(r.Departure) + r.DepartureDateDiff*minutesPerDay },
+	"arrival":             func(r ScheduleRecord) int { return int(r.Arrival) + r.ArrivalDateDiff*minutesPerDay },
+	"departure_utc":       func(r ScheduleRecord) int { return int(r.DepartureUTC) + r.DepartureUTCDateDiff*minutesPerDay },
+	"arrival_utc":         func(r ScheduleRecord) int { return int(r.ArrivalUTC) + r.ArrivalUTCDateDiff*minutesPerDay },
+	"departure_date_diff": func(r ScheduleRecord) int { return r.DepartureDateDiff },
+	"arrival_date_diff":   func(r ScheduleRecord) int { return r.ArrivalDateDiff },
+	"departure_variation": func(r ScheduleRecord) int { return r.DepartureVariation },
+	"arrival_variation":   func(r ScheduleRecord) int { return r.ArrivalVariation },
+	"dst_shift":           func(r ScheduleRecord) int { return r.DSTShift },
+	"arrival_dst_shift":   func(r ScheduleRecord) int { return r.ArrivalDSTShift },
+}
+
+// SortRecordsBy orders the records by the column with the given key. Dates
+// and numbers sort in their natural order, equal records keep their order.
+func SortRecordsBy(records []ScheduleRecord, key string, descending bool) error {
+	column, ok := ColumnByKey(key)
+	if !ok {
+		return fmt.Errorf("unknown column %q", key)
+	}
+	number := numericColumns[key]
+	slices.SortStableFunc(records, func(a, b ScheduleRecord) int {
+		c := 0
+		if number != nil {
+			c = cmp.Compare(number(a), number(b))
+		}
+		if c == 0 {
+			// ISO dates sort as text
+			c = strings.Compare(column.Value(a, dateLayout), column.Value(b, dateLayout))
+		}
+		if descending {
+			return -c
+		}
+		return c
+	})
+	return nil
+}
+
+// WeekdayCounts returns the number of operations of the records on each
+// weekday, Monday first.
+func WeekdayCounts(records []ScheduleRecord) [7]int {
+	var counts [7]int
+	for _, op := range ExpandRecords(records) {
+		counts[WeekdayOf(op.Date)-1]++
+	}
+	return counts
+}
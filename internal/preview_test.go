@@ -0,0 +1,96 @@
+package internal
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func previewRecords() []ScheduleRecord {
+	return []ScheduleRecord{
+		testRecord("KRK", "FRA", "LH", "1365", "10:20", "12:05", "2025-03-30", "2025-04-26", "1.3.5..", "32N", "LH", "J"),
+		withExceptions(testRecord("KRK", "MUC", "LH", "1623", "06:00", "07:25", "2025-03-31", "2025-04-13", "1234567", "E95", "CL", "J"), "2025-04-02"),
+		testRecord("KRK", "FRA", "LH", "999", "22:50", "00:35", "2025-04-01", "2025-04-30", ".2.4.6.", "320", "LH", "J"),
+	}
+}
+
+func TestFilterRecords(t *testing.T) {
+	date := func(s string) time.Time {
+		d, _ := time.Parse(dateLayout, s)
+		return d
+	}
+
+	tests := []struct {
+		name     string
+		filter   RecordFilter
+		expected []int
+	}{
+		{name: "No filter", filter: RecordFilter{}, expected: []int{1365, 1623, 999}},
+		{name: "Flight number", filter: RecordFilter{FlightNumber: "1623"}, expected: []int{1623}},
+		{name: "Weekday", filter: RecordFilter{Weekday: 2}, expected: []int{1623, 999}},
+		{name: "Aircraft type ignoring case", filter: RecordFilter{AircraftType: "e95"}, expected: []int{1623}},
+		{name: "Operating date", filter: RecordFilter{Date: date("2025-04-07")}, expected: []int{1365, 1623}},
+		{name: "Exception date", filter: RecordFilter{Date: date("2025-04-02")}, expected: []int{1365}},
+		{name: "Combined", filter: RecordFilter{Weekday: 6, AircraftType: "320"}, expected: []int{999}},
+		{name: "Nothing matches", filter: RecordFilter{FlightNumber: "1"}, expected: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []int
+			for _, r := range FilterRecords(previewRecords(), tt.filter) {
+				got = append(got, r.FlightNumber)
+			}
+			if !slices.Equal(got, tt.expected) {
+				t.Errorf("Test %s failed: expected %v, got %v", tt.name, tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestSortRecordsBy(t *testing.T) {
+	tests := []struct {
+		name       string
+		key        string
+		descending bool
+		expected   []int
+	}{
+		{name: "Flight number as number", key: "flight_number", expected: []int{999, 1365, 1623}},
+		{name: "Flight number descending", key: "flight_number", descending: true, expected: []int{1623, 1365, 999}},
+		{name: "Departure", key: "departure", expected: []int{1623, 1365, 999}},
+		{name: "Arrival after midnight", key: "arrival", expected: []int{1623, 1365, 999}},
+		{name: "End date", key: "end_date", descending: true, expected: []int{999, 1365, 1623}},
+		{name: "Aircraft type", key: "aircraft_type", expected: []int{999, 1365, 1623}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			records := previewRecords()
+			// The overnight flight lands the next day
+			records[2].ArrivalDateDiff = 1
+			if err := SortRecordsBy(records, tt.key, tt.descending); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			var got []int
+			for _, r := range records {
+				got = append(got, r.FlightNumber)
+			}
+			if !slices.Equal(got, tt.expected) {
+				t.Errorf("Test %s failed: expected %v, got %v", tt.name, tt.expected, got)
+			}
+		})
+	}
+
+	if err := SortRecordsBy(previewRecords(), "gate", false); err == nil {
+		t.Errorf("expected error for unknown column")
+	}
+}
+
+func TestWeekdayCounts(t *testing.T) {
+	// LH1365 4 weeks on Mon, Wed, Fri; LH1623 2 weeks daily but 2 April;
+	// LH999 on Tue, Thu, Sat through April
+	expected := [7]int{4 + 2, 2 + 5, 4 + 1, 2 + 4, 4 + 2, 2 + 4, 2}
+	if got := WeekdayCounts(previewRecords()); got != expected {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+}
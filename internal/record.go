@@ -0,0 +1,243 @@
+package internal
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const dateLayout = "2006-01-02"
+
+// TimeOfDay is a time in minutes since midnight as used by the Lufthansa API.
+type TimeOfDay int
+
+func (t TimeOfDay) String() string {
+	return NumberToTime(int64(t))
+}
+
+func (t TimeOfDay) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+func (t *TimeOfDay) UnmarshalText(text []byte) error {
+	parsed, err := ParseTimeOfDay(string(text))
+	if err != nil {
+		return err
+	}
+	*t = parsed
+	return nil
+}
+
+// WithDayOffset formats the time followed by the day offset when it is not on
+// the operating day, e.g. "01:10+1".
+func (t TimeOfDay) WithDayOffset(offset int) string {
+	if offset == 0 {
+		return t.String()
+	}
+	return fmt.Sprintf("%s%+d", t, offset)
+}
+
+// ParseTimeOfDay reads a HH:MM time.
+func ParseTimeOfDay(s string) (TimeOfDay, error) {
+	hours, minutes, ok := strings.Cut(s, ":")
+	if !ok {
+		return 0, fmt.Errorf("invalid time %q", s)
+	}
+	h, err := strconv.Atoi(hours)
+	if err != nil || h < 0 || h > 23 {
+		return 0, fmt.Errorf("invalid time %q", s)
+	}
+	m, err := strconv.Atoi(minutes)
+	if err != nil || m < 0 || m > 59 {
+		return 0, fmt.Errorf("invalid time %q", s)
+	}
+	return TimeOfDay(h*60 + m), nil
+}
+
+// Flight holds everything describing a flight leg apart from when it operates.
+type Flight struct {
+	Origin       string `json:"origin"`
+	Destination  string `json:"destination"`
+	Airline      string `json:"airline"`
+	FlightNumber int    `json:"flight_number"`
+	Suffix       string `json:"suffix,omitempty"`
+
+	// Local times
+	Departure TimeOfDay `json:"departure"`
+	Arrival   TimeOfDay `json:"arrival"`
+
+	DepartureUTC TimeOfDay `json:"departure_utc"`
+	ArrivalUTC   TimeOfDay `json:"arrival_utc"`
+	// Day offsets of the times relative to the operating day, which is local
+	// or UTC depending on the time mode the record was read in
+	DepartureDateDiff    int `json:"departure_date_diff"`
+	ArrivalDateDiff      int `json:"arrival_date_diff"`
+	DepartureUTCDateDiff int `json:"departure_utc_date_diff"`
+	ArrivalUTCDateDiff   int `json:"arrival_utc_date_diff"`
+	// UTC offsets in minutes
+	DepartureVariation int `json:"departure_variation"`
+	ArrivalVariation   int `json:"arrival_variation"`
+
+	AircraftType         string `json:"aircraft_type"`
+	AircraftOwner        string `json:"aircraft_owner"`
+	ServiceType          string `json:"service_type"`
+	Registration         string `json:"registration,omitempty"`
+	ConfigurationVersion string `json:"configuration,omitempty"`
+}
+
+// ScheduleRecord is one schedule line - a flight leg operating on Days within
+// the StartDate - EndDate period.
+type ScheduleRecord struct {
+	Flight
+
+	StartDate time.Time `json:"start_date"`
+	EndDate   time.Time `json:"end_date"`
+	Days      Weekdays  `json:"days"`
+	// Exceptions lists dates within the period the flight does not operate on
+	// despite the weekday matching, only set when gaps are reported.
+	Exceptions []time.Time `json:"exceptions,omitempty"`
+	// DSTShift and ArrivalDSTShift are the changes in minutes of the local
+	// departure and arrival against the previous period of the flight across
+	// a daylight saving time switch.
+	DSTShift        int `json:"dst_shift,omitempty"`
+	ArrivalDSTShift int `json:"arrival_dst_shift,omitempty"`
+	// MergedDST is set on records joining periods on both sides of a daylight
+	// saving time switch, their UTC times and offsets are those of the first
+	// period and the UTC columns are left blank.
+	MergedDST bool `json:"merged_dst,omitempty"`
+}
+
+// sameFlight reports whether both records describe the same flight apart from
+// the period and days of operation.
+func (r ScheduleRecord) sameFlight(other ScheduleRecord) bool {
+	return r.Flight == other.Flight
+}
+
+// FirstOperation returns the first date within the period the flight operates on.
+func (r ScheduleRecord) FirstOperation() (time.Time, bool) {
+	date, ok := r.Days.firstOn(r.StartDate)
+	return date, ok && !date.After(r.EndDate)
+}
+
+// LastOperation returns the last date within the period the flight operates on.
+func (r ScheduleRecord) LastOperation() (time.Time, bool) {
+	date, ok := r.Days.lastOn(r.EndDate)
+	return date, ok && !date.Before(r.StartDate)
+}
+
+// OperatesOn reports whether the flight operates on date, a day within the
+// period on one of the days that is not an exception.
+func (r ScheduleRecord) OperatesOn(date time.Time) bool {
+	return !date.Before(r.StartDate) && !date.After(r.EndDate) &&
+		r.Days.OperatesOn(date) && !slices.ContainsFunc(r.Exceptions, date.Equal)
+}
+
+// Shift moves the period and days of operation of the record by days while the
+// flight times stay put, so their day offsets move the opposite way.
+func (r ScheduleRecord) Shift(days int) ScheduleRecord {
+	if days == 0 {
+		return r
+	}
+	r.StartDate = r.StartDate.AddDate(0, 0, days)
+	r.EndDate = r.EndDate.AddDate(0, 0, days)
+	r.Days = r.Days.Shift(days)
+	r.DepartureDateDiff -= days
+	r.ArrivalDateDiff -= days
+	r.DepartureUTCDateDiff -= days
+	r.ArrivalUTCDateDiff -= days
+
+	exceptions := make([]time.Time, 0, len(r.Exceptions))
+	for _, d := range r.Exceptions {
+		exceptions = append(exceptions, d.AddDate(0, 0, days))
+	}
+	r.Exceptions = exceptions
+	if len(exceptions) == 0 {
+		r.Exceptions = nil
+	}
+	return r
+}
+
+// ArrivalBased moves the record onto the day it arrives on, using the UTC or
+// local arrival depending on the basis of mode.
+func (r ScheduleRecord) ArrivalBased(mode TimeMode) ScheduleRecord {
+	if mode.Basis() == TimeModeUTC {
+		return r.Shift(r.ArrivalUTCDateDiff)
+	}
+	return r.Shift(r.ArrivalDateDiff)
+}
+
+// ParseSSIMDate parses a DDMMMYY date such as 4JUL24 or 19JUL24.
+func ParseSSIMDate(s string) (time.Time, error) {
+	date, err := time.Parse(dateLayout, SSIMtoDate(s))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid SSIM date %q", s)
+	}
+	return date, nil
+}
+
+// Helper function to convert FlightResponse to schedule records, the period
+// and days of operation follow the basis of mode.
+func convertFlightResponseToRecords(d FlightResponse, mode TimeMode) ([]ScheduleRecord, error) {
+	if len(d.Legs) == 0 {
+		return nil, fmt.Errorf("flight %s%d has no legs", d.Airline, d.FlightNumber)
+	}
+	period := d.PeriodOfOperationLT
+	if mode.Basis() == TimeModeUTC {
+		period = d.PeriodOfOperationUTC
+	}
+	startDate, err := ParseSSIMDate(period.StartDate)
+	if err != nil {
+		return nil, err
+	}
+	endDate, err := ParseSSIMDate(period.EndDate)
+	if err != nil {
+		return nil, err
+	}
+
+	days, err := ParseWeekdays(period.DaysOfOperation)
+	if err != nil {
+		return nil, err
+	}
+
+	leg := d.Legs[0]
+	flight := Flight{
+		Origin:               leg.Origin,
+		Destination:          leg.Destination,
+		Airline:              d.Airline,
+		FlightNumber:         d.FlightNumber,
+		Suffix:               d.Suffix,
+		Departure:            TimeOfDay(leg.AircraftDepartureTimeLT),
+		Arrival:              TimeOfDay(leg.AircraftArrivalTimeLT),
+		DepartureUTC:         TimeOfDay(leg.AircraftDepartureTimeUTC),
+		ArrivalUTC:           TimeOfDay(leg.AircraftArrivalTimeUTC),
+		DepartureDateDiff:    int(leg.AircraftDepartureTimeDateDiffLT),
+		ArrivalDateDiff:      int(leg.AircraftArrivalTimeDateDiffLT),
+		DepartureVariation:   int(leg.AircraftDepartureTimeVariation),
+		ArrivalVariation:     int(leg.AircraftArrivalTimeVariation),
+		AircraftType:         leg.AircraftType,
+		AircraftOwner:        leg.AircraftOwner,
+		ServiceType:          leg.ServiceType,
+		Registration:         leg.Registration,
+		ConfigurationVersion: leg.AircraftConfigurationVersion,
+	}
+	// The API gives the day offsets of each time against its own period,
+	// derive the other pair against the operating day of the chosen basis.
+	if mode.Basis() == TimeModeUTC {
+		flight.DepartureUTCDateDiff = int(leg.AircraftDepartureTimeDateDiffUTC)
+		flight.ArrivalUTCDateDiff = int(leg.AircraftArrivalTimeDateDiffUTC)
+		flight.DepartureDateDiff = dayOffset(int(flight.DepartureUTC) + flight.DepartureUTCDateDiff*minutesPerDay + flight.DepartureVariation)
+		flight.ArrivalDateDiff = dayOffset(int(flight.ArrivalUTC) + flight.ArrivalUTCDateDiff*minutesPerDay + flight.ArrivalVariation)
+	} else {
+		flight.DepartureUTCDateDiff = dayOffset(int(flight.Departure) + flight.DepartureDateDiff*minutesPerDay - flight.DepartureVariation)
+		flight.ArrivalUTCDateDiff = dayOffset(int(flight.Arrival) + flight.ArrivalDateDiff*minutesPerDay - flight.ArrivalVariation)
+	}
+	record := ScheduleRecord{
+		Flight:    flight,
+		StartDate: startDate,
+		EndDate:   endDate,
+		Days:      days,
+	}
+	return []ScheduleRecord{record}, nil
+}
@@ -0,0 +1,78 @@
+package internal
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseTimeOfDay(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		expected  TimeOfDay
+		expectErr bool
+	}{
+		{
+			name:     "Morning",
+			input:    "06:05",
+			expected: 365,
+		},
+		{
+			name:     "Midnight",
+			input:    "00:00",
+			expected: 0,
+		},
+		{
+			name:     "Round trip of NumberToTime",
+			input:    TimeOfDay(1439).String(),
+			expected: 1439,
+		},
+		{
+			name:      "Missing separator",
+			input:     "0605",
+			expectErr: true,
+		},
+		{
+			name:      "Invalid minutes",
+			input:     "06:75",
+			expectErr: true,
+		},
+		{
+			name:      "Invalid hours",
+			input:     "24:00",
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := ParseTimeOfDay(tt.input)
+			if (err != nil) != tt.expectErr {
+				t.Errorf("Test %s failed: expected error: %v, got: %v", tt.name, tt.expectErr, err)
+			}
+			if result != tt.expected {
+				t.Errorf("Test %s failed: expected %v, got %v", tt.name, tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestScheduleRecordShift(t *testing.T) {
+	record := withExceptions(testRecord("FRA", "KRK", "LH", "1364", "23:40", "01:10", "2025-03-31", "2025-04-30", "1.3....", "32N", "LH", "J"), "2025-04-14")
+	record.ArrivalDateDiff = 1
+
+	expected := withExceptions(testRecord("FRA", "KRK", "LH", "1364", "23:40", "01:10", "2025-04-01", "2025-05-01", ".2.4...", "32N", "LH", "J"), "2025-04-15")
+	expected.DepartureDateDiff = -1
+	expected.DepartureUTCDateDiff = -1
+	expected.ArrivalUTCDateDiff = -1
+
+	if result := record.ArrivalBased(TimeModeLT); !reflect.DeepEqual(result, expected) {
+		t.Errorf("expected %v, got %v", expected, result)
+	}
+	if record.StartDate.Format(dateLayout) != "2025-03-31" || record.Exceptions[0].Format(dateLayout) != "2025-04-14" {
+		t.Errorf("shifting modified the original record: %v", record)
+	}
+	if result := record.ArrivalBased(TimeModeUTC); !reflect.DeepEqual(result, record) {
+		t.Errorf("expected UTC arrival on the same day to keep the record, got %v", result)
+	}
+}
@@ -0,0 +1,247 @@
+package internal
+
+import (
+	"cmp"
+	_ "embed"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+//go:embed data/operators.csv
+var operatorsCSV string
+
+//go:embed data/aircraft.csv
+var aircraftCSV string
+
+// Operator is an airline of the reference data.
+type Operator struct {
+	IATA     string `json:"iata"`
+	ICAO     string `json:"icao"`
+	Name     string `json:"name"`
+	Alliance string `json:"alliance,omitempty"`
+	// Group is the airline group owning the operator
+	Group string `json:"group,omitempty"`
+}
+
+// AircraftType is an aircraft type of the reference data.
+type AircraftType struct {
+	IATA string `json:"iata"`
+	// ICAO is empty for IATA codes standing for a whole family such as 330
+	ICAO   string `json:"icao,omitempty"`
+	Family string `json:"family"`
+	// Seats is the typical capacity, the default of the seat table
+	Seats int `json:"seats"`
+	// WakeCategory is the ICAO wake turbulence category, L, M, H or J
+	WakeCategory string `json:"wake_category"`
+}
+
+// ReferenceData holds the operators and aircraft types by IATA code.
+type ReferenceData struct {
+	operators map[string]Operator
+	aircraft  map[string]AircraftType
+}
+
+// ReadOperators reads a CSV with iata, icao, name, alliance and group
+// columns and a header row.
+func ReadOperators(r io.Reader) ([]Operator, error) {
+	rows, err := readReferenceCSV(r, []string{"iata", "icao", "name", "alliance", "group"}, "operators")
+	if err != nil {
+		return nil, err
+	}
+	operators := make([]Operator, 0, len(rows))
+	for i, row := range rows {
+		o := Operator{IATA: strings.ToUpper(row[0]), ICAO: strings.ToUpper(row[1]), Name: row[2], Alliance: row[3], Group: row[4]}
+		if len(o.IATA) != 2 || o.Name == "" {
+			return nil, fmt.Errorf("invalid operator %q on line %d", row[0], i+2)
+		}
+		operators = append(operators, o)
+	}
+	return operators, nil
+}
+
+// ReadAircraftTypes reads a CSV with iata, icao, family, seats and
+// wake_category columns and a header row.
+func ReadAircraftTypes(r io.Reader) ([]AircraftType, error) {
+	rows, err := readReferenceCSV(r, []string{"iata", "icao", "family", "seats", "wake_category"}, "aircraft types")
+	if err != nil {
+		return nil, err
+	}
+	types := make([]AircraftType, 0, len(rows))
+	for i, row := range rows {
+		seats, err := strconv.Atoi(row[3])
+		if err != nil || seats < 0 {
+			return nil, fmt.Errorf("invalid seats %q for aircraft %s on line %d", row[3], row[0], i+2)
+		}
+		a := AircraftType{IATA: strings.ToUpper(row[0]), ICAO: strings.ToUpper(row[1]), Family: row[2], Seats: seats, WakeCategory: strings.ToUpper(row[4])}
+		if a.IATA == "" || !slices.Contains([]string{"L", "M", "H", "J"}, a.WakeCategory) {
+			return nil, fmt.Errorf("invalid aircraft %q with wake category %q on line %d", row[0], row[4], i+2)
+		}
+		types = append(types, a)
+	}
+	return types, nil
+}
+
+// readReferenceCSV returns the trimmed rows of the CSV without its header,
+// which must name the columns so a file without one does not lose its first
+// row.
+func readReferenceCSV(r io.Reader, columns []string, name string) ([][]string, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = len(columns)
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", name, err)
+	}
+	for _, row := range rows {
+		for i := range row {
+			row[i] = strings.TrimSpace(row[i])
+		}
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("missing header of %s, expected %s", name, strings.Join(columns, ","))
+	}
+	header := rows[0]
+	header[0] = strings.TrimPrefix(header[0], "\ufeff")
+	for i, column := range columns {
+		if !strings.EqualFold(header[i], column) {
+			return nil, fmt.Errorf("invalid header of %s, expected %s", name, strings.Join(columns, ","))
+		}
+	}
+	return rows[1:], nil
+}
+
+func (d *ReferenceData) add(operators []Operator, aircraft []AircraftType) {
+	for _, o := range operators {
+		d.operators[o.IATA] = o
+	}
+	for _, a := range aircraft {
+		d.aircraft[a.IATA] = a
+	}
+}
+
+// DefaultReferenceData returns the operators and aircraft types of the
+// embedded reference data.
+func DefaultReferenceData() ReferenceData {
+	operators, err := ReadOperators(strings.NewReader(operatorsCSV))
+	if err != nil {
+		panic(fmt.Sprintf("invalid embedded operator data: %v", err))
+	}
+	aircraft, err := ReadAircraftTypes(strings.NewReader(aircraftCSV))
+	if err != nil {
+		panic(fmt.Sprintf("invalid embedded aircraft data: %v", err))
+	}
+	d := ReferenceData{operators: make(map[string]Operator), aircraft: make(map[string]AircraftType)}
+	d.add(operators, aircraft)
+	return d
+}
+
+// LoadReferenceData returns the default reference data with the entries of
+// the operator and aircraft CSV files added or replacing the defaults. A
+// missing file leaves the defaults.
+func LoadReferenceData(operatorsPath, aircraftPath string) (ReferenceData, error) {
+	d := DefaultReferenceData()
+	var operators []Operator
+	err := readOptionalFile(operatorsPath, func(r io.Reader) (err error) {
+		operators, err = ReadOperators(r)
+		return err
+	})
+	if err != nil {
+		return d, err
+	}
+	var aircraft []AircraftType
+	err = readOptionalFile(aircraftPath, func(r io.Reader) (err error) {
+		aircraft, err = ReadAircraftTypes(r)
+		return err
+	})
+	if err != nil {
+		return d, err
+	}
+	d.add(operators, aircraft)
+	return d, nil
+}
+
+// readOptionalFile reads the file at path unless it does not exist.
+func readOptionalFile(path string, read func(r io.Reader) error) error {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return read(f)
+}
+
+// Operator finds an operator by its IATA or ICAO code.
+func (d ReferenceData) Operator(code string) (Operator, bool) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if o, ok := d.operators[code]; ok {
+		return o, true
+	}
+	for _, o := range d.Operators() {
+		if o.ICAO != "" && o.ICAO == code {
+			return o, true
+		}
+	}
+	return Operator{}, false
+}
+
+// AircraftType finds an aircraft type by its IATA code, or by its ICAO code
+// taking the first IATA code sharing it.
+func (d ReferenceData) AircraftType(code string) (AircraftType, bool) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if a, ok := d.aircraft[code]; ok {
+		return a, true
+	}
+	for _, a := range d.AircraftTypes() {
+		if a.ICAO != "" && a.ICAO == code {
+			return a, true
+		}
+	}
+	return AircraftType{}, false
+}
+
+// Operators lists the operators ordered by IATA code.
+func (d ReferenceData) Operators() []Operator {
+	return slices.SortedFunc(maps.Values(d.operators), func(a, b Operator) int { return cmp.Compare(a.IATA, b.IATA) })
+}
+
+// AircraftTypes lists the aircraft types ordered by IATA code.
+func (d ReferenceData) AircraftTypes() []AircraftType {
+	return slices.SortedFunc(maps.Values(d.aircraft), func(a, b AircraftType) int { return cmp.Compare(a.IATA, b.IATA) })
+}
+
+var (
+	referenceOnce sync.Once
+	referenceMu   sync.RWMutex
+	referenceData ReferenceData
+)
+
+func loadReferences() {
+	referenceData = DefaultReferenceData()
+}
+
+// References returns the reference data the exports use, the embedded
+// defaults until SetReferences replaces them.
+func References() ReferenceData {
+	referenceOnce.Do(loadReferences)
+	referenceMu.RLock()
+	defer referenceMu.RUnlock()
+	return referenceData
+}
+
+// SetReferences replaces the reference data the exports use.
+func SetReferences(d ReferenceData) {
+	referenceOnce.Do(loadReferences)
+	referenceMu.Lock()
+	defer referenceMu.Unlock()
+	referenceData = d
+}
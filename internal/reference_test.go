@@ -0,0 +1,97 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReferenceDataLookup(t *testing.T) {
+	d := DefaultReferenceData()
+	tests := []struct {
+		name     string
+		code     string
+		expected string
+		found    bool
+	}{
+		{name: "IATA code", code: "lo", expected: "LOT Polish Airlines", found: true},
+		{name: "ICAO code", code: "AUA", expected: "Austrian Airlines", found: true},
+		{name: "Unknown code", code: "XX", found: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o, found := d.Operator(tt.code)
+			if o.Name != tt.expected || found != tt.found {
+				t.Errorf("expected %q %v, got %q %v", tt.expected, tt.found, o.Name, found)
+			}
+		})
+	}
+
+	if a, _ := d.AircraftType("A20N"); a.IATA != "32N" || a.Family != "A320" || a.WakeCategory != "M" {
+		t.Errorf("unexpected aircraft type %+v", a)
+	}
+	// The seat table defaults to the typical seats of the types
+	for _, a := range d.AircraftTypes() {
+		if seats, _ := DefaultSeatTable().Seats(a.IATA, ""); seats != a.Seats {
+			t.Errorf("expected %d seats for aircraft type %s, got %d", a.Seats, a.IATA, seats)
+		}
+	}
+}
+
+func TestReadAircraftTypes(t *testing.T) {
+	if _, err := ReadAircraftTypes(strings.NewReader("iata,icao,family,seats,wake_category\n32N,A20N,A320,180,X\n")); err == nil {
+		t.Errorf("expected error for invalid wake category")
+	}
+	if _, err := ReadAircraftTypes(strings.NewReader("iata,icao,family,seats,wake_category\n32N,A20N,A320,many,M\n")); err == nil {
+		t.Errorf("expected error for invalid seats")
+	}
+	if _, err := ReadAircraftTypes(strings.NewReader("32N,A20N,A320,180,M\n")); err == nil {
+		t.Errorf("expected error for a missing header")
+	}
+	if _, err := ReadAircraftTypes(strings.NewReader("")); err == nil {
+		t.Errorf("expected error for an empty file")
+	}
+	if types, err := ReadAircraftTypes(strings.NewReader("\ufeffIATA,ICAO,Family,Seats,Wake_Category\n32N,A20N,A320,180,M\n")); err != nil || len(types) != 1 {
+		t.Errorf("expected the header of a spreadsheet export to be accepted, got %v (%v)", types, err)
+	}
+}
+
+func TestLoadReferenceData(t *testing.T) {
+	dir := t.TempDir()
+	operators := filepath.Join(dir, "operators.csv")
+	if err := os.WriteFile(operators, []byte("iata,icao,name,alliance,group\nLO,LOT,LOT,Star Alliance,PGL\nXQ,SXS,SunExpress,,\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	d, err := LoadReferenceData(operators, filepath.Join(dir, "missing.csv"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if o, _ := d.Operator("LO"); o.Name != "LOT" || o.Group != "PGL" {
+		t.Errorf("expected the file to replace the default, got %+v", o)
+	}
+	if _, ok := d.Operator("SXS"); !ok {
+		t.Errorf("expected the file to add an operator")
+	}
+	if _, ok := d.Operator("LH"); !ok {
+		t.Errorf("expected the default operators to be kept")
+	}
+	if _, ok := d.AircraftType("32N"); !ok {
+		t.Errorf("expected the default aircraft types for a missing file")
+	}
+}
+
+func TestReferenceColumns(t *testing.T) {
+	record := testRecord("KRK", "FRA", "LH", "1365", "10:20", "12:05", "2025-03-30", "2025-10-25", "1234567", "32N", "LH", "J")
+	template := ParseColumnList("operator_name,alliance,aircraft_icao,aircraft_family,wake_category")
+	expectedHeader := []string{"Operator name", "Alliance", "Aircraft ICAO", "Aircraft family", "Wake category"}
+	expectedRow := []string{"Lufthansa", "Star Alliance", "A20N", "A320", "M"}
+
+	if got := template.Header(LocaleEN); !reflect.DeepEqual(got, expectedHeader) {
+		t.Errorf("expected header %v, got %v", expectedHeader, got)
+	}
+	if got := template.Render(record, dateLayout); !reflect.DeepEqual(got, expectedRow) {
+		t.Errorf("expected row %v, got %v", expectedRow, got)
+	}
+}
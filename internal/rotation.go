@@ -0,0 +1,245 @@
+package internal
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultMinTurnaround is the shortest ground time in minutes a rotation is
+// not flagged at unless another minimum is given.
+const DefaultMinTurnaround = 30
+
+// Rotation links a flight arriving at a station with the departure of the
+// same aircraft from there on the local dates of the period.
+type Rotation struct {
+	Station string `json:"station"`
+	// Inbound is nil when the aircraft stays overnight before the departure
+	Inbound *Flight `json:"inbound,omitempty"`
+	// Outbound is nil when the aircraft stays overnight after the arrival
+	Outbound  *Flight   `json:"outbound,omitempty"`
+	StartDate time.Time `json:"start_date"`
+	EndDate   time.Time `json:"end_date"`
+	Days      Weekdays  `json:"days"`
+	// GroundMinutes is the time between the arrival and the departure, zero
+	// unless both flights are known
+	GroundMinutes int `json:"ground_minutes"`
+	// Short marks a ground time below the minimum turnaround
+	Short bool `json:"short"`
+}
+
+// Paired reports whether the rotation has both an inbound and an outbound flight.
+func (r Rotation) Paired() bool {
+	return r.Inbound != nil && r.Outbound != nil
+}
+
+// sameAircraft reports whether the departure can be flown by the aircraft
+// of the arrival: the same operator and type, and the same registration
+// when both flights have one.
+func sameAircraft(arrival, departure Flight) bool {
+	if arrival.AircraftOwner != departure.AircraftOwner || arrival.AircraftType != departure.AircraftType {
+		return false
+	}
+	return arrival.Registration == "" || departure.Registration == "" || arrival.Registration == departure.Registration
+}
+
+// rotationKey identifies a rotation repeating over the dates of a period, a
+// zero flight stands for a missing one.
+type rotationKey struct {
+	inbound, outbound Flight
+}
+
+// BuildRotations pairs the arrivals at the station with the departures from
+// it on the same local date. Arrivals are taken in time order and paired
+// with the earliest later departure flown by the same aircraft, flights left
+// over start or end the day at the station. Pairs repeating on several
+// dates are combined into periods like the schedule records.
+func BuildRotations(records []ScheduleRecord, station string, minTurnaround int) ([]Rotation, error) {
+	station = strings.ToUpper(strings.TrimSpace(station))
+	if station == "" {
+		return nil, fmt.Errorf("station is required")
+	}
+	if minTurnaround < 0 {
+		return nil, fmt.Errorf("invalid minimum turnaround of %d minutes", minTurnaround)
+	}
+
+	arrivals := make(map[time.Time][]Flight)
+	departures := make(map[time.Time][]Flight)
+	for _, op := range ExpandRecords(records) {
+		if op.Flight.Destination == station {
+			date := op.Date.AddDate(0, 0, op.Flight.ArrivalDateDiff)
+			arrivals[date] = append(arrivals[date], op.Flight)
+		}
+		if op.Flight.Origin == station {
+			date := op.Date.AddDate(0, 0, op.Flight.DepartureDateDiff)
+			departures[date] = append(departures[date], op.Flight)
+		}
+	}
+
+	var keys []rotationKey
+	datesByKey := make(map[rotationKey][]time.Time)
+	add := func(key rotationKey, date time.Time) {
+		if _, ok := datesByKey[key]; !ok {
+			keys = append(keys, key)
+		}
+		datesByKey[key] = append(datesByKey[key], date)
+	}
+	dates := slices.Collect(maps.Keys(arrivals))
+	for date := range departures {
+		if _, ok := arrivals[date]; !ok {
+			dates = append(dates, date)
+		}
+	}
+	slices.SortFunc(dates, time.Time.Compare)
+	for _, date := range dates {
+		for _, key := range pairDay(arrivals[date], departures[date]) {
+			add(key, date)
+		}
+	}
+
+	var rotations []Rotation
+	for _, key := range keys {
+		for _, period := range periodsOf(datesByKey[key]) {
+			rotation := Rotation{Station: station, StartDate: period.StartDate, EndDate: period.EndDate, Days: period.Days}
+			if key.inbound != (Flight{}) {
+				rotation.Inbound = &key.inbound
+			}
+			if key.outbound != (Flight{}) {
+				rotation.Outbound = &key.outbound
+			}
+			if rotation.Paired() {
+				rotation.GroundMinutes = int(key.outbound.Departure) - int(key.inbound.Arrival)
+				rotation.Short = rotation.GroundMinutes < minTurnaround
+			}
+			rotations = append(rotations, rotation)
+		}
+	}
+	sortRotations(rotations)
+	return rotations, nil
+}
+
+// pairDay pairs the arrivals and departures of a day at a station.
+func pairDay(arrivals, departures []Flight) []rotationKey {
+	byArrival := func(a, b Flight) int { return cmp.Compare(a.Arrival, b.Arrival) }
+	byDeparture := func(a, b Flight) int { return cmp.Compare(a.Departure, b.Departure) }
+	slices.SortStableFunc(arrivals, byArrival)
+	slices.SortStableFunc(departures, byDeparture)
+
+	var keys []rotationKey
+	used := make([]bool, len(departures))
+	for _, arrival := range arrivals {
+		key := rotationKey{inbound: arrival}
+		for i, departure := range departures {
+			if !used[i] && departure.Departure >= arrival.Arrival && sameAircraft(arrival, departure) {
+				used[i] = true
+				key.outbound = departure
+				break
+			}
+		}
+		keys = append(keys, key)
+	}
+	for i, departure := range departures {
+		if !used[i] {
+			keys = append(keys, rotationKey{outbound: departure})
+		}
+	}
+	return keys
+}
+
+// periodsOf compresses sorted dates into periods of consecutive weeks,
+// combining the weekdays sharing the same weeks.
+func periodsOf(dates []time.Time) []ScheduleRecord {
+	var runs []ScheduleRecord
+	for day := 1; day <= 7; day++ {
+		runs = append(runs, weeklyRuns(Flight{}, day, dates, false)...)
+	}
+	SortRecordsByStartDate(runs)
+	return MergeDays(runs)
+}
+
+// sortRotations orders rotations by start date and the time the aircraft
+// reaches the station.
+func sortRotations(rotations []Rotation) {
+	at := func(r Rotation) TimeOfDay {
+		if r.Inbound != nil {
+			return r.Inbound.Arrival
+		}
+		return r.Outbound.Departure
+	}
+	slices.SortStableFunc(rotations, func(a, b Rotation) int {
+		return cmp.Or(
+			a.StartDate.Compare(b.StartDate),
+			cmp.Compare(at(a), at(b)),
+			cmp.Compare(a.Days, b.Days),
+		)
+	})
+}
+
+// rotationColumns are the columns of the rotation report.
+var rotationColumns = []string{
+	"station", "inbound", "origin", "arrival", "outbound", "destination", "departure",
+	"ground_time", "aircraft_type", "aircraft_owner", "registration", "start_date", "end_date", "days", "note",
+}
+
+// RotationRows renders the rotation report with a header row in the locale.
+func RotationRows(rotations []Rotation, l Locale, dateLayout string) [][]string {
+	header := make([]string, 0, len(rotationColumns))
+	for _, c := range rotationColumns {
+		header = append(header, T(l, "rotation."+c))
+	}
+	rows := [][]string{header}
+	for _, r := range rotations {
+		var inbound, origin, arrival, outbound, destination, departure, ground, note string
+		aircraft := r.Inbound
+		if r.Inbound != nil {
+			inbound, origin, arrival = flightDesignator(*r.Inbound), r.Inbound.Origin, r.Inbound.Arrival.String()
+		}
+		if r.Outbound != nil {
+			outbound, destination, departure = flightDesignator(*r.Outbound), r.Outbound.Destination, r.Outbound.Departure.String()
+			aircraft = r.Outbound
+		}
+		switch {
+		case r.Paired():
+			ground = strconv.Itoa(r.GroundMinutes)
+			if r.Short {
+				note = T(l, "rotation.note.short")
+			}
+		case r.Inbound != nil:
+			note = T(l, "rotation.note.night_stop")
+		default:
+			note = T(l, "rotation.note.first_departure")
+		}
+		rows = append(rows, []string{
+			r.Station,
+			inbound, origin, arrival,
+			outbound, destination, departure,
+			ground,
+			aircraft.AircraftType, aircraft.AircraftOwner, aircraft.Registration,
+			r.StartDate.Format(dateLayout),
+			r.EndDate.Format(dateLayout),
+			r.Days.String(),
+			note,
+		})
+	}
+	return rows
+}
+
+func flightDesignator(f Flight) string {
+	return fmt.Sprintf("%s%d%s", f.Airline, f.FlightNumber, f.Suffix)
+}
+
+// WriteRotationsCSV writes the rotation report using the locale and format of opts.
+func WriteRotationsCSV(writer io.Writer, rotations []Rotation, opts ExportOptions) error {
+	format := opts.format()
+	return writeCSVRows(writer, RotationRows(rotations, opts.Locale, format.DateLayout), format.Delimiter)
+}
+
+// WriteRotationsXLSX writes the rotation report as a spreadsheet.
+func WriteRotationsXLSX(writer io.Writer, rotations []Rotation, opts ExportOptions) error {
+	return WriteXLSX(writer, T(opts.Locale, "rotation.sheet"), RotationRows(rotations, opts.Locale, opts.format().DateLayout))
+}
@@ -0,0 +1,62 @@
+package internal
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestBuildRotations(t *testing.T) {
+	records := []ScheduleRecord{
+		testRecord("FRA", "KRK", "LH", "1364", "08:05", "09:40", "2025-03-31", "2025-04-13", "1234567", "32N", "LH", "J"),
+		testRecord("KRK", "FRA", "LH", "1365", "10:20", "12:05", "2025-03-31", "2025-04-13", "123456.", "32N", "LH", "J"),
+		testRecord("MUC", "KRK", "LH", "1620", "12:00", "13:00", "2025-03-31", "2025-04-13", "1.3.5..", "E95", "CL", "J"),
+		testRecord("KRK", "MUC", "LH", "1621", "13:20", "14:25", "2025-03-31", "2025-04-13", "1.3.5..", "E95", "CL", "J"),
+		testRecord("MUC", "KRK", "LH", "1622", "21:30", "22:30", "2025-03-31", "2025-04-06", "1......", "E95", "CL", "J"),
+		testRecord("KRK", "MUC", "LH", "1623", "06:00", "07:05", "2025-04-01", "2025-04-07", ".2.....", "E95", "CL", "J"),
+		// Same time but another aircraft type, not flown by the inbound A320neo
+		testRecord("KRK", "FRA", "LH", "1367", "10:00", "11:45", "2025-03-31", "2025-03-31", "1......", "320", "LH", "J"),
+	}
+
+	rotations, err := BuildRotations(records, "krk", 30)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var got []string
+	for _, r := range RotationRows(rotations, LocaleEN, dateLayout)[1:] {
+		got = append(got, r[1]+" "+r[4]+" "+r[7]+" "+r[11]+" "+r[12]+" "+r[13]+" "+r[14])
+	}
+	expected := []string{
+		"LH1364 LH1365 40 2025-03-31 2025-04-12 123456. ",
+		" LH1367  2025-03-31 2025-03-31 1...... Departure after night stop",
+		"LH1620 LH1621 20 2025-03-31 2025-04-11 1.3.5.. Turnaround too short",
+		"LH1622   2025-03-31 2025-03-31 1...... Night stop after arrival",
+		" LH1623  2025-04-01 2025-04-01 .2..... Departure after night stop",
+		"LH1364   2025-04-06 2025-04-13 ......7 Night stop after arrival",
+	}
+	if !slices.Equal(got, expected) {
+		t.Errorf("expected\n%q\ngot\n%q", expected, got)
+	}
+
+	if _, err := BuildRotations(records, " ", 30); err == nil {
+		t.Errorf("expected error without station")
+	}
+}
+
+func TestPairDayRegistration(t *testing.T) {
+	flight := func(number int, registration string, departure, arrival TimeOfDay) Flight {
+		return Flight{Airline: "LH", FlightNumber: number, AircraftType: "32N", AircraftOwner: "LH", Registration: registration, Departure: departure, Arrival: arrival}
+	}
+	arrival := flight(1364, "DAINA", 0, 9*60)
+	departures := []Flight{flight(1365, "DAINB", 10*60, 0), flight(1367, "DAINA", 11*60, 0), flight(1369, "", 12*60, 0)}
+
+	keys := pairDay([]Flight{arrival}, departures)
+	if len(keys) != 3 || keys[0].outbound.FlightNumber != 1367 {
+		t.Errorf("expected the arrival to pair with the departure of its registration, got %+v", keys)
+	}
+	// Without a registration the first departure of the type is taken
+	arrival.Registration = ""
+	if keys := pairDay([]Flight{arrival}, departures); keys[0].outbound.FlightNumber != 1365 {
+		t.Errorf("expected the first departure, got %+v", keys[0].outbound)
+	}
+}
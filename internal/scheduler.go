@@ -0,0 +1,328 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Fetcher downloads the flattened API response for the queries.
+type Fetcher func(ctx context.Context, queries []ApiQuery) ([]byte, error)
+
+// WatchJob is a fetch run on a cron schedule.
+type WatchJob struct {
+	Name     string `json:"name"`
+	Schedule string `json:"schedule"`
+	Carrier  string `json:"carrier"`
+	// Origin and Destination narrow the fetch to a route, by default the
+	// routes of the carrier are fetched
+	Origin      string `json:"origin,omitempty"`
+	Destination string `json:"destination,omitempty"`
+	// Season is "current", "next" or a code such as S25, current by default
+	Season   string   `json:"season,omitempty"`
+	TimeMode TimeMode `json:"time_mode,omitempty"`
+	Separate bool     `json:"separate,omitempty"`
+}
+
+// WatchConfig configures the scheduled fetches and where their changes are sent.
+type WatchConfig struct {
+	// Timezone the schedules are evaluated in, the server time zone by default
+	Timezone string            `json:"timezone,omitempty"`
+	Locale   Locale            `json:"locale,omitempty"`
+	Jobs     []WatchJob        `json:"jobs"`
+	Webhooks []WebhookNotifier `json:"webhooks,omitempty"`
+	SMTP     []SMTPNotifier    `json:"smtp,omitempty"`
+}
+
+func LoadWatchConfig(path string) (WatchConfig, error) {
+	var cfg WatchConfig
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return cfg, err
+	}
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return cfg, fmt.Errorf("failed to parse watch config: %w", err)
+	}
+	return cfg, nil
+}
+
+// Notifiers returns every notifier of the configuration.
+func (c WatchConfig) Notifiers() []Notifier {
+	var notifiers []Notifier
+	for _, wh := range c.Webhooks {
+		notifiers = append(notifiers, wh)
+	}
+	for _, m := range c.SMTP {
+		notifiers = append(notifiers, m)
+	}
+	return notifiers
+}
+
+// Period returns the dates the job fetches when run on date.
+func (j WatchJob) Period(date time.Time) (Season, error) {
+	switch strings.ToLower(j.Season) {
+	case "", "current":
+		return SeasonOf(date), nil
+	case "next":
+		return SeasonOf(date).Next(), nil
+	default:
+		return ParseSeason(j.Season)
+	}
+}
+
+// Queries builds the API queries of the job for the period.
+func (j WatchJob) Queries(period Season) ([]ApiQuery, error) {
+	code, ok := CarrierCode(j.Carrier)
+	if !ok {
+		return nil, fmt.Errorf("unknown carrier %q", j.Carrier)
+	}
+	from, to := DateToSSIM(period.Start.Format(dateLayout)), DateToSSIM(period.End.Format(dateLayout))
+	queries := GetQueryListForAirline(code, from, to, j.TimeMode)
+	if j.Origin != "" || j.Destination != "" {
+		queries = []ApiQuery{{
+			Airline:         j.Carrier,
+			StartDate:       from,
+			EndDate:         to,
+			DaysOfOperation: "1234567",
+			TimeMode:        j.TimeMode.Query(),
+			Origin:          strings.ToUpper(j.Origin),
+			Destination:     strings.ToUpper(j.Destination),
+		}}
+	}
+	for _, q := range queries {
+		if err := q.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	return queries, nil
+}
+
+type scheduledJob struct {
+	WatchJob
+	schedule CronSchedule
+	next     time.Time
+}
+
+// Scheduler runs watch jobs, stores their snapshots and notifies changes
+// against the previous snapshot of the job.
+type Scheduler struct {
+	jobs      []*scheduledJob
+	fetch     Fetcher
+	store     *SnapshotStore
+	notifiers []Notifier
+	location  *time.Location
+	locale    Locale
+	now       func() time.Time
+}
+
+func NewScheduler(cfg WatchConfig, fetch Fetcher, store *SnapshotStore) (*Scheduler, error) {
+	s := &Scheduler{
+		fetch:     fetch,
+		store:     store,
+		notifiers: cfg.Notifiers(),
+		location:  time.Local,
+		locale:    supportedLocale(cfg.Locale),
+		now:       time.Now,
+	}
+	if cfg.Timezone != "" {
+		loc, err := time.LoadLocation(cfg.Timezone)
+		if err != nil {
+			return nil, err
+		}
+		s.location = loc
+	}
+
+	names := make(map[string]bool)
+	for _, job := range cfg.Jobs {
+		if job.Name == "" || names[job.Name] {
+			return nil, fmt.Errorf("watch job names must be unique and not empty, got %q", job.Name)
+		}
+		names[job.Name] = true
+		schedule, err := ParseCron(job.Schedule)
+		if err != nil {
+			return nil, fmt.Errorf("watch job %s: %w", job.Name, err)
+		}
+		period, err := job.Period(s.now().In(s.location))
+		if err != nil {
+			return nil, fmt.Errorf("watch job %s: %w", job.Name, err)
+		}
+		if _, err := job.Queries(period); err != nil {
+			return nil, fmt.Errorf("watch job %s: %w", job.Name, err)
+		}
+		s.jobs = append(s.jobs, &scheduledJob{WatchJob: job, schedule: schedule})
+	}
+	return s, nil
+}
+
+// JobStatus is a watch job together with the time it runs next and the
+// outcome of its last stored run.
+type JobStatus struct {
+	WatchJob
+	NextRun      time.Time  `json:"next_run"`
+	LastRun      *time.Time `json:"last_run,omitempty"`
+	LastSnapshot uint64     `json:"last_snapshot,omitempty"`
+	// Warnings of the validation of the records of the last run
+	Warnings []Warning `json:"warnings,omitempty"`
+}
+
+// Jobs returns the configured jobs in order with their next run and the
+// latest snapshot they stored.
+func (s *Scheduler) Jobs() []JobStatus {
+	last := make(map[string]SnapshotMeta)
+	if s.store != nil {
+		snapshots, err := s.store.List("")
+		if err != nil {
+			log.Printf("Error listing snapshots: %v", err)
+		}
+		// Newest first, the first snapshot of a job is its last run
+		for _, meta := range snapshots {
+			if _, ok := last[meta.Params["job"]]; !ok {
+				last[meta.Params["job"]] = meta
+			}
+		}
+	}
+
+	now := s.now().In(s.location)
+	jobs := make([]JobStatus, 0, len(s.jobs))
+	for _, job := range s.jobs {
+		status := JobStatus{WatchJob: job.WatchJob, NextRun: job.schedule.Next(now)}
+		if meta, ok := last[job.Name]; ok {
+			status.LastRun = &meta.FetchedAt
+			status.LastSnapshot = meta.ID
+			status.Warnings = meta.Warnings
+		}
+		jobs = append(jobs, status)
+	}
+	return jobs
+}
+
+func supportedLocale(l Locale) Locale {
+	if isSupportedLocale(l) {
+		return l
+	}
+	return DefaultLocale
+}
+
+// Run executes the jobs on their schedules until ctx is cancelled.
+func (s *Scheduler) Run(ctx context.Context) {
+	if len(s.jobs) == 0 {
+		return
+	}
+	for _, job := range s.jobs {
+		job.next = job.schedule.Next(s.now().In(s.location))
+	}
+	for {
+		var due time.Time
+		for _, job := range s.jobs {
+			if !job.next.IsZero() && (due.IsZero() || job.next.Before(due)) {
+				due = job.next
+			}
+		}
+		if due.IsZero() {
+			return
+		}
+
+		timer := time.NewTimer(due.Sub(s.now()))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		for _, job := range s.jobs {
+			if job.next.After(due) {
+				continue
+			}
+			if _, err := s.RunJob(ctx, job.WatchJob); err != nil {
+				log.Printf("Watch job %s failed: %v", job.Name, err)
+			}
+			job.next = job.schedule.Next(s.now().In(s.location))
+		}
+	}
+}
+
+// RunJob fetches the schedule of the job, validates it, stores it as a
+// snapshot with its warnings and notifies the changes against the previous
+// snapshot of the job. The notification is returned even when nothing changed.
+func (s *Scheduler) RunJob(ctx context.Context, job WatchJob) (Notification, error) {
+	n := Notification{Job: job.Name, Carrier: job.Carrier, Locale: s.locale}
+
+	period, err := job.Period(s.now().In(s.location))
+	if err != nil {
+		return n, err
+	}
+	queries, err := job.Queries(period)
+	if err != nil {
+		return n, err
+	}
+	data, err := s.fetch(ctx, queries)
+	if err != nil {
+		return n, err
+	}
+	records, warnings, err := PrepareValidatedRecords(data, ExportOptions{Separate: job.Separate, TimeMode: job.TimeMode}, DefaultRules(queries))
+	if err != nil {
+		return n, err
+	}
+
+	previous, err := s.previousSnapshot(job.Name, period.Code)
+	if err != nil && !errors.Is(err, ErrSnapshotNotFound) {
+		return n, err
+	}
+	snap := Snapshot{
+		SnapshotMeta: SnapshotMeta{
+			Carrier:   job.Carrier,
+			From:      period.Start,
+			To:        period.End,
+			FetchedAt: s.now().UTC(),
+			Params: map[string]string{
+				"job":      job.Name,
+				"season":   period.Code,
+				"separate": strconv.FormatBool(job.Separate),
+			},
+		},
+		Records: records,
+	}
+	snap.Warnings = warnings
+	if len(snap.Warnings) > 0 {
+		log.Printf("Watch job %s: %d validation warnings", job.Name, len(snap.Warnings))
+	}
+	if err := s.store.Save(&snap); err != nil {
+		return n, err
+	}
+	n.ToSnapshot = snap.ID
+	if previous.ID == 0 {
+		return n, nil
+	}
+
+	n.FromSnapshot = previous.ID
+	n.Changes = DiffRecords(previous.Records, records)
+	if len(n.Changes) == 0 {
+		return n, nil
+	}
+	var errs []error
+	for _, notifier := range s.notifiers {
+		errs = append(errs, notifier.Notify(ctx, n))
+	}
+	return n, errors.Join(errs...)
+}
+
+// previousSnapshot returns the latest snapshot stored by the job for the season.
+func (s *Scheduler) previousSnapshot(job, season string) (Snapshot, error) {
+	snapshots, err := s.store.List("")
+	if err != nil {
+		return Snapshot{}, err
+	}
+	for _, meta := range snapshots {
+		if meta.Params["job"] == job && meta.Params["season"] == season {
+			return s.store.Get(meta.ID)
+		}
+	}
+	return Snapshot{}, ErrSnapshotNotFound
+}
@@ -0,0 +1,140 @@
+package internal
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSchedulerRunJob(t *testing.T) {
+	store, err := OpenSnapshotStore(filepath.Join(t.TempDir(), "snapshots.db"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer store.Close()
+
+	notified := 0
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { notified++ }))
+	defer webhook.Close()
+
+	// The way back stays the same
+	back := `{"airline":"LH","flightNumber":1364,"periodOfOperationLT":{"startDate":"30MAR25","endDate":"26APR25","daysOfOperation":"1234567"},"legs":[{"origin":"FRA","destination":"KRK","aircraftType":"E95","aircraftDepartureTimeLT":800,"aircraftArrivalTimeLT":890}]}`
+	responses := []string{
+		`[{"airline":"LH","flightNumber":1365,"periodOfOperationLT":{"startDate":"30MAR25","endDate":"26APR25","daysOfOperation":"1234567"},"legs":[{"origin":"KRK","destination":"FRA","aircraftType":"32N","aircraftDepartureTimeLT":620,"aircraftArrivalTimeLT":725}]},` + back + `]`,
+		`[{"airline":"LH","flightNumber":1365,"periodOfOperationLT":{"startDate":"30MAR25","endDate":"26APR25","daysOfOperation":"1234567"},"legs":[{"origin":"KRK","destination":"FRA","aircraftType":"320","aircraftDepartureTimeLT":620,"aircraftArrivalTimeLT":725}]},` + back + `]`,
+		`[{"airline":"LH","flightNumber":1365,"periodOfOperationLT":{"startDate":"30MAR25","endDate":"26APR25","daysOfOperation":"1234567"},"legs":[{"origin":"KRK","destination":"FRA","aircraftType":"320","aircraftDepartureTimeLT":620,"aircraftArrivalTimeLT":725}]},` + back + `]`,
+	}
+	var fetched [][]ApiQuery
+	fetch := func(_ context.Context, queries []ApiQuery) ([]byte, error) {
+		fetched = append(fetched, queries)
+		return []byte(responses[len(fetched)-1]), nil
+	}
+
+	cfg := WatchConfig{
+		Jobs:     []WatchJob{{Name: "krk-fra", Schedule: "0 6 * * *", Carrier: "LH", Origin: "krk", Destination: "fra"}},
+		Webhooks: []WebhookNotifier{{URL: webhook.URL}},
+	}
+	scheduler, err := NewScheduler(cfg, fetch, store)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	scheduler.now = func() time.Time { return time.Date(2025, 3, 1, 6, 0, 0, 0, time.UTC) }
+
+	first, err := scheduler.RunJob(context.Background(), cfg.Jobs[0])
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if first.FromSnapshot != 0 || first.ToSnapshot == 0 || notified != 0 {
+		t.Errorf("expected first run to only store a snapshot, got %+v", first)
+	}
+	q := fetched[0][0]
+	if len(fetched[0]) != 1 || q.Origin != "KRK" || q.Destination != "FRA" || q.StartDate != "27OCT24" || q.EndDate != "29MAR25" {
+		t.Errorf("expected KRK-FRA query for W24, got %+v", fetched[0])
+	}
+
+	second, err := scheduler.RunJob(context.Background(), cfg.Jobs[0])
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if second.FromSnapshot != first.ToSnapshot || len(second.Changes) != 7 || second.Changes[0].Kind != ChangeAircraft || notified != 1 {
+		t.Errorf("expected aircraft changes on every weekday to be notified once, got %+v (notified %d)", second, notified)
+	}
+
+	// Without reference data every aircraft type is unknown
+	defer SetReferences(References())
+	SetReferences(ReferenceData{})
+	third, err := scheduler.RunJob(context.Background(), cfg.Jobs[0])
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(third.Changes) != 0 || notified != 1 {
+		t.Errorf("expected no notification without changes, got %+v (notified %d)", third, notified)
+	}
+
+	snapshots, _ := store.List("LH")
+	if len(snapshots) != 3 || snapshots[0].Params["job"] != "krk-fra" || snapshots[0].Params["season"] != "W24" {
+		t.Errorf("unexpected snapshots %+v", snapshots)
+	}
+	if len(snapshots[1].Warnings) != 0 || len(snapshots[0].Warnings) != 2 || snapshots[0].Warnings[0].Rule != "unknown_aircraft" {
+		t.Errorf("expected unknown aircraft warnings on the last snapshot only, got %+v and %+v", snapshots[1].Warnings, snapshots[0].Warnings)
+	}
+
+	jobs := scheduler.Jobs()
+	if jobs[0].LastRun == nil || jobs[0].LastSnapshot != third.ToSnapshot || len(jobs[0].Warnings) != 2 {
+		t.Errorf("expected the status of the last run, got %+v", jobs[0])
+	}
+}
+
+func TestSchedulerJobs(t *testing.T) {
+	cfg := WatchConfig{
+		Timezone: "Europe/Warsaw",
+		Jobs: []WatchJob{
+			{Name: "lh", Schedule: "0 6 * * *", Carrier: "LH"},
+			{Name: "os", Schedule: "@weekly", Carrier: "OS", Season: "next"},
+		},
+	}
+	scheduler, err := NewScheduler(cfg, nil, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	scheduler.now = func() time.Time { return time.Date(2025, 3, 5, 12, 0, 0, 0, time.UTC) }
+
+	warsaw, _ := time.LoadLocation("Europe/Warsaw")
+	expected := []time.Time{
+		time.Date(2025, 3, 6, 6, 0, 0, 0, warsaw),
+		time.Date(2025, 3, 9, 0, 0, 0, 0, warsaw),
+	}
+	jobs := scheduler.Jobs()
+	if len(jobs) != len(expected) {
+		t.Fatalf("expected %d jobs, got %d", len(expected), len(jobs))
+	}
+	for i, job := range jobs {
+		if job.Name != cfg.Jobs[i].Name || !job.NextRun.Equal(expected[i]) {
+			t.Errorf("Test %s failed: expected next run %v, got %v", cfg.Jobs[i].Name, expected[i], job.NextRun)
+		}
+	}
+}
+
+func TestNewSchedulerValidation(t *testing.T) {
+	tests := []struct {
+		name string
+		jobs []WatchJob
+	}{
+		{name: "Invalid schedule", jobs: []WatchJob{{Name: "a", Schedule: "every day", Carrier: "LH"}}},
+		{name: "Unknown carrier", jobs: []WatchJob{{Name: "a", Schedule: "@daily", Carrier: "XX"}}},
+		{name: "Invalid airport", jobs: []WatchJob{{Name: "a", Schedule: "@daily", Carrier: "LH", Origin: "KRK", Destination: "FR"}}},
+		{name: "Duplicated name", jobs: []WatchJob{{Name: "a", Schedule: "@daily", Carrier: "LH"}, {Name: "a", Schedule: "@daily", Carrier: "OS"}}},
+		{name: "Invalid season", jobs: []WatchJob{{Name: "a", Schedule: "@daily", Carrier: "LH", Season: "summer"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewScheduler(WatchConfig{Jobs: tt.jobs}, nil, nil); err == nil {
+				t.Errorf("expected error")
+			}
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Season is an IATA scheduling season. Summer runs from the last Sunday of
+// March up to the last Sunday of October, winter from there up to the last
+// Sunday of March of the next year, both ending on the Saturday before.
+type Season struct {
+	// Code such as S25 or W25, winter seasons carry the year they start in
+	Code  string
+	Start time.Time
+	End   time.Time
+}
+
+func (s Season) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Code string `json:"code"`
+		From string `json:"from"`
+		To   string `json:"to"`
+	}{s.Code, s.Start.Format(dateLayout), s.End.Format(dateLayout)})
+}
+
+// Contains reports whether date falls within the season.
+func (s Season) Contains(date time.Time) bool {
+	return !date.Before(s.Start) && !date.After(s.End)
+}
+
+// Next returns the season following s.
+func (s Season) Next() Season {
+	return SeasonOf(s.End.AddDate(0, 0, 1))
+}
+
+// lastSunday returns the last Sunday of the month.
+func lastSunday(year int, month time.Month) time.Time {
+	last := time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC)
+	return last.AddDate(0, 0, -int(last.Weekday()))
+}
+
+func SummerSeason(year int) Season {
+	return Season{
+		Code:  fmt.Sprintf("S%02d", year%100),
+		Start: lastSunday(year, time.March),
+		End:   lastSunday(year, time.October).AddDate(0, 0, -1),
+	}
+}
+
+func WinterSeason(year int) Season {
+	return Season{
+		Code:  fmt.Sprintf("W%02d", year%100),
+		Start: lastSunday(year, time.October),
+		End:   lastSunday(year+1, time.March).AddDate(0, 0, -1),
+	}
+}
+
+// SeasonOf returns the season date falls in.
+func SeasonOf(date time.Time) Season {
+	date = time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
+	if summer := SummerSeason(date.Year()); summer.Contains(date) {
+		return summer
+	}
+	if date.Before(lastSunday(date.Year(), time.March)) {
+		return WinterSeason(date.Year() - 1)
+	}
+	return WinterSeason(date.Year())
+}
+
+// ParseSeason reads a season code such as S25 or W25.
+func ParseSeason(code string) (Season, error) {
+	if len(code) != 3 {
+		return Season{}, fmt.Errorf("invalid season %q", code)
+	}
+	yy, err := strconv.Atoi(code[1:])
+	if err != nil || yy < 0 {
+		return Season{}, fmt.Errorf("invalid season %q", code)
+	}
+	switch code[0] {
+	case 'S', 's':
+		return SummerSeason(2000 + yy), nil
+	case 'W', 'w':
+		return WinterSeason(2000 + yy), nil
+	default:
+		return Season{}, fmt.Errorf("invalid season %q", code)
+	}
+}
+
+// UpcomingSeasons lists the season of date followed by the next n seasons.
+func UpcomingSeasons(date time.Time, n int) []Season {
+	seasons := []Season{SeasonOf(date)}
+	for i := 0; i < n; i++ {
+		seasons = append(seasons, seasons[len(seasons)-1].Next())
+	}
+	return seasons
+}
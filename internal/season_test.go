@@ -0,0 +1,71 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestSeasons(t *testing.T) {
+	tests := []struct {
+		code string
+		from string
+		to   string
+	}{
+		{code: "S24", from: "2024-03-31", to: "2024-10-26"},
+		{code: "W24", from: "2024-10-27", to: "2025-03-29"},
+		{code: "S25", from: "2025-03-30", to: "2025-10-25"},
+		{code: "W25", from: "2025-10-26", to: "2026-03-28"},
+		// 31 October 2026 is a Saturday, the season still ends before the last Sunday
+		{code: "S26", from: "2026-03-29", to: "2026-10-24"},
+		{code: "W26", from: "2026-10-25", to: "2027-03-27"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.code, func(t *testing.T) {
+			s, err := ParseSeason(tt.code)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if from, to := s.Start.Format(dateLayout), s.End.Format(dateLayout); from != tt.from || to != tt.to {
+				t.Errorf("expected %s - %s, got %s - %s", tt.from, tt.to, from, to)
+			}
+			if s.Code != tt.code {
+				t.Errorf("expected code %s, got %s", tt.code, s.Code)
+			}
+			if got := SeasonOf(s.Start); got != s {
+				t.Errorf("expected season of %s to be %v, got %v", tt.from, s, got)
+			}
+			if got := SeasonOf(s.End); got != s {
+				t.Errorf("expected season of %s to be %v, got %v", tt.to, s, got)
+			}
+		})
+	}
+
+	for _, code := range []string{"X25", "S2", "Sab", ""} {
+		if _, err := ParseSeason(code); err == nil {
+			t.Errorf("expected error for %q", code)
+		}
+	}
+}
+
+func TestUpcomingSeasons(t *testing.T) {
+	date := time.Date(2026, 1, 15, 10, 0, 0, 0, time.Local)
+	var codes []string
+	for _, s := range UpcomingSeasons(date, 3) {
+		codes = append(codes, s.Code)
+	}
+	expected := "W25 S26 W26 S27"
+	if got := fmt.Sprint(codes); got != "["+expected+"]" {
+		t.Errorf("expected %s, got %s", expected, got)
+	}
+
+	data, err := json.Marshal(UpcomingSeasons(date, 0))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := string(data); got != `[{"code":"W25","from":"2025-10-26","to":"2026-03-28"}]` {
+		t.Errorf("unexpected JSON %s", got)
+	}
+}
@@ -0,0 +1,249 @@
+package internal
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	ErrSnapshotNotFound = errors.New("snapshot not found")
+	// ErrNoEarlierSnapshot is returned when no earlier snapshot was built
+	// with the same query as the latest one
+	ErrNoEarlierSnapshot = errors.New("no earlier snapshot with the same parameters")
+)
+
+// formatParams only change how the records are written, snapshots differing
+// in them hold the same schedule.
+var formatParams = []string{"template", "columns", "delimiter", "date-format"}
+
+var (
+	snapshotMetaBucket    = []byte("meta")
+	snapshotRecordsBucket = []byte("records")
+)
+
+// SnapshotMeta describes a stored snapshot without its records.
+type SnapshotMeta struct {
+	ID        uint64    `json:"id"`
+	Carrier   string    `json:"carrier"`
+	From      time.Time `json:"from"`
+	To        time.Time `json:"to"`
+	FetchedAt time.Time `json:"fetched_at"`
+	// Params holds the query and export options the records were built with
+	Params      map[string]string `json:"params,omitempty"`
+	RecordCount int               `json:"record_count"`
+	// Warnings the validation of the records raised when they were exported
+	Warnings []Warning `json:"warnings,omitempty"`
+}
+
+// SameQuery reports whether both snapshots hold the schedule of the same
+// carrier, period and query, so their records can be compared.
+func (m SnapshotMeta) SameQuery(other SnapshotMeta) bool {
+	if m.Carrier != other.Carrier || !m.From.Equal(other.From) || !m.To.Equal(other.To) {
+		return false
+	}
+	query := func(params map[string]string) map[string]string {
+		q := maps.Clone(params)
+		for _, p := range formatParams {
+			delete(q, p)
+		}
+		return q
+	}
+	return maps.Equal(query(m.Params), query(other.Params))
+}
+
+// Snapshot is the normalized schedule of a carrier as fetched at a point in time.
+type Snapshot struct {
+	SnapshotMeta
+	Records []ScheduleRecord `json:"records"`
+}
+
+// snapshotLockTimeout is how long an operation waits for another process
+// using the database file.
+const snapshotLockTimeout = 10 * time.Second
+
+// SnapshotStore keeps snapshots in a bbolt database file. bbolt locks the
+// file while it is open, so it is opened for each operation only and the
+// server and the command line can use the same file side by side.
+type SnapshotStore struct {
+	path string
+	// mu orders the operations of the process, the file lock only those of
+	// different processes
+	mu sync.RWMutex
+}
+
+func OpenSnapshotStore(path string) (*SnapshotStore, error) {
+	s := &SnapshotStore{path: path}
+	err := s.update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{snapshotMetaBucket, snapshotRecordsBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Close is kept for the callers, the file is not held open between operations.
+func (s *SnapshotStore) Close() error {
+	return nil
+}
+
+func (s *SnapshotStore) open(readOnly bool) (*bolt.DB, error) {
+	db, err := bolt.Open(s.path, 0o600, &bolt.Options{Timeout: snapshotLockTimeout, ReadOnly: readOnly})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open snapshot store: %w", err)
+	}
+	return db, nil
+}
+
+func (s *SnapshotStore) view(fn func(*bolt.Tx) error) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	db, err := s.open(true)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	return db.View(fn)
+}
+
+func (s *SnapshotStore) update(fn func(*bolt.Tx) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	db, err := s.open(false)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	return db.Update(fn)
+}
+
+func snapshotKey(id uint64) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, id)
+	return key
+}
+
+// Save stores the snapshot under a new ID, which is set on snap.
+func (s *SnapshotStore) Save(snap *Snapshot) error {
+	if snap.FetchedAt.IsZero() {
+		snap.FetchedAt = time.Now().UTC()
+	}
+	snap.RecordCount = len(snap.Records)
+
+	return s.update(func(tx *bolt.Tx) error {
+		meta := tx.Bucket(snapshotMetaBucket)
+		id, err := meta.NextSequence()
+		if err != nil {
+			return err
+		}
+		snap.ID = id
+
+		metaData, err := json.Marshal(snap.SnapshotMeta)
+		if err != nil {
+			return err
+		}
+		records, err := json.Marshal(snap.Records)
+		if err != nil {
+			return err
+		}
+		if err := meta.Put(snapshotKey(id), metaData); err != nil {
+			return err
+		}
+		return tx.Bucket(snapshotRecordsBucket).Put(snapshotKey(id), records)
+	})
+}
+
+// List returns the snapshots of the carrier, all when carrier is empty,
+// newest first.
+func (s *SnapshotStore) List(carrier string) ([]SnapshotMeta, error) {
+	snapshots := []SnapshotMeta{}
+	err := s.view(func(tx *bolt.Tx) error {
+		return tx.Bucket(snapshotMetaBucket).ForEach(func(_, v []byte) error {
+			var meta SnapshotMeta
+			if err := json.Unmarshal(v, &meta); err != nil {
+				return err
+			}
+			if carrier == "" || meta.Carrier == carrier {
+				snapshots = append(snapshots, meta)
+			}
+			return nil
+		})
+	})
+	slices.Reverse(snapshots)
+	return snapshots, err
+}
+
+// Latest returns the newest snapshot of the carrier.
+func (s *SnapshotStore) Latest(carrier string) (Snapshot, error) {
+	snapshots, err := s.List(carrier)
+	if err != nil {
+		return Snapshot{}, err
+	}
+	if len(snapshots) == 0 {
+		return Snapshot{}, ErrSnapshotNotFound
+	}
+	return s.Get(snapshots[0].ID)
+}
+
+// LatestPair returns the newest snapshot of the carrier, of any carrier when
+// carrier is empty, and the latest earlier one with the same query.
+func (s *SnapshotStore) LatestPair(carrier string) (older, newer Snapshot, err error) {
+	snapshots, err := s.List(carrier)
+	if err != nil {
+		return Snapshot{}, Snapshot{}, err
+	}
+	if len(snapshots) == 0 {
+		return Snapshot{}, Snapshot{}, ErrSnapshotNotFound
+	}
+	for _, meta := range snapshots[1:] {
+		if meta.SameQuery(snapshots[0]) {
+			if older, err = s.Get(meta.ID); err != nil {
+				return Snapshot{}, Snapshot{}, err
+			}
+			newer, err = s.Get(snapshots[0].ID)
+			return older, newer, err
+		}
+	}
+	return Snapshot{}, Snapshot{}, ErrNoEarlierSnapshot
+}
+
+func (s *SnapshotStore) Get(id uint64) (Snapshot, error) {
+	var snap Snapshot
+	err := s.view(func(tx *bolt.Tx) error {
+		metaData := tx.Bucket(snapshotMetaBucket).Get(snapshotKey(id))
+		if metaData == nil {
+			return ErrSnapshotNotFound
+		}
+		if err := json.Unmarshal(metaData, &snap.SnapshotMeta); err != nil {
+			return err
+		}
+		return json.Unmarshal(tx.Bucket(snapshotRecordsBucket).Get(snapshotKey(id)), &snap.Records)
+	})
+	return snap, err
+}
+
+func (s *SnapshotStore) Delete(id uint64) error {
+	return s.update(func(tx *bolt.Tx) error {
+		meta := tx.Bucket(snapshotMetaBucket)
+		if meta.Get(snapshotKey(id)) == nil {
+			return ErrSnapshotNotFound
+		}
+		if err := meta.Delete(snapshotKey(id)); err != nil {
+			return err
+		}
+		return tx.Bucket(snapshotRecordsBucket).Delete(snapshotKey(id))
+	})
+}
@@ -0,0 +1,147 @@
+package internal
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSnapshotStore(t *testing.T) {
+	store, err := OpenSnapshotStore(filepath.Join(t.TempDir(), "snapshots.db"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer store.Close()
+
+	record := withExceptions(testRecord("KRK", "FRA", "LH", "1365", "10:20", "12:05", "2025-03-30", "2025-10-25", "1.3.5..", "32N", "LH", "J"), "2025-04-02")
+	record.Registration = "DAINA"
+	fetchedAt := time.Date(2025, 3, 1, 8, 0, 0, 0, time.UTC)
+
+	first := Snapshot{
+		SnapshotMeta: SnapshotMeta{Carrier: "LH", FetchedAt: fetchedAt, Params: map[string]string{"separate": "on"}},
+		Records:      []ScheduleRecord{record},
+	}
+	second := Snapshot{SnapshotMeta: SnapshotMeta{Carrier: "LX", FetchedAt: fetchedAt.AddDate(0, 0, 1)}}
+	third := Snapshot{SnapshotMeta: SnapshotMeta{Carrier: "LH", FetchedAt: fetchedAt.AddDate(0, 0, 2)}}
+	for _, snap := range []*Snapshot{&first, &second, &third} {
+		if err := store.Save(snap); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if first.ID != 1 || second.ID != 2 || third.ID != 3 {
+		t.Errorf("expected sequential IDs, got %d %d %d", first.ID, second.ID, third.ID)
+	}
+
+	got, err := store.Get(first.ID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(got, first) {
+		t.Errorf("expected %v, got %v", first, got)
+	}
+	if got.RecordCount != 1 {
+		t.Errorf("expected record count 1, got %d", got.RecordCount)
+	}
+
+	list, err := store.List("LH")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(list) != 2 || list[0].ID != third.ID || list[1].ID != first.ID {
+		t.Errorf("expected LH snapshots newest first, got %v", list)
+	}
+	if all, _ := store.List(""); len(all) != 3 {
+		t.Errorf("expected 3 snapshots, got %v", all)
+	}
+	if latest, err := store.Latest("LX"); err != nil || latest.ID != second.ID {
+		t.Errorf("expected latest LX snapshot %d, got %d (%v)", second.ID, latest.ID, err)
+	}
+
+	if err := store.Delete(first.ID); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := store.Get(first.ID); !errors.Is(err, ErrSnapshotNotFound) {
+		t.Errorf("expected not found after delete, got %v", err)
+	}
+	if err := store.Delete(first.ID); !errors.Is(err, ErrSnapshotNotFound) {
+		t.Errorf("expected not found for second delete, got %v", err)
+	}
+	if _, err := store.Latest("SN"); !errors.Is(err, ErrSnapshotNotFound) {
+		t.Errorf("expected not found without snapshots, got %v", err)
+	}
+}
+
+func TestSnapshotStoreLatestPair(t *testing.T) {
+	store, err := OpenSnapshotStore(filepath.Join(t.TempDir(), "snapshots.db"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer store.Close()
+
+	from := time.Date(2025, 3, 30, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2025, 10, 25, 0, 0, 0, 0, time.UTC)
+	carrier := SnapshotMeta{Carrier: "LH", From: from, To: to, Params: map[string]string{"separate": "on", "delimiter": "comma"}}
+	route := SnapshotMeta{Carrier: "LH", From: from, To: to, Params: map[string]string{"separate": "on", "origin": "KRK", "destination": "FRA"}}
+	job := SnapshotMeta{Carrier: "LH", From: from, To: to, Params: map[string]string{"job": "lh-krk", "season": "S25"}}
+	// The same query written with another delimiter
+	latest := SnapshotMeta{Carrier: "LH", From: from, To: to, Params: map[string]string{"separate": "on", "delimiter": "semicolon"}}
+	for _, meta := range []SnapshotMeta{carrier, route, job, latest} {
+		if err := store.Save(&Snapshot{SnapshotMeta: meta}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	older, newer, err := store.LatestPair("LH")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if older.ID != 1 || newer.ID != 4 {
+		t.Errorf("expected snapshots 1 and 4, got %d and %d", older.ID, newer.ID)
+	}
+
+	if err := store.Save(&Snapshot{SnapshotMeta: route}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if older, newer, err := store.LatestPair(""); err != nil || older.ID != 2 || newer.ID != 5 {
+		t.Errorf("expected snapshots 2 and 5, got %d and %d (%v)", older.ID, newer.ID, err)
+	}
+
+	// A season fetched for the first time has nothing to compare with
+	next := SnapshotMeta{Carrier: "LH", From: to.AddDate(0, 0, 1), To: to.AddDate(0, 5, 0), Params: carrier.Params}
+	if err := store.Save(&Snapshot{SnapshotMeta: next}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, _, err := store.LatestPair("LH"); !errors.Is(err, ErrNoEarlierSnapshot) {
+		t.Errorf("expected no earlier snapshot, got %v", err)
+	}
+	if _, _, err := store.LatestPair("SN"); !errors.Is(err, ErrSnapshotNotFound) {
+		t.Errorf("expected not found without snapshots, got %v", err)
+	}
+}
+
+func TestSnapshotStoreShared(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "snapshots.db")
+	// The server keeps its store for its whole lifetime
+	server, err := OpenSnapshotStore(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer server.Close()
+	if err := server.Save(&Snapshot{SnapshotMeta: SnapshotMeta{Carrier: "LH"}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	cli, err := OpenSnapshotStore(path)
+	if err != nil {
+		t.Fatalf("expected a second store on the same file, got %v", err)
+	}
+	defer cli.Close()
+	if err := cli.Save(&Snapshot{SnapshotMeta: SnapshotMeta{Carrier: "LX"}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if list, err := server.List(""); err != nil || len(list) != 2 {
+		t.Errorf("expected both snapshots, got %v (%v)", list, err)
+	}
+}
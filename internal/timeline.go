@@ -0,0 +1,290 @@
+package internal
+
+import (
+	"cmp"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+)
+
+// TimelineColorings are the flight attributes the bars of a timeline can be
+// coloured by.
+var TimelineColorings = []string{"carrier", "aircraft"}
+
+// TimelineBar is a flight drawn from its departure to its arrival, in
+// minutes since midnight of the day it departs on. End passes a day for
+// flights arriving after midnight.
+type TimelineBar struct {
+	Flight      string `json:"flight"`
+	Origin      string `json:"origin"`
+	Destination string `json:"destination"`
+	Departure   string `json:"departure"`
+	Arrival     string `json:"arrival"`
+	Start       int    `json:"start"`
+	End         int    `json:"end"`
+	// Key is the carrier or aircraft type the bar is coloured by
+	Key string `json:"key"`
+	// Lane is the line of the row the bar is drawn in, bars overlapping in
+	// time take separate lanes
+	Lane int `json:"lane"`
+}
+
+// TimelineRow holds the flights departing on a weekday or a date.
+type TimelineRow struct {
+	Weekday int `json:"weekday"`
+	// Date is nil for the rows of a weekly pattern
+	Date  *time.Time    `json:"date,omitempty"`
+	Lanes int           `json:"lanes"`
+	Bars  []TimelineBar `json:"bars"`
+}
+
+// Timeline places the flights of a schedule on a grid of days and local
+// departure times.
+type Timeline struct {
+	ColorBy string        `json:"color_by"`
+	From    time.Time     `json:"from"`
+	To      time.Time     `json:"to"`
+	Rows    []TimelineRow `json:"rows"`
+	// Keys lists the carriers or aircraft types of the bars in legend order
+	Keys []string `json:"keys"`
+}
+
+// BuildTimeline draws the flights departing in the period by their local
+// departure date. With byWeekday the rows are the weekdays and hold every
+// flight departing on one of their dates once, otherwise there is a row per
+// date of the period.
+func BuildTimeline(records []ScheduleRecord, colorBy string, byWeekday bool, from, to time.Time) (Timeline, error) {
+	colorBy = cmp.Or(strings.ToLower(strings.TrimSpace(colorBy)), "carrier")
+	if !slices.Contains(TimelineColorings, colorBy) {
+		return Timeline{}, fmt.Errorf("invalid colouring %q", colorBy)
+	}
+	if to.Before(from) {
+		return Timeline{}, fmt.Errorf("period ends before it starts")
+	}
+
+	timeline := Timeline{ColorBy: colorBy, From: from, To: to}
+	index := make(map[time.Time]int)
+	if byWeekday {
+		for day := 1; day <= 7; day++ {
+			timeline.Rows = append(timeline.Rows, TimelineRow{Weekday: day})
+		}
+	} else {
+		for date := from; !date.After(to); date = date.AddDate(0, 0, 1) {
+			index[date] = len(timeline.Rows)
+			day := date
+			timeline.Rows = append(timeline.Rows, TimelineRow{Weekday: WeekdayOf(date), Date: &day})
+		}
+	}
+
+	type rowFlight struct {
+		row    int
+		flight Flight
+	}
+	seen := make(map[rowFlight]bool)
+	keys := make(map[string]bool)
+	for _, op := range ExpandRecords(records) {
+		date := op.Date.AddDate(0, 0, op.Flight.DepartureDateDiff)
+		if date.Before(from) || date.After(to) {
+			continue
+		}
+		row := WeekdayOf(date) - 1
+		if !byWeekday {
+			row = index[date]
+		}
+		if seen[rowFlight{row, op.Flight}] {
+			continue
+		}
+		seen[rowFlight{row, op.Flight}] = true
+
+		bar := timelineBar(op.Flight, colorBy)
+		keys[bar.Key] = true
+		timeline.Rows[row].Bars = append(timeline.Rows[row].Bars, bar)
+	}
+
+	for i := range timeline.Rows {
+		timeline.Rows[i].assignLanes()
+	}
+	timeline.Keys = slices.Sorted(maps.Keys(keys))
+	return timeline, nil
+}
+
+func timelineBar(f Flight, colorBy string) TimelineBar {
+	start := int(f.Departure)
+	// Local arrival moved into the time zone of the departure, so that flights
+	// across time zones keep their length
+	end := int(f.Arrival) + minutesPerDay*arrivalDays(f) - f.ArrivalVariation + f.DepartureVariation
+	key := f.Airline
+	if colorBy == "aircraft" {
+		key = f.AircraftType
+	}
+	return TimelineBar{
+		Flight:      flightDesignator(f),
+		Origin:      f.Origin,
+		Destination: f.Destination,
+		Departure:   f.Departure.String(),
+		Arrival:     f.Arrival.String(),
+		Start:       start,
+		End:         end,
+		Key:         key,
+	}
+}
+
+// assignLanes orders the bars of the row by time and puts each one in the
+// first lane free at its start.
+func (r *TimelineRow) assignLanes() {
+	slices.SortStableFunc(r.Bars, func(a, b TimelineBar) int {
+		return cmp.Or(cmp.Compare(a.Start, b.Start), cmp.Compare(a.End, b.End), strings.Compare(a.Flight, b.Flight))
+	})
+	var laneEnds []int
+	for i, bar := range r.Bars {
+		lane := slices.IndexFunc(laneEnds, func(end int) bool { return end <= bar.Start })
+		if lane < 0 {
+			lane = len(laneEnds)
+			laneEnds = append(laneEnds, 0)
+		}
+		laneEnds[lane] = bar.End
+		r.Bars[i].Lane = lane
+	}
+	r.Lanes = max(len(laneEnds), 1)
+}
+
+// timelinePalette colours the keys of a timeline in legend order.
+var timelinePalette = []color.RGBA{
+	{0x3f, 0x4e, 0x7f, 0xff}, {0xe0, 0x7a, 0x5f, 0xff}, {0x81, 0xb2, 0x9a, 0xff}, {0xf2, 0xcc, 0x8f, 0xff},
+	{0x6d, 0x59, 0x7a, 0xff}, {0x3d, 0x9a, 0xb0, 0xff}, {0xb5, 0x65, 0x76, 0xff}, {0x9c, 0x89, 0x5e, 0xff},
+}
+
+func (t Timeline) color(key string) color.RGBA {
+	i, _ := slices.BinarySearch(t.Keys, key)
+	return timelinePalette[i%len(timelinePalette)]
+}
+
+func hexColor(c color.RGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
+
+// Layout of the timeline in pixels, the grid uses the width of the movement chart.
+const (
+	timelineLaneHeight = 18
+	timelineRowPadding = 4
+	timelineLegendLine = 20
+	timelineLegendItem = 96
+)
+
+// timelineLayout returns the top of each row, the bottom of the grid and
+// the size of the picture.
+func (t Timeline) timelineLayout() (tops []int, gridBottom, width, height int) {
+	y := chartHeaderSize
+	for _, row := range t.Rows {
+		tops = append(tops, y)
+		y += row.Lanes*timelineLaneHeight + 2*timelineRowPadding
+	}
+	lines := (len(t.Keys) + chartGridWidth/timelineLegendItem - 1) / (chartGridWidth / timelineLegendItem)
+	return tops, y, chartLabelWidth + chartGridWidth + 10, y + 10 + lines*timelineLegendLine
+}
+
+// legendBox returns the corner of the colour box of the i-th key, the keys
+// wrap over the width of the grid.
+func legendBox(i, gridBottom int) (x, y int) {
+	perLine := chartGridWidth / timelineLegendItem
+	return chartLabelWidth + i%perLine*timelineLegendItem, gridBottom + 10 + i/perLine*timelineLegendLine
+}
+
+// barBox returns the horizontal extent of a bar in pixels, cut at the end of
+// the day.
+func barBox(bar TimelineBar) (x, width float64) {
+	scale := float64(chartGridWidth) / minutesPerDay
+	end := min(bar.End, minutesPerDay)
+	return chartLabelWidth + float64(bar.Start)*scale, max(float64(end-bar.Start)*scale, 1)
+}
+
+// label returns the weekday or date of the row in the locale.
+func (r TimelineRow) label(l Locale, dateLayout string) string {
+	if r.Date != nil {
+		return r.Date.Format(dateLayout)
+	}
+	return T(l, fmt.Sprintf("weekday.%d", r.Weekday))
+}
+
+// WriteTimelineSVG draws the timeline with a row for each weekday or date
+// and an hour grid, bars arriving after midnight are cut at the end of the
+// day and keep their times in the tooltip.
+func WriteTimelineSVG(writer io.Writer, t Timeline, opts ExportOptions) error {
+	tops, gridBottom, width, height := t.timelineLayout()
+	hour := float64(chartGridWidth) / 24
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" font-family="sans-serif" font-size="11">`, width, height, width, height)
+	title := xmlEscape(T(opts.Locale, "timeline.title"))
+	fmt.Fprintf(&b, `<title>%s</title><text x="0" y="14" font-size="13" font-weight="bold">%s</text>`, title, title)
+	for h := 0; h <= 24; h++ {
+		x := chartLabelWidth + float64(h)*hour
+		fmt.Fprintf(&b, `<line x1="%.1f" y1="%d" x2="%.1f" y2="%d" stroke="#e5e7eb"/>`, x, chartHeaderSize, x, gridBottom)
+		if h%2 == 0 && h < 24 {
+			fmt.Fprintf(&b, `<text x="%.1f" y="%d">%02d</text>`, x, chartHeaderSize-6, h)
+		}
+	}
+	for i, row := range t.Rows {
+		top := tops[i]
+		fmt.Fprintf(&b, `<line x1="0" y1="%d" x2="%d" y2="%d" stroke="#e5e7eb"/>`, top, width, top)
+		fmt.Fprintf(&b, `<text x="0" y="%d">%s</text>`, top+timelineRowPadding+timelineLaneHeight-5, xmlEscape(row.label(opts.Locale, opts.format().DateLayout)))
+		for _, bar := range row.Bars {
+			x, w := barBox(bar)
+			y := top + timelineRowPadding + bar.Lane*timelineLaneHeight
+			fmt.Fprintf(&b, `<g><title>%s %s-%s %s-%s (%s)</title>`,
+				xmlEscape(bar.Flight), bar.Origin, bar.Destination, bar.Departure, bar.Arrival, xmlEscape(bar.Key))
+			fmt.Fprintf(&b, `<rect x="%.1f" y="%d" width="%.1f" height="%d" rx="2" fill="%s"/>`, x, y+1, w, timelineLaneHeight-2, hexColor(t.color(bar.Key)))
+			// Label the bars wide enough for the designator
+			if w >= float64(6*len(bar.Flight)+4) {
+				fmt.Fprintf(&b, `<text x="%.1f" y="%d" fill="#ffffff" font-size="10">%s</text>`, x+2, y+timelineLaneHeight-5, xmlEscape(bar.Flight))
+			}
+			b.WriteString(`</g>`)
+		}
+	}
+	for i, key := range t.Keys {
+		x, y := legendBox(i, gridBottom)
+		fmt.Fprintf(&b, `<rect x="%d" y="%d" width="12" height="12" fill="%s"/><text x="%d" y="%d">%s</text>`,
+			x, y, hexColor(t.color(key)), x+16, y+10, xmlEscape(key))
+	}
+	b.WriteString(`</svg>`)
+	_, err := io.WriteString(writer, b.String())
+	return err
+}
+
+// WriteTimelinePNG draws the grid and bars of the timeline like the SVG as
+// a PNG image. The image has no text since the standard library has no fonts.
+func WriteTimelinePNG(writer io.Writer, t Timeline) error {
+	tops, gridBottom, width, height := t.timelineLayout()
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(img, img.Bounds(), image.White, image.Point{}, draw.Src)
+	grid := image.NewUniform(color.RGBA{0xe5, 0xe7, 0xeb, 0xff})
+	fill := func(x0, y0, x1, y1 int, c image.Image) {
+		draw.Draw(img, image.Rect(x0, y0, x1, y1), c, image.Point{}, draw.Src)
+	}
+
+	for h := 0; h <= 24; h++ {
+		x := chartLabelWidth + h*chartGridWidth/24
+		fill(x, chartHeaderSize, x+1, gridBottom, grid)
+	}
+	for i, row := range t.Rows {
+		top := tops[i]
+		fill(0, top, width, top+1, grid)
+		for _, bar := range row.Bars {
+			x, w := barBox(bar)
+			y := top + timelineRowPadding + bar.Lane*timelineLaneHeight
+			fill(int(x), y+1, int(x+w+0.5), y+timelineLaneHeight-1, image.NewUniform(t.color(bar.Key)))
+		}
+	}
+	for i, key := range t.Keys {
+		x, y := legendBox(i, gridBottom)
+		fill(x, y, x+12, y+12, image.NewUniform(t.color(key)))
+	}
+	return png.Encode(writer, img)
+}
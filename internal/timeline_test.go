@@ -0,0 +1,110 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/xml"
+	"image/png"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBuildTimeline(t *testing.T) {
+	date := func(s string) time.Time {
+		d, _ := time.Parse(dateLayout, s)
+		return d
+	}
+	records := previewRecords()
+	// Overlaps LH1365 on Mondays and lands an hour later in local time
+	overlap := testRecord("KRK", "LHR", "LH", "2000", "11:00", "12:20", "2025-03-31", "2025-04-13", "1......", "E95", "CL", "J")
+	overlap.DepartureVariation, overlap.ArrivalVariation = 120, 60
+	records = append(records, overlap)
+
+	timeline, err := BuildTimeline(records, "", true, date("2025-03-31"), date("2025-04-13"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(timeline.Rows) != 7 || !slices.Equal(timeline.Keys, []string{"LH"}) {
+		t.Fatalf("unexpected timeline %+v", timeline)
+	}
+
+	tests := []struct {
+		name     string
+		row      TimelineRow
+		expected []string
+		lanes    int
+	}{
+		{name: "Monday", row: timeline.Rows[0], expected: []string{"LH1623 360-445 0", "LH1365 620-725 0", "LH2000 660-800 1"}, lanes: 2},
+		{name: "Tuesday with the overnight flight", row: timeline.Rows[1], expected: []string{"LH1623 360-445 0", "LH999 1370-1475 0"}, lanes: 1},
+		{name: "Sunday", row: timeline.Rows[6], expected: []string{"LH1623 360-445 0"}, lanes: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, bar := range tt.row.Bars {
+				got = append(got, bar.Flight+" "+strconv.Itoa(bar.Start)+"-"+strconv.Itoa(bar.End)+" "+strconv.Itoa(bar.Lane))
+			}
+			if !slices.Equal(got, tt.expected) || tt.row.Lanes != tt.lanes {
+				t.Errorf("Test %s failed: expected %v in %d lanes, got %v in %d", tt.name, tt.expected, tt.lanes, got, tt.row.Lanes)
+			}
+		})
+	}
+
+	daily, err := BuildTimeline(records, "aircraft", false, date("2025-04-01"), date("2025-04-02"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// LH1623 does not operate on 2 April
+	if len(daily.Rows) != 2 || daily.Rows[0].Date == nil || len(daily.Rows[0].Bars) != 2 || len(daily.Rows[1].Bars) != 1 {
+		t.Errorf("unexpected daily timeline %+v", daily.Rows)
+	}
+	if !slices.Equal(daily.Keys, []string{"320", "32N", "E95"}) {
+		t.Errorf("unexpected keys %v", daily.Keys)
+	}
+
+	if _, err := BuildTimeline(records, "owner", true, date("2025-03-31"), date("2025-04-13")); err == nil {
+		t.Errorf("expected error for an unknown colouring")
+	}
+}
+
+func TestWriteTimeline(t *testing.T) {
+	date, _ := time.Parse(dateLayout, "2025-03-31")
+	timeline, err := BuildTimeline(previewRecords(), "aircraft", true, date, date.AddDate(0, 0, 13))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteTimelineSVG(&buf, timeline, ExportOptions{Locale: LocaleEN}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	svg := buf.String()
+	decoder := xml.NewDecoder(strings.NewReader(svg))
+	for {
+		if _, err := decoder.Token(); err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatalf("invalid SVG: %v", err)
+		}
+	}
+	if !strings.Contains(svg, "Schedule timeline") || !strings.Contains(svg, "<title>LH999 KRK-FRA 22:50-00:35 (320)</title>") || !strings.Contains(svg, ">Sun</text>") {
+		t.Errorf("unexpected chart %.300s", svg)
+	}
+
+	buf.Reset()
+	if err := WriteTimelinePNG(&buf, timeline); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	img, err := png.Decode(&buf)
+	if err != nil {
+		t.Fatalf("invalid PNG: %v", err)
+	}
+	_, _, width, height := timeline.timelineLayout()
+	if b := img.Bounds(); b.Dx() != width || b.Dy() != height {
+		t.Errorf("expected %dx%d, got %v", width, height, b)
+	}
+}
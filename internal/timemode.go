@@ -0,0 +1,72 @@
+package internal
+
+import (
+	"fmt"
+	"strings"
+)
+
+// TimeMode selects whether schedules are read and exported in local or UTC times.
+type TimeMode string
+
+const (
+	TimeModeLT  TimeMode = "LT"
+	TimeModeUTC TimeMode = "UTC"
+	// TimeModeBoth exports local and UTC times side by side, periods stay local.
+	TimeModeBoth TimeMode = "BOTH"
+)
+
+// ParseTimeMode reads a time mode option, an empty option means local times.
+func ParseTimeMode(s string) (TimeMode, error) {
+	switch m := TimeMode(strings.ToUpper(strings.TrimSpace(s))); m {
+	case "":
+		return TimeModeLT, nil
+	case TimeModeLT, TimeModeUTC, TimeModeBoth:
+		return m, nil
+	default:
+		return "", fmt.Errorf("invalid time mode %q", s)
+	}
+}
+
+// Basis returns the time mode the periods and days of operation are given in.
+func (m TimeMode) Basis() TimeMode {
+	if m == TimeModeUTC {
+		return TimeModeUTC
+	}
+	return TimeModeLT
+}
+
+// Query returns the timeMode parameter of the Lufthansa API.
+func (m TimeMode) Query() string {
+	return string(m.Basis())
+}
+
+// DayBasis selects which end of a flight gives its operating day.
+type DayBasis string
+
+const (
+	DayBasisDeparture DayBasis = "departure"
+	DayBasisArrival   DayBasis = "arrival"
+)
+
+// ParseDayBasis reads a day basis option, an empty option means departure.
+func ParseDayBasis(s string) (DayBasis, error) {
+	switch b := DayBasis(strings.ToLower(strings.TrimSpace(s))); b {
+	case "":
+		return DayBasisDeparture, nil
+	case DayBasisDeparture, DayBasisArrival:
+		return b, nil
+	default:
+		return "", fmt.Errorf("invalid day basis %q", s)
+	}
+}
+
+// dayOffset returns the number of days minutes since midnight of the
+// operating day fall after it, negative before it.
+func dayOffset(minutes int) int {
+	if minutes < 0 {
+		return (minutes+1)/minutesPerDay - 1
+	}
+	return minutes / minutesPerDay
+}
+
+const minutesPerDay = 24 * 60
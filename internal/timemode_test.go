@@ -0,0 +1,148 @@
+package internal
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestParseTimeMode(t *testing.T) {
+	tests := []struct {
+		input     string
+		expected  TimeMode
+		expectErr bool
+	}{
+		{input: "", expected: TimeModeLT},
+		{input: "lt", expected: TimeModeLT},
+		{input: "UTC", expected: TimeModeUTC},
+		{input: " both ", expected: TimeModeBoth},
+		{input: "GMT", expectErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			result, err := ParseTimeMode(tt.input)
+			if (err != nil) != tt.expectErr {
+				t.Fatalf("expected error: %v, got: %v", tt.expectErr, err)
+			}
+			if result != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestParseDayBasis(t *testing.T) {
+	tests := []struct {
+		input     string
+		expected  DayBasis
+		expectErr bool
+	}{
+		{input: "", expected: DayBasisDeparture},
+		{input: "departure", expected: DayBasisDeparture},
+		{input: "Arrival", expected: DayBasisArrival},
+		{input: "landing", expectErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			result, err := ParseDayBasis(tt.input)
+			if (err != nil) != tt.expectErr {
+				t.Fatalf("expected error: %v, got: %v", tt.expectErr, err)
+			}
+			if result != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestDayOffset(t *testing.T) {
+	tests := []struct {
+		minutes  int
+		expected int
+	}{
+		{minutes: 0, expected: 0},
+		{minutes: 1439, expected: 0},
+		{minutes: 1440, expected: 1},
+		{minutes: -1, expected: -1},
+		{minutes: -1440, expected: -1},
+		{minutes: -1441, expected: -2},
+	}
+
+	for _, tt := range tests {
+		if result := dayOffset(tt.minutes); result != tt.expected {
+			t.Errorf("dayOffset(%d): expected %d, got %d", tt.minutes, tt.expected, result)
+		}
+	}
+}
+
+func TestCreateCSVFromResponseTimeMode(t *testing.T) {
+	// LH1364 arrives after local midnight, LH1366 departs after local but before UTC midnight
+	data := []byte(`[{"airline":"LH","flightNumber":1364,"periodOfOperationLT":{"startDate":"30MAR25","endDate":"5APR25","daysOfOperation":"1234567"},"periodOfOperationUTC":{"startDate":"30MAR25","endDate":"5APR25","daysOfOperation":"1234567"},` +
+		`"legs":[{"origin":"FRA","destination":"KRK","aircraftDepartureTimeLT":1420,"aircraftArrivalTimeLT":70,"aircraftArrivalTimeDateDiffLT":1,"aircraftDepartureTimeUTC":1300,"aircraftArrivalTimeUTC":1390,"aircraftDepartureTimeVariation":120,"aircraftArrivalTimeVariation":120}]},` +
+		`{"airline":"LH","flightNumber":1366,"periodOfOperationLT":{"startDate":"31MAR25","endDate":"6APR25","daysOfOperation":"1234567"},"periodOfOperationUTC":{"startDate":"30MAR25","endDate":"5APR25","daysOfOperation":"1234567"},` +
+		`"legs":[{"origin":"FRA","destination":"KRK","aircraftDepartureTimeLT":30,"aircraftArrivalTimeLT":120,"aircraftDepartureTimeUTC":1350,"aircraftArrivalTimeUTC":0,"aircraftArrivalTimeDateDiffUTC":1,"aircraftDepartureTimeVariation":120,"aircraftArrivalTimeVariation":120}]}]`)
+
+	tests := []struct {
+		name     string
+		mode     TimeMode
+		basis    DayBasis
+		expected string
+	}{
+		{
+			name: "Local times",
+			mode: TimeModeLT,
+			expected: "Flight,Departure,Arrival,Start date\n" +
+				"1364,23:40,01:10+1,2025-03-30\n" +
+				"1366,00:30,02:00,2025-03-31\n",
+		},
+		{
+			name: "UTC times",
+			mode: TimeModeUTC,
+			expected: "Flight,Departure UTC,Arrival UTC,Start date\n" +
+				"1364,21:40,23:10,2025-03-30\n" +
+				"1366,22:30,00:00+1,2025-03-30\n",
+		},
+		{
+			name: "Both side by side",
+			mode: TimeModeBoth,
+			expected: "Flight,Departure,Departure UTC,Arrival,Arrival UTC,Start date\n" +
+				"1364,23:40,21:40,01:10+1,23:10,2025-03-30\n" +
+				"1366,00:30,22:30-1,02:00,00:00,2025-03-31\n",
+		},
+		{
+			name:  "Local arrival days",
+			mode:  TimeModeLT,
+			basis: DayBasisArrival,
+			expected: "Flight,Departure,Arrival,Start date\n" +
+				"1364,23:40-1,01:10,2025-03-31\n" +
+				"1366,00:30,02:00,2025-03-31\n",
+		},
+		{
+			name:  "UTC arrival days",
+			mode:  TimeModeUTC,
+			basis: DayBasisArrival,
+			expected: "Flight,Departure UTC,Arrival UTC,Start date\n" +
+				"1364,21:40,23:10,2025-03-30\n" +
+				"1366,22:30-1,00:00,2025-03-31\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			opts := ExportOptions{
+				TimeMode: tt.mode,
+				DayBasis: tt.basis,
+				Template: ExportTemplate{Columns: []string{"flight_number", "departure", "arrival", "start_date"}},
+				Locale:   LocaleEN,
+			}
+			if _, err := CreateCSVFromResponse(&buf, data, opts, nil); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := buf.String(); got != tt.expected {
+				t.Errorf("Test %s failed: expected %q, got %q", tt.name, tt.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,309 @@
+package internal
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// Warning is a violation of a validation rule by a flight, or by a route
+// when the flight number is zero.
+type Warning struct {
+	Rule string `json:"rule"`
+	Route
+	StartDate time.Time `json:"start_date"`
+	EndDate   time.Time `json:"end_date"`
+	// Values fill in the message of the rule
+	Values []string `json:"values,omitempty"`
+}
+
+// Message describes the warning in the locale.
+func (w Warning) Message(l Locale) string {
+	args := make([]any, len(w.Values))
+	for i, v := range w.Values {
+		args[i] = v
+	}
+	return T(l, "warning."+w.Rule, args...)
+}
+
+// Rule is a sanity check of normalized schedule records.
+type Rule interface {
+	// Name identifies the rule in its warnings and messages
+	Name() string
+	Check(records []ScheduleRecord) []Warning
+}
+
+// DefaultRules returns every rule, checking aircraft types against the seat
+// table and expecting flights on each route of the queries.
+func DefaultRules(queries []ApiQuery) []Rule {
+	return []Rule{
+		PeriodRule{},
+		ArrivalOrderRule{},
+		BlockTimeRule{},
+		AircraftTypeRule{},
+		DuplicateFlightRule{},
+		EmptyRouteRule{Queries: queries},
+		UnknownAirportRule{Queries: queries},
+	}
+}
+
+// RawRule is a rule checking the records as decoded from the API response,
+// before normalizing drops or merges them.
+type RawRule interface {
+	Rule
+	raw()
+}
+
+// Validate checks the records with the rules, returning the warnings in the
+// order of the rules.
+func Validate(records []ScheduleRecord, rules []Rule) []Warning {
+	return validate(records, records, rules)
+}
+
+// validate checks the decoded records with the raw rules and the normalized
+// records with the others.
+func validate(decoded, normalized []ScheduleRecord, rules []Rule) []Warning {
+	var warnings []Warning
+	for _, rule := range rules {
+		records := normalized
+		if _, ok := rule.(RawRule); ok {
+			records = decoded
+		}
+		for _, w := range rule.Check(records) {
+			w.Rule = rule.Name()
+			warnings = append(warnings, w)
+		}
+	}
+	return warnings
+}
+
+func recordWarning(r ScheduleRecord, values ...string) Warning {
+	return Warning{Route: routeOf(r.Flight), StartDate: r.StartDate, EndDate: r.EndDate, Values: values}
+}
+
+// blockMinutes returns the time from the departure to the arrival of the
+// flight in minutes, with the day offsets and UTC variations of the record.
+func blockMinutes(f Flight) int {
+	return int(f.Arrival) - f.ArrivalVariation + minutesPerDay*(f.ArrivalDateDiff-f.DepartureDateDiff) - int(f.Departure) + f.DepartureVariation
+}
+
+// PeriodRule flags records whose period ends before it starts. Normalizing
+// drops them, so it checks the records as decoded.
+type PeriodRule struct{}
+
+func (PeriodRule) Name() string { return "invalid_period" }
+
+func (PeriodRule) raw() {}
+
+func (PeriodRule) Check(records []ScheduleRecord) []Warning {
+	var warnings []Warning
+	for _, r := range records {
+		if r.EndDate.Before(r.StartDate) {
+			warnings = append(warnings, recordWarning(r))
+		}
+	}
+	return warnings
+}
+
+// ArrivalOrderRule flags flights arriving before they depart without a day
+// change, comparing the times in UTC.
+type ArrivalOrderRule struct{}
+
+func (ArrivalOrderRule) Name() string { return "arrival_before_departure" }
+
+func (ArrivalOrderRule) Check(records []ScheduleRecord) []Warning {
+	var warnings []Warning
+	for _, r := range records {
+		if r.ArrivalDateDiff == r.DepartureDateDiff && blockMinutes(r.Flight) <= 0 {
+			warnings = append(warnings, recordWarning(r, r.Arrival.String(), r.Departure.String()))
+		}
+	}
+	return warnings
+}
+
+// BlockTimeRule flags block times outside of the plausible limits, or
+// further than the tolerance from the median of the city pair when it has
+// enough records to compare with.
+type BlockTimeRule struct{}
+
+// Limits of the block time rule in minutes.
+const (
+	minBlockTime       = 15
+	maxBlockTime       = 20 * 60
+	blockTimeTolerance = 30
+	blockTimeSamples   = 3
+)
+
+func (BlockTimeRule) Name() string { return "block_time" }
+
+func (BlockTimeRule) Check(records []ScheduleRecord) []Warning {
+	type cityPair struct{ origin, destination string }
+	blocks := make(map[cityPair][]int)
+	for _, r := range records {
+		pair := cityPair{r.Origin, r.Destination}
+		blocks[pair] = append(blocks[pair], blockMinutes(r.Flight))
+	}
+	medians := make(map[cityPair]int)
+	for pair, b := range blocks {
+		medians[pair] = median(b)
+	}
+
+	var warnings []Warning
+	for _, r := range records {
+		block := blockMinutes(r.Flight)
+		if block <= 0 && r.ArrivalDateDiff == r.DepartureDateDiff {
+			// Reported by the arrival order rule
+			continue
+		}
+		pair := cityPair{r.Origin, r.Destination}
+		median := medians[pair]
+		// The tolerance grows with the flight, a quarter of long block times
+		tolerance := max(blockTimeTolerance, median/4)
+		outlier := len(blocks[pair]) >= blockTimeSamples && (block < median-tolerance || block > median+tolerance)
+		if block < minBlockTime || block > maxBlockTime || outlier {
+			warnings = append(warnings, recordWarning(r, strconv.Itoa(block), strconv.Itoa(median)))
+		}
+	}
+	return warnings
+}
+
+// AircraftTypeRule flags aircraft types missing from the reference data.
+type AircraftTypeRule struct{}
+
+func (AircraftTypeRule) Name() string { return "unknown_aircraft" }
+
+func (AircraftTypeRule) Check(records []ScheduleRecord) []Warning {
+	references := References()
+	var warnings []Warning
+	for _, r := range records {
+		if _, ok := references.AircraftType(r.AircraftType); !ok {
+			warnings = append(warnings, recordWarning(r, r.AircraftType))
+		}
+	}
+	return warnings
+}
+
+// DuplicateFlightRule flags flight numbers departing more than once from
+// an airport on the same day with different flights, one warning for the
+// dates of each flight number.
+type DuplicateFlightRule struct{}
+
+func (DuplicateFlightRule) Name() string { return "duplicate_flight" }
+
+func (DuplicateFlightRule) Check(records []ScheduleRecord) []Warning {
+	type dated struct {
+		route Route
+		date  time.Time
+	}
+	count := make(map[dated]int)
+	for _, op := range ExpandRecords(records) {
+		count[dated{routeOf(op.Flight), op.Date}]++
+	}
+	dates := make(map[Route][]time.Time)
+	var routes []Route
+	for key, n := range count {
+		if n < 2 {
+			continue
+		}
+		if _, ok := dates[key.route]; !ok {
+			routes = append(routes, key.route)
+		}
+		dates[key.route] = append(dates[key.route], key.date)
+	}
+	slices.SortFunc(routes, compareRoutes)
+
+	var warnings []Warning
+	for _, route := range routes {
+		d := dates[route]
+		slices.SortFunc(d, time.Time.Compare)
+		warnings = append(warnings, Warning{Route: route, StartDate: d[0], EndDate: d[len(d)-1], Values: []string{strconv.Itoa(len(d))}})
+	}
+	return warnings
+}
+
+func compareRoutes(a, b Route) int {
+	return cmp.Or(
+		cmp.Compare(a.Airline, b.Airline),
+		cmp.Compare(a.FlightNumber, b.FlightNumber),
+		cmp.Compare(a.Suffix, b.Suffix),
+		cmp.Compare(a.Origin, b.Origin),
+		cmp.Compare(a.Destination, b.Destination),
+	)
+}
+
+// EmptyRouteRule flags the routes of the queries without any flight of
+// their airline in the records, in each direction as both are fetched.
+type EmptyRouteRule struct {
+	Queries []ApiQuery
+}
+
+func (EmptyRouteRule) Name() string { return "empty_route" }
+
+func (rule EmptyRouteRule) Check(records []ScheduleRecord) []Warning {
+	var warnings []Warning
+	for _, q := range rule.Queries {
+		back := q
+		back.Swap()
+		for _, q := range []ApiQuery{q, back} {
+			found := slices.ContainsFunc(records, func(r ScheduleRecord) bool {
+				return r.Airline == q.Airline && r.Origin == q.Origin && r.Destination == q.Destination
+			})
+			if found {
+				continue
+			}
+			// Queries without a period, such as those listing routes, leave the dates empty
+			start, _ := ParseSSIMDate(q.StartDate)
+			end, _ := ParseSSIMDate(q.EndDate)
+			warnings = append(warnings, Warning{Route: Route{Airline: q.Airline, Origin: q.Origin, Destination: q.Destination}, StartDate: start, EndDate: end})
+		}
+	}
+	return warnings
+}
+
+// UnknownAirportRule flags the airports of the queries missing from the
+// reference data, whose local times can not be checked against a time zone.
+type UnknownAirportRule struct {
+	Queries []ApiQuery
+}
+
+func (UnknownAirportRule) Name() string { return "unknown_airport" }
+
+func (rule UnknownAirportRule) Check([]ScheduleRecord) []Warning {
+	var warnings []Warning
+	for _, q := range rule.Queries {
+		for _, code := range []string{q.Origin, q.Destination} {
+			if KnownAirport(code) {
+				continue
+			}
+			start, _ := ParseSSIMDate(q.StartDate)
+			end, _ := ParseSSIMDate(q.EndDate)
+			warnings = append(warnings, Warning{Route: Route{Airline: q.Airline, Origin: q.Origin, Destination: q.Destination}, StartDate: start, EndDate: end, Values: []string{code}})
+		}
+	}
+	return warnings
+}
+
+// warningColumns are the columns of the warnings sheet.
+var warningColumns = []string{"rule", "flight", "origin", "destination", "start_date", "end_date", "message"}
+
+// WarningRows renders the warnings with a header row in the locale.
+func WarningRows(warnings []Warning, l Locale, dateLayout string) [][]string {
+	header := make([]string, 0, len(warningColumns))
+	for _, c := range warningColumns {
+		header = append(header, T(l, "warning.column."+c))
+	}
+	rows := [][]string{header}
+	for _, w := range warnings {
+		var flight, start, end string
+		if w.FlightNumber != 0 {
+			flight = fmt.Sprintf("%s%d%s", w.Airline, w.FlightNumber, w.Suffix)
+		}
+		if !w.StartDate.IsZero() {
+			start, end = w.StartDate.Format(dateLayout), w.EndDate.Format(dateLayout)
+		}
+		rows = append(rows, []string{T(l, "warning.rule."+w.Rule), flight, w.Origin, w.Destination, start, end, w.Message(l)})
+	}
+	return rows
+}
@@ -0,0 +1,82 @@
+package internal
+
+import (
+	"bytes"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	overnight := testRecord("FRA", "KRK", "LH", "1370", "23:00", "00:30", "2025-03-31", "2025-04-06", "1234567", "32N", "LH", "J")
+	overnight.ArrivalDateDiff = 1
+	records := []ScheduleRecord{
+		testRecord("KRK", "FRA", "LH", "1365", "10:20", "12:05", "2025-03-31", "2025-04-06", "1234567", "32N", "LH", "J"),
+		testRecord("KRK", "FRA", "LH", "1367", "10:00", "11:45", "2025-03-31", "2025-04-06", "1234567", "320", "LH", "J"),
+		testRecord("KRK", "FRA", "LH", "1369", "18:00", "23:55", "2025-03-31", "2025-04-06", "1234567", "32N", "LH", "J"),
+		// A second departure of LH1365 on two days
+		testRecord("KRK", "FRA", "LH", "1365", "15:00", "16:45", "2025-04-01", "2025-04-02", "1234567", "32N", "LH", "J"),
+		testRecord("FRA", "KRK", "LH", "1364", "12:00", "11:00", "2025-03-31", "2025-04-06", "1234567", "32N", "LH", "J"),
+		overnight,
+		testRecord("MUC", "KRK", "LH", "1620", "12:00", "13:00", "2025-03-31", "2025-04-06", "1.3.5..", "XYZ", "CL", "J"),
+		testRecord("KRK", "MUC", "LH", "1621", "13:20", "14:25", "2025-04-10", "2025-04-01", "1.3.5..", "E95", "CL", "J"),
+	}
+	queries := []ApiQuery{
+		{Airline: "LH", Origin: "KRK", Destination: "FRA", StartDate: "31MAR25", EndDate: "06APR25"},
+		{Airline: "LH", Origin: "KRK", Destination: "ZRH", StartDate: "31MAR25", EndDate: "06APR25"},
+		{Airline: "LH", Origin: "KRK", Destination: "BOS", StartDate: "31MAR25", EndDate: "06APR25"},
+	}
+
+	warnings := Validate(records, DefaultRules(queries))
+	var got []string
+	for _, row := range WarningRows(warnings, LocaleEN, dateLayout)[1:] {
+		got = append(got, strings.Join(row, " "))
+	}
+	expected := []string{
+		"Invalid period LH1621 KRK MUC 2025-04-10 2025-04-01 Period ends before it starts",
+		"Arrival before departure LH1364 FRA KRK 2025-03-31 2025-04-06 Arrival 11:00 before departure 12:00 without a day change",
+		"Block time LH1369 KRK FRA 2025-03-31 2025-04-06 Implausible block time of 355 min, the city pair median is 105 min",
+		"Unknown aircraft type LH1620 MUC KRK 2025-03-31 2025-04-06 Unknown aircraft type XYZ",
+		"Duplicate flight LH1365 KRK FRA 2025-04-01 2025-04-02 Flight number departs more than once a day on 2 days",
+		"Empty route  KRK ZRH 2025-03-31 2025-04-06 No flights found on the route",
+		"Empty route  ZRH KRK 2025-03-31 2025-04-06 No flights found on the route",
+		"Empty route  KRK BOS 2025-03-31 2025-04-06 No flights found on the route",
+		"Empty route  BOS KRK 2025-03-31 2025-04-06 No flights found on the route",
+		"Unknown airport  KRK BOS 2025-03-31 2025-04-06 Airport BOS is missing from the reference data, its local times are not checked",
+	}
+	if !slices.Equal(got, expected) {
+		t.Errorf("expected\n%q\ngot\n%q", expected, got)
+	}
+
+	// Both directions of the route have flights
+	if warnings := Validate([]ScheduleRecord{records[0], records[1], overnight}, DefaultRules(queries[:1])); len(warnings) != 0 {
+		t.Errorf("expected no warnings, got %+v", warnings)
+	}
+}
+
+func TestBlockMinutes(t *testing.T) {
+	// KRK in UTC+2 to LHR in UTC+1, arriving the next day
+	f := Flight{Departure: 23 * 60, Arrival: 30, DepartureVariation: 120, ArrivalVariation: 60, ArrivalDateDiff: 1}
+	if got := blockMinutes(f); got != 150 {
+		t.Errorf("expected 150 minutes, got %d", got)
+	}
+}
+
+func TestValidateExport(t *testing.T) {
+	// LH1621 ends before it starts, the export drops it but warns about it
+	data := []byte(`[{"airline":"LH","flightNumber":1365,"periodOfOperationLT":{"startDate":"31MAR25","endDate":"06APR25","daysOfOperation":"1234567"},"legs":[{"origin":"KRK","destination":"FRA","aircraftType":"32N","aircraftDepartureTimeLT":620,"aircraftArrivalTimeLT":725}]},` +
+		`{"airline":"LH","flightNumber":1621,"periodOfOperationLT":{"startDate":"10APR25","endDate":"01APR25","daysOfOperation":"1.3.5.."},"legs":[{"origin":"KRK","destination":"MUC","aircraftType":"E95","aircraftDepartureTimeLT":800,"aircraftArrivalTimeLT":865}]}]`)
+	opts := ExportOptions{Template: ExportTemplate{Columns: []string{"flight_number"}}, Locale: LocaleEN}
+
+	var buf bytes.Buffer
+	warnings, err := CreateCSVFromResponse(&buf, data, opts, DefaultRules(nil))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := buf.String(); got != "Flight\n1365\n" {
+		t.Errorf("expected only LH1365 exported, got %q", got)
+	}
+	if len(warnings) != 1 || warnings[0].Rule != "invalid_period" || warnings[0].FlightNumber != 1621 {
+		t.Errorf("expected the invalid period of LH1621, got %+v", warnings)
+	}
+}
@@ -0,0 +1,181 @@
+package internal
+
+import (
+	"fmt"
+	"iter"
+	"strings"
+	"time"
+)
+
+// Weekdays is a set of ISO weekdays, bit 0 is Monday and bit 6 is Sunday.
+type Weekdays uint8
+
+const AllWeekdays Weekdays = 1<<7 - 1
+
+func NewWeekdays(days ...int) Weekdays {
+	var w Weekdays
+	for _, d := range days {
+		if d >= 1 && d <= 7 {
+			w |= 1 << (d - 1)
+		}
+	}
+	return w
+}
+
+// ParseWeekdays reads days of operation in any of the formats used by the
+// Lufthansa API and SSIM files: compact "1357", positional "1 3 5 7" or
+// dotted "1.3.5.7". Positional strings must keep every digit in its column.
+func ParseWeekdays(s string) (Weekdays, error) {
+	positional := len(s) == 7 && strings.ContainsAny(s, " .")
+	var w Weekdays
+	for i, c := range s {
+		switch {
+		case c >= '1' && c <= '7':
+			day := int(c - '0')
+			if positional && day != i+1 {
+				return 0, fmt.Errorf("invalid days of operation %q: day %d in position %d", s, day, i+1)
+			}
+			if w.Has(day) {
+				return 0, fmt.Errorf("invalid days of operation %q: day %d repeated", s, day)
+			}
+			w = w.Add(day)
+		case c == ' ' || c == '.':
+		default:
+			return 0, fmt.Errorf("invalid days of operation %q", s)
+		}
+	}
+	return w, nil
+}
+
+// WeekdayOf returns the ISO weekday of t, Monday is 1 and Sunday is 7.
+func WeekdayOf(t time.Time) int {
+	wd := int(t.Weekday())
+	if wd == 0 {
+		return 7
+	}
+	return wd
+}
+
+func (w Weekdays) Has(day int) bool {
+	return day >= 1 && day <= 7 && w&(1<<(day-1)) != 0
+}
+
+// OperatesOn reports whether the weekday of date is in the set.
+func (w Weekdays) OperatesOn(date time.Time) bool {
+	return w.Has(WeekdayOf(date))
+}
+
+func (w Weekdays) Add(day int) Weekdays {
+	return w.Union(NewWeekdays(day))
+}
+
+func (w Weekdays) Union(other Weekdays) Weekdays {
+	return (w | other) & AllWeekdays
+}
+
+func (w Weekdays) Intersect(other Weekdays) Weekdays {
+	return w & other & AllWeekdays
+}
+
+func (w Weekdays) Difference(other Weekdays) Weekdays {
+	return w &^ other & AllWeekdays
+}
+
+func (w Weekdays) IsEmpty() bool {
+	return w&AllWeekdays == 0
+}
+
+func (w Weekdays) Count() int {
+	n := 0
+	for range w.All() {
+		n++
+	}
+	return n
+}
+
+// All iterates over the days in the set from Monday to Sunday.
+func (w Weekdays) All() iter.Seq[int] {
+	return func(yield func(int) bool) {
+		for d := 1; d <= 7; d++ {
+			if w.Has(d) && !yield(d) {
+				return
+			}
+		}
+	}
+}
+
+// Shift moves every day by offset days, wrapping around the week.
+// A flight departing Sunday with arrival offset +1 arrives on Monday.
+func (w Weekdays) Shift(offset int) Weekdays {
+	offset = ((offset % 7) + 7) % 7
+	var shifted Weekdays
+	for d := range w.All() {
+		shifted = shifted.Add((d-1+offset)%7 + 1)
+	}
+	return shifted
+}
+
+func (w Weekdays) format(off byte) string {
+	var b strings.Builder
+	for d := 1; d <= 7; d++ {
+		if w.Has(d) {
+			b.WriteByte(byte('0' + d))
+		} else {
+			b.WriteByte(off)
+		}
+	}
+	return b.String()
+}
+
+// String formats the set as seven positions with dots for days off, e.g. "1.3.5.7".
+func (w Weekdays) String() string {
+	return w.format('.')
+}
+
+func (w Weekdays) MarshalText() ([]byte, error) {
+	return []byte(w.String()), nil
+}
+
+func (w *Weekdays) UnmarshalText(text []byte) error {
+	parsed, err := ParseWeekdays(string(text))
+	if err != nil {
+		return err
+	}
+	*w = parsed
+	return nil
+}
+
+// SSIM formats the set as seven positions with spaces for days off, e.g. "1 3 5 7".
+func (w Weekdays) SSIM() string {
+	return w.format(' ')
+}
+
+// Compact lists only the operating days, e.g. "1357", as expected by the
+// daysOfOperation query parameter of the Lufthansa API.
+func (w Weekdays) Compact() string {
+	var b strings.Builder
+	for d := range w.All() {
+		b.WriteByte(byte('0' + d))
+	}
+	return b.String()
+}
+
+// firstOn returns the first date on or after from that falls on one of the days.
+func (w Weekdays) firstOn(from time.Time) (time.Time, bool) {
+	for i := 0; i < 7; i++ {
+		if date := from.AddDate(0, 0, i); w.OperatesOn(date) {
+			return date, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// lastOn returns the last date on or before to that falls on one of the days.
+func (w Weekdays) lastOn(to time.Time) (time.Time, bool) {
+	for i := 0; i < 7; i++ {
+		if date := to.AddDate(0, 0, -i); w.OperatesOn(date) {
+			return date, true
+		}
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,123 @@
+package internal
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestParseWeekdays(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		expected  Weekdays
+		expectErr bool
+	}{
+		{
+			name:     "Lufthansa positional with spaces",
+			input:    "123   7",
+			expected: NewWeekdays(1, 2, 3, 7),
+		},
+		{
+			name:     "Dotted format",
+			input:    ".23456.",
+			expected: NewWeekdays(2, 3, 4, 5, 6),
+		},
+		{
+			name:     "Compact format",
+			input:    "1357",
+			expected: NewWeekdays(1, 3, 5, 7),
+		},
+		{
+			name:     "Empty",
+			input:    "",
+			expected: 0,
+		},
+		{
+			name:      "Digit out of position",
+			input:     "3 1    ",
+			expectErr: true,
+		},
+		{
+			name:      "Repeated day",
+			input:     "113",
+			expectErr: true,
+		},
+		{
+			name:      "Invalid character",
+			input:     "1x3",
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := ParseWeekdays(tt.input)
+			if (err != nil) != tt.expectErr {
+				t.Errorf("Test %s failed: expected error: %v, got: %v", tt.name, tt.expectErr, err)
+			}
+			if result != tt.expected {
+				t.Errorf("Test %s failed: expected %s, got %s", tt.name, tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestWeekdaysFormat(t *testing.T) {
+	w := NewWeekdays(1, 3, 5, 7)
+	if got := w.String(); got != "1.3.5.7" {
+		t.Errorf("expected dotted 1.3.5.7, got %s", got)
+	}
+	if got := w.SSIM(); got != "1 3 5 7" {
+		t.Errorf("expected SSIM %q, got %q", "1 3 5 7", got)
+	}
+	if got := w.Compact(); got != "1357" {
+		t.Errorf("expected compact 1357, got %s", got)
+	}
+	for _, s := range []string{w.String(), w.SSIM(), w.Compact()} {
+		if parsed, err := ParseWeekdays(s); err != nil || parsed != w {
+			t.Errorf("round trip of %q failed: %s, %v", s, parsed, err)
+		}
+	}
+}
+
+func TestWeekdaysAlgebra(t *testing.T) {
+	a := NewWeekdays(1, 2, 3)
+	b := NewWeekdays(3, 4)
+
+	tests := []struct {
+		name     string
+		result   Weekdays
+		expected Weekdays
+	}{
+		{name: "Union", result: a.Union(b), expected: NewWeekdays(1, 2, 3, 4)},
+		{name: "Intersect", result: a.Intersect(b), expected: NewWeekdays(3)},
+		{name: "Difference", result: a.Difference(b), expected: NewWeekdays(1, 2)},
+		{name: "Shift forward", result: NewWeekdays(1, 7).Shift(1), expected: NewWeekdays(1, 2)},
+		{name: "Shift backward", result: NewWeekdays(1, 7).Shift(-1), expected: NewWeekdays(6, 7)},
+		{name: "Shift full week", result: a.Shift(7), expected: a},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.result != tt.expected {
+				t.Errorf("Test %s failed: expected %s, got %s", tt.name, tt.expected, tt.result)
+			}
+		})
+	}
+
+	if days := slices.Collect(b.All()); !slices.Equal(days, []int{3, 4}) {
+		t.Errorf("expected iteration [3 4], got %v", days)
+	}
+	if !a.Intersect(NewWeekdays(7)).IsEmpty() {
+		t.Errorf("expected empty intersection")
+	}
+	if a.Count() != 3 {
+		t.Errorf("expected count 3, got %d", a.Count())
+	}
+	// 2025-03-30 is a Sunday
+	sunday := time.Date(2025, 3, 30, 0, 0, 0, 0, time.UTC)
+	if WeekdayOf(sunday) != 7 || !NewWeekdays(7).OperatesOn(sunday) || a.OperatesOn(sunday) {
+		t.Errorf("unexpected weekday handling for %v", sunday)
+	}
+}
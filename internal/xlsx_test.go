@@ -0,0 +1,113 @@
+package internal
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteXLSX(t *testing.T) {
+	var buf bytes.Buffer
+	rows := [][]string{{"Flight", "Old"}, {"LH1365", "<32N & co>"}}
+	if err := WriteXLSX(&buf, "Changes", rows); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	parts := xlsxParts(t, buf.Bytes())
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml", "xl/_rels/workbook.xml.rels", "xl/worksheets/sheet1.xml"} {
+		if _, ok := parts[name]; !ok {
+			t.Errorf("missing part %s", name)
+		}
+	}
+	if !strings.Contains(parts["xl/workbook.xml"], `name="Changes"`) {
+		t.Errorf("sheet name missing from workbook")
+	}
+	sheet := parts["xl/worksheets/sheet1.xml"]
+	for _, want := range []string{`<c r="A1" t="inlineStr"><is><t xml:space="preserve">Flight</t>`, `<c r="B2" t="inlineStr"><is><t xml:space="preserve">&lt;32N &amp; co&gt;</t>`} {
+		if !strings.Contains(sheet, want) {
+			t.Errorf("expected %s in sheet", want)
+		}
+	}
+}
+
+func TestWriteRecordsXLSX(t *testing.T) {
+	records := []ScheduleRecord{{
+		Flight:    Flight{Airline: "LH", FlightNumber: 1365, Origin: "KRK", Destination: "FRA"},
+		StartDate: time.Date(2025, 3, 30, 0, 0, 0, 0, time.UTC),
+		EndDate:   time.Date(2025, 4, 5, 0, 0, 0, 0, time.UTC),
+		Days:      NewWeekdays(1, 2, 3, 4, 5, 6, 7),
+	}}
+	opts := ExportOptions{
+		Template: ExportTemplate{Columns: []string{"flight_number", "origin", "start_date"}},
+		Locale:   LocalePL,
+	}
+
+	var buf bytes.Buffer
+	if err := WriteRecordsXLSX(&buf, records, opts); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	parts := xlsxParts(t, buf.Bytes())
+	if !strings.Contains(parts["xl/workbook.xml"], `name="Rozkład"`) {
+		t.Errorf("sheet name missing from workbook")
+	}
+	sheet := parts["xl/worksheets/sheet1.xml"]
+	for _, want := range []string{`<t xml:space="preserve">Numer</t>`, `<c r="B2" t="inlineStr"><is><t xml:space="preserve">KRK</t>`, `<t xml:space="preserve">30.03.2025</t>`} {
+		if !strings.Contains(sheet, want) {
+			t.Errorf("expected %s in sheet", want)
+		}
+	}
+	if _, ok := parts["xl/worksheets/sheet2.xml"]; ok {
+		t.Errorf("expected no warnings sheet without warnings")
+	}
+
+	buf.Reset()
+	opts.Warnings = []Warning{{Rule: "unknown_aircraft", Route: routeOf(records[0].Flight), StartDate: records[0].StartDate, EndDate: records[0].EndDate, Values: []string{"XYZ"}}}
+	if err := WriteRecordsXLSX(&buf, records, opts); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	parts = xlsxParts(t, buf.Bytes())
+	if !strings.Contains(parts["xl/workbook.xml"], `<sheet name="Ostrzeżenia" sheetId="2" r:id="rId2"/>`) || !strings.Contains(parts["[Content_Types].xml"], "/xl/worksheets/sheet2.xml") {
+		t.Errorf("warnings sheet missing from workbook")
+	}
+	for _, want := range []string{`<t xml:space="preserve">Nieznany typ samolotu</t>`, `<t xml:space="preserve">LH1365</t>`, `<t xml:space="preserve">Nieznany typ samolotu XYZ</t>`} {
+		if !strings.Contains(parts["xl/worksheets/sheet2.xml"], want) {
+			t.Errorf("expected %s in warnings sheet", want)
+		}
+	}
+
+	opts.Template = ExportTemplate{Columns: []string{"gate"}}
+	if err := WriteRecordsXLSX(&buf, records, opts); err == nil {
+		t.Errorf("expected unknown column error")
+	}
+}
+
+// xlsxParts unpacks the spreadsheet into its parts by name.
+func xlsxParts(t *testing.T, data []byte) map[string]string {
+	t.Helper()
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("invalid zip: %v", err)
+	}
+	parts := make(map[string]string)
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		content, _ := io.ReadAll(rc)
+		rc.Close()
+		parts[f.Name] = string(content)
+	}
+	return parts
+}
+
+func TestColumnName(t *testing.T) {
+	for i, want := range map[int]string{0: "A", 25: "Z", 26: "AA", 27: "AB", 701: "ZZ", 702: "AAA"} {
+		if got := columnName(i); got != want {
+			t.Errorf("columnName(%d): expected %s, got %s", i, want, got)
+		}
+	}
+}